@@ -1,16 +1,25 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gomodule/redigo/redis"
 )
 
+const (
+	AppEnvDevelopment = "development"
+
+	defaultJWTSecret = "jwt-secret"
+)
+
 type Config struct {
 	// Server
 	ServerPort string
+	AppEnv     string
 
 	// Database
 	DBDriver   string
@@ -23,6 +32,62 @@ type Config struct {
 	// JWT
 	JWTSecret      string
 	JWTExpiryHours int
+	// PEM files; tokens are signed with HS256 and JWTSecret when no signing
+	// key is set
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+
+	// Login
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	LoginLockMinutes      int
+
+	// Sessions
+	NewDeviceOTPRequired bool
+
+	// Phone numbers without a country code are read as numbers of this country
+	DefaultCountryCode string
+
+	// OTP
+	OTPTTLMinutes  int
+	OTPMaxRequests int
+	SMSOutboxFile  string
+
+	// MFA
+	TOTPIssuer         string
+	MFAThresholdAmount string
+
+	// Limits; without a rules file transactions are not limited
+	LimitsFile          string
+	LimitsReloadSeconds int
+
+	// Risk rules; a zero value turns the rule that uses it off
+	RiskLargeAmount      string
+	RiskNewDeviceHours   int
+	RiskPinResetHours    int
+	RiskBurstMinutes     int
+	RiskBurstRecipients  int
+	RiskRoundAmountUnit  string
+	RiskStructuringHours int
+	RiskStructuringCount int
+
+	// Screening; without a list file nobody is screened. Names match when
+	// their similarity reaches ScreeningMinScore percent.
+	ScreeningListFile    string
+	ScreeningPollSeconds int
+	ScreeningMinScore    int
+
+	// KYC documents and case attachments are stored under BlobDir
+	BlobDir            string
+	KYCMaxImageBytes   int
+	CaseMaxUploadBytes int
+
+	// Idempotency
+	IdempotencyTTLHours int
+
+	// Outbox
+	OutboxPollIntervalMs int
+	OutboxBatchSize      int
 
 	// Redis
 	RedisHost     string
@@ -33,23 +98,68 @@ type Config struct {
 
 func LoadConfig() *Config {
 	config := &Config{
-		ServerPort:     getEnv("SERVER_PORT", "8080"),
-		DBDriver:       getEnv("DB_DRIVER", "mysql"),
-		DBHost:         getEnv("DB_HOST", "localhost"),
-		DBPort:         getEnv("DB_PORT", "3306"),
-		DBUser:         getEnv("DB_USER", "user"),
-		DBPassword:     getEnv("DB_PASSWORD", "password"),
-		DBName:         getEnv("DB_NAME", "emoney"),
-		JWTSecret:      getEnv("JWT_SECRET", "jwt-secret"),
-		JWTExpiryHours: getEnvAsInt("JWT_EXPIRY_HOURS", 24),
-		RedisHost:      getEnv("REDIS_HOST", "redis"),
-		RedisPort:      getEnv("REDIS_PORT", "6379"),
-		RedisPassword:  getEnv("REDIS_PASSWORD", ""),
+		ServerPort:              getEnv("SERVER_PORT", "8080"),
+		AppEnv:                  getEnv("APP_ENV", ""),
+		DBDriver:                getEnv("DB_DRIVER", "mysql"),
+		DBHost:                  getEnv("DB_HOST", "localhost"),
+		DBPort:                  getEnv("DB_PORT", "3306"),
+		DBUser:                  getEnv("DB_USER", "user"),
+		DBPassword:              getEnv("DB_PASSWORD", "password"),
+		DBName:                  getEnv("DB_NAME", "emoney"),
+		JWTSecret:               getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTExpiryHours:          getEnvAsInt("JWT_EXPIRY_HOURS", 24),
+		JWTSigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles: getEnvAsList("JWT_VERIFICATION_KEY_FILES"),
+		LoginMaxAttempts:        getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginMaxAttemptsPerIP:   getEnvAsInt("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		LoginLockMinutes:        getEnvAsInt("LOGIN_LOCK_MINUTES", 15),
+		DefaultCountryCode:      getEnv("DEFAULT_COUNTRY_CODE", "62"),
+		NewDeviceOTPRequired:    getEnvAsBool("NEW_DEVICE_OTP_REQUIRED", false),
+		OTPTTLMinutes:           getEnvAsInt("OTP_TTL_MINUTES", 5),
+		OTPMaxRequests:          getEnvAsInt("OTP_MAX_REQUESTS", 3),
+		SMSOutboxFile:           getEnv("SMS_OUTBOX_FILE", ""),
+		TOTPIssuer:              getEnv("TOTP_ISSUER", "E-Wallet"),
+		MFAThresholdAmount:      getEnv("MFA_THRESHOLD_AMOUNT", "1000000.00"),
+		LimitsFile:              getEnv("LIMITS_FILE", ""),
+		LimitsReloadSeconds:     getEnvAsInt("LIMITS_RELOAD_SECONDS", 10),
+		RiskLargeAmount:         getEnv("RISK_LARGE_AMOUNT", "5000000.00"),
+		RiskNewDeviceHours:      getEnvAsInt("RISK_NEW_DEVICE_HOURS", 24),
+		RiskPinResetHours:       getEnvAsInt("RISK_PIN_RESET_HOURS", 24),
+		RiskBurstMinutes:        getEnvAsInt("RISK_BURST_MINUTES", 60),
+		RiskBurstRecipients:     getEnvAsInt("RISK_BURST_NEW_RECIPIENTS", 3),
+		RiskRoundAmountUnit:     getEnv("RISK_ROUND_AMOUNT_UNIT", "100000.00"),
+		RiskStructuringHours:    getEnvAsInt("RISK_STRUCTURING_HOURS", 24),
+		RiskStructuringCount:    getEnvAsInt("RISK_STRUCTURING_COUNT", 3),
+		ScreeningListFile:       getEnv("SCREENING_LIST_FILE", ""),
+		ScreeningPollSeconds:    getEnvAsInt("SCREENING_RELOAD_SECONDS", 60),
+		ScreeningMinScore:       getEnvAsInt("SCREENING_MIN_SCORE", 90),
+		BlobDir:                 getEnv("BLOB_DIR", "data/blobs"),
+		KYCMaxImageBytes:        getEnvAsInt("KYC_MAX_IMAGE_BYTES", 5<<20),
+		CaseMaxUploadBytes:      getEnvAsInt("CASE_MAX_UPLOAD_BYTES", 10<<20),
+		IdempotencyTTLHours:     getEnvAsInt("IDEMPOTENCY_TTL_HOURS", 24),
+		OutboxPollIntervalMs:    getEnvAsInt("OUTBOX_POLL_INTERVAL_MS", 500),
+		OutboxBatchSize:         getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+		RedisHost:               getEnv("REDIS_HOST", "redis"),
+		RedisPort:               getEnv("REDIS_PORT", "6379"),
+		RedisPassword:           getEnv("REDIS_PASSWORD", ""),
 	}
 
 	return config
 }
 
+// Validate refuses settings that are only acceptable on a developer machine.
+// JWT_SECRET also keys the OTP hashes, so it must be changed even when tokens
+// are signed with asymmetric keys.
+func (c *Config) Validate() error {
+	if c.AppEnv == AppEnvDevelopment {
+		return nil
+	}
+	if c.JWTSecret == defaultJWTSecret {
+		return errors.New("JWT_SECRET must be changed from its default outside APP_ENV=development")
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -70,3 +180,26 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return value
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Error converting %s to bool, using default value %t: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
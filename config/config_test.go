@@ -0,0 +1,52 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigJWTAndEnvironment(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "a-real-secret")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "keys/2024-03.pem")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", " keys/2024-02.pub.pem, ,keys/2024-01.pub.pem ")
+
+	cfg := LoadConfig()
+	if cfg.AppEnv != "production" {
+		t.Errorf("AppEnv = %q", cfg.AppEnv)
+	}
+	if cfg.JWTSigningKeyFile != "keys/2024-03.pem" {
+		t.Errorf("JWTSigningKeyFile = %q", cfg.JWTSigningKeyFile)
+	}
+	want := []string{"keys/2024-02.pub.pem", "keys/2024-01.pub.pem"}
+	if !reflect.DeepEqual(cfg.JWTVerificationKeyFiles, want) {
+		t.Errorf("JWTVerificationKeyFiles = %q, want %q", cfg.JWTVerificationKeyFiles, want)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestLoadConfigJWTDefaults(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "")
+
+	cfg := LoadConfig()
+	if cfg.AppEnv != "" || cfg.JWTSigningKeyFile != "" || cfg.JWTVerificationKeyFiles != nil {
+		t.Errorf("config = %q, %q, %q", cfg.AppEnv, cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted the default JWT_SECRET outside development")
+	}
+}
+
+func TestValidateDevelopment(t *testing.T) {
+	t.Setenv("APP_ENV", AppEnvDevelopment)
+	t.Setenv("JWT_SECRET", defaultJWTSecret)
+
+	if err := LoadConfig().Validate(); err != nil {
+		t.Errorf("Validate() = %v, want the default secret allowed in development", err)
+	}
+}
@@ -6,14 +6,50 @@ CREATE TABLE IF NOT EXISTS users (
 			first_name VARCHAR(100) NOT NULL,
             last_name VARCHAR(100) NOT NULL,
 			address VARCHAR(100) NOT NULL,
+			verification_status VARCHAR(20) NOT NULL DEFAULT 'UNVERIFIED',
+			totp_secret VARCHAR(64) DEFAULT NULL,
+			totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			totp_last_step BIGINT DEFAULT NULL,
+			role VARCHAR(20) NOT NULL DEFAULT 'customer',
+			status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+			status_reason VARCHAR(255) DEFAULT NULL,
+			tier VARCHAR(20) NOT NULL DEFAULT 'BASIC',
+			pin_reset_at DATETIME NULL DEFAULT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 		) ENGINE=InnoDB;
 
-CREATE TABLE IF NOT EXISTS wallets (
+CREATE TABLE IF NOT EXISTS recovery_codes (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id VARCHAR(100) NOT NULL,
+			code_hash CHAR(64) NOT NULL,
+			used_at TIMESTAMP NULL DEFAULT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY uniq_recovery_codes_user_code (user_id, code_hash),
+			FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS sessions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			session_id VARCHAR(100) NOT NULL UNIQUE,
+			user_id VARCHAR(100) NOT NULL,
+			device_id VARCHAR(100) NOT NULL,
+			device_name VARCHAR(100) NOT NULL,
+			platform VARCHAR(50) NOT NULL,
+			ip_address VARCHAR(45) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NULL DEFAULT NULL,
+			revoked_at TIMESTAMP NULL DEFAULT NULL,
+			INDEX idx_sessions_user_device (user_id, device_id),
+			FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS wallets (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id VARCHAR(100) NOT NULL UNIQUE,
 			balance DECIMAL(15,2) DEFAULT 0.00,
+			status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
@@ -21,7 +57,7 @@ CREATE TABLE IF NOT EXISTS wallets (
 
 CREATE TABLE IF NOT EXISTS transactions (
 			id INT AUTO_INCREMENT PRIMARY KEY,
-			transaction_id VARCHAR(100) NOT NULL,
+			transaction_id VARCHAR(100) NOT NULL UNIQUE,
 			user_id VARCHAR(100) NOT NULL,
 			type VARCHAR(20) NOT NULL,
 			amount DECIMAL(15,2) NOT NULL,
@@ -29,6 +65,220 @@ CREATE TABLE IF NOT EXISTS transactions (
             balance_after DECIMAL(15,2) DEFAULT NULL,
 			description TEXT,
 			status VARCHAR(20) NOT NULL,
+			failure_reason VARCHAR(100) DEFAULT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-		) ENGINE=InnoDB;
\ No newline at end of file
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			account_code VARCHAR(120) NOT NULL UNIQUE,
+			type VARCHAR(20) NOT NULL,
+			user_id VARCHAR(100) DEFAULT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'IDR',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_ledger_accounts_user_id (user_id)
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			journal_id VARCHAR(100) NOT NULL UNIQUE,
+			reference_id VARCHAR(100) NOT NULL,
+			type VARCHAR(20) NOT NULL,
+			description TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_journal_entries_reference_id (reference_id)
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS postings (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			journal_id VARCHAR(100) NOT NULL,
+			account_code VARCHAR(120) NOT NULL,
+			direction VARCHAR(6) NOT NULL,
+			amount DECIMAL(15,2) NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'IDR',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (journal_id) REFERENCES journal_entries(journal_id),
+			FOREIGN KEY (account_code) REFERENCES ledger_accounts(account_code),
+			INDEX idx_postings_account_code (account_code)
+		) ENGINE=InnoDB;
+
+INSERT IGNORE INTO ledger_accounts (account_code, type, currency) VALUES
+			('SYSTEM:TOPUP_SOURCE', 'SYSTEM', 'IDR'),
+			('SYSTEM:PAYMENT_SINK', 'SYSTEM', 'IDR'),
+			('SYSTEM:FEES', 'SYSTEM', 'IDR'),
+			('SYSTEM:PAYOUT_SINK', 'SYSTEM', 'IDR'),
+			('SYSTEM:OPENING_EQUITY', 'SYSTEM', 'IDR');
+
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			job_name VARCHAR(100) NOT NULL,
+			reference_id VARCHAR(100) NOT NULL DEFAULT '',
+			payload JSON NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			available_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			published_at TIMESTAMP NULL DEFAULT NULL,
+			INDEX idx_outbox_messages_status_available_at (status, available_at),
+			INDEX idx_outbox_messages_reference_id (reference_id)
+		) ENGINE=InnoDB;
+
+-- audit_events is append-only. Every hash covers the hash of the row before
+-- it and audit_chain holds the hash of the last row.
+CREATE TABLE IF NOT EXISTS audit_events (
+			sequence BIGINT PRIMARY KEY,
+			event_id VARCHAR(100) NOT NULL UNIQUE,
+			action VARCHAR(50) NOT NULL,
+			actor_id VARCHAR(100) NOT NULL DEFAULT '',
+			user_id VARCHAR(100) NOT NULL DEFAULT '',
+			target_id VARCHAR(100) NOT NULL DEFAULT '',
+			phone_number VARCHAR(20) NOT NULL DEFAULT '',
+			ip_address VARCHAR(45) NOT NULL DEFAULT '',
+			user_agent VARCHAR(255) NOT NULL DEFAULT '',
+			before_snapshot TEXT NOT NULL,
+			after_snapshot TEXT NOT NULL,
+			details TEXT NOT NULL,
+			prev_hash CHAR(64) NOT NULL,
+			hash CHAR(64) NOT NULL,
+			created_at DATETIME NOT NULL,
+			INDEX idx_audit_events_action (action),
+			INDEX idx_audit_events_actor_id (actor_id),
+			INDEX idx_audit_events_user_id (user_id),
+			INDEX idx_audit_events_target_id (target_id),
+			INDEX idx_audit_events_created_at (created_at)
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS audit_chain (
+			id TINYINT PRIMARY KEY,
+			last_sequence BIGINT NOT NULL,
+			last_hash CHAR(64) NOT NULL
+		) ENGINE=InnoDB;
+
+INSERT IGNORE INTO audit_chain (id, last_sequence, last_hash) VALUES (1, 0, '');
+
+-- limit_usage backs the limit counters in Redis. released_at is set when the
+-- transaction failed and no longer counts.
+CREATE TABLE IF NOT EXISTS limit_usage (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			transaction_id VARCHAR(100) NOT NULL UNIQUE,
+			user_id VARCHAR(100) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			amount DECIMAL(15,2) NOT NULL,
+			created_at DATETIME NOT NULL,
+			released_at DATETIME NULL DEFAULT NULL,
+			INDEX idx_limit_usage_user_kind_created_at (user_id, kind, created_at)
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS kyc_submissions (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			submission_id VARCHAR(100) NOT NULL UNIQUE,
+			user_id VARCHAR(100) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			id_type VARCHAR(20) NOT NULL,
+			id_number VARCHAR(50) NOT NULL,
+			id_image_key VARCHAR(255) NOT NULL,
+			selfie_key VARCHAR(255) NOT NULL,
+			selfie_metadata TEXT NOT NULL,
+			reviewer_id VARCHAR(100) DEFAULT NULL,
+			review_note VARCHAR(255) DEFAULT NULL,
+			created_at DATETIME NOT NULL,
+			reviewed_at DATETIME NULL DEFAULT NULL,
+			INDEX idx_kyc_submissions_status_created_at (status, created_at),
+			INDEX idx_kyc_submissions_user_id (user_id),
+			FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+
+-- risk_decisions holds the outcome and rule hits of every assessed
+-- transaction. Held transactions get reviewer_id and reviewed_at once staff
+-- release or reject them.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			transaction_id VARCHAR(100) NOT NULL UNIQUE,
+			user_id VARCHAR(100) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			target_user VARCHAR(100) NOT NULL DEFAULT '',
+			amount DECIMAL(15,2) NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'IDR',
+			new_recipient BOOLEAN NOT NULL DEFAULT FALSE,
+			outcome VARCHAR(10) NOT NULL,
+			hits TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			reviewer_id VARCHAR(100) DEFAULT NULL,
+			review_note VARCHAR(255) DEFAULT NULL,
+			reviewed_at DATETIME NULL DEFAULT NULL,
+			INDEX idx_risk_decisions_user_created_at (user_id, created_at),
+			INDEX idx_risk_decisions_outcome_created_at (outcome, created_at)
+		) ENGINE=InnoDB;
+
+-- compliance_cases track flagged users. Cases are kept when an unverified
+-- user is replaced, so there is no foreign key to users.
+CREATE TABLE IF NOT EXISTS compliance_cases (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			case_id VARCHAR(100) NOT NULL UNIQUE,
+			user_id VARCHAR(100) NOT NULL,
+			source VARCHAR(20) NOT NULL,
+			trigger_type VARCHAR(20) NOT NULL DEFAULT '',
+			summary VARCHAR(255) NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL,
+			assignee_id VARCHAR(100) DEFAULT NULL,
+			matches TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			closed_by VARCHAR(100) DEFAULT NULL,
+			closing_note VARCHAR(255) DEFAULT NULL,
+			closed_at DATETIME NULL DEFAULT NULL,
+			INDEX idx_compliance_cases_status_created_at (status, created_at),
+			INDEX idx_compliance_cases_user_id_status (user_id, status),
+			INDEX idx_compliance_cases_assignee_id (assignee_id)
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS compliance_case_transactions (
+			case_id VARCHAR(100) NOT NULL,
+			transaction_id VARCHAR(100) NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (case_id, transaction_id),
+			INDEX idx_compliance_case_transactions_transaction_id (transaction_id),
+			FOREIGN KEY (case_id) REFERENCES compliance_cases(case_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS compliance_case_notes (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			note_id VARCHAR(100) NOT NULL UNIQUE,
+			case_id VARCHAR(100) NOT NULL,
+			author_id VARCHAR(100) NOT NULL,
+			body TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			INDEX idx_compliance_case_notes_case_id (case_id, created_at),
+			FOREIGN KEY (case_id) REFERENCES compliance_cases(case_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+
+-- screening_clearances hold the watchlist entries staff found not to be the
+-- user. entry_version changes with the listing, which screens the user again.
+CREATE TABLE IF NOT EXISTS screening_clearances (
+			user_id VARCHAR(100) NOT NULL,
+			entry_id VARCHAR(100) NOT NULL,
+			entry_version VARCHAR(64) NOT NULL,
+			case_id VARCHAR(100) NOT NULL,
+			cleared_by VARCHAR(100) NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, entry_id)
+		) ENGINE=InnoDB;
+
+-- compliance_case_attachments only hold the blob key, the files are kept in
+-- the blob store.
+CREATE TABLE IF NOT EXISTS compliance_case_attachments (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			attachment_id VARCHAR(100) NOT NULL UNIQUE,
+			case_id VARCHAR(100) NOT NULL,
+			uploaded_by VARCHAR(100) NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			content_type VARCHAR(100) NOT NULL,
+			size BIGINT NOT NULL,
+			blob_key VARCHAR(255) NOT NULL,
+			created_at DATETIME NOT NULL,
+			INDEX idx_compliance_case_attachments_case_id (case_id, created_at),
+			FOREIGN KEY (case_id) REFERENCES compliance_cases(case_id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
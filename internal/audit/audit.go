@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+// Logger records security relevant events.
+type Logger interface {
+	Log(event entity.AuditEvent)
+}
+
+type logLogger struct{}
+
+// NewLogLogger writes audit events as JSON lines to the standard logger.
+func NewLogLogger() Logger {
+	return logLogger{}
+}
+
+func (logLogger) Log(event entity.AuditEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("AUDIT failed to encode %s event: %v", event.Type, err)
+		return
+	}
+	log.Printf("AUDIT %s", payload)
+}
+
+type storeLogger struct {
+	repository repository.IAuditRepository
+	fallback   Logger
+}
+
+// NewStoreLogger appends audit events to the hash chained audit_events table.
+// Events that cannot be stored are written to the standard logger instead, so
+// a database outage does not lose them silently.
+func NewStoreLogger(repo repository.IAuditRepository) Logger {
+	return storeLogger{repository: repo, fallback: logLogger{}}
+}
+
+func (l storeLogger) Log(event entity.AuditEvent) {
+	if err := l.repository.Append(&event); err != nil {
+		log.Printf("AUDIT failed to store %s event: %v", event.Type, err)
+		l.fallback.Log(event)
+	}
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+)
+
+const auditUsage = `usage: audit verify
+
+  verify  recompute the audit log hash chain and report the first broken event`
+
+func (c Commands) audit(args []string) error {
+	if len(args) != 1 || args[0] != "verify" {
+		return errors.New(auditUsage)
+	}
+
+	result, err := c.AuditService.Verify()
+	if err != nil {
+		return err
+	}
+	if err := printJSON(result); err != nil {
+		return err
+	}
+
+	if !result.Valid {
+		return fmt.Errorf("audit log is broken at event %d: %s", result.BrokenSequence, result.Reason)
+	}
+	return nil
+}
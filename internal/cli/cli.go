@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+// Commands are maintenance subcommands run with the server binary, e.g.
+// `./main dead-jobs list -name=topup_job`.
+type Commands struct {
+	JobService    service.IJobService
+	UserService   service.IUserService
+	AuditService  service.IAuditService
+	LedgerService service.ILedgerService
+}
+
+func (c Commands) Run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+
+	switch args[0] {
+	case "audit":
+		return c.audit(args[1:])
+	case "dead-jobs":
+		return c.deadJobs(args[1:])
+	case "ledger":
+		return c.ledger(args[1:])
+	case "set-role":
+		return c.setRole(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
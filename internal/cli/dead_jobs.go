@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const deadJobsUsage = `usage: dead-jobs <list|retry|discard|stats> [flags]
+
+  list     list dead jobs matching the filter flags
+  retry    retry one dead job (-id and -died-at) or every job matching the filter
+  discard  discard one dead job (-id and -died-at) or every job matching the filter
+  stats    show queued, retrying and dead counts per job type`
+
+func (c Commands) deadJobs(args []string) error {
+	if len(args) == 0 {
+		return errors.New(deadJobsUsage)
+	}
+
+	flags := flag.NewFlagSet("dead-jobs "+args[0], flag.ContinueOnError)
+	name := flags.String("name", "", "only jobs with this name, e.g. topup_job")
+	errorContains := flags.String("error", "", "only jobs whose last error contains this text")
+	diedAfter := flags.String("died-after", "", "only jobs that died after this RFC 3339 time")
+	diedBefore := flags.String("died-before", "", "only jobs that died before this RFC 3339 time")
+	jobID := flags.String("id", "", "id of a single dead job")
+	diedAt := flags.Int64("died-at", 0, "died_at of a single dead job")
+	all := flags.Bool("all", false, "allow retry or discard of every dead job when no filter is given")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	filter := entity.DeadJobFilter{Name: *name, ErrorContains: *errorContains}
+	var err error
+	if *diedAfter != "" {
+		if filter.DiedAfter, err = time.Parse(time.RFC3339, *diedAfter); err != nil {
+			return fmt.Errorf("invalid -died-after: %w", err)
+		}
+	}
+	if *diedBefore != "" {
+		if filter.DiedBefore, err = time.Parse(time.RFC3339, *diedBefore); err != nil {
+			return fmt.Errorf("invalid -died-before: %w", err)
+		}
+	}
+	hasFilter := filter != entity.DeadJobFilter{}
+
+	switch args[0] {
+	case "list":
+		jobs, err := c.JobService.ListDeadJobs(filter)
+		if err != nil {
+			return err
+		}
+		return printJSON(jobs)
+
+	case "stats":
+		stats, err := c.JobService.Stats()
+		if err != nil {
+			return err
+		}
+		return printJSON(stats)
+
+	case "retry", "discard":
+		if *jobID != "" {
+			if args[0] == "retry" {
+				err = c.JobService.RetryDeadJob(*diedAt, *jobID)
+			} else {
+				err = c.JobService.DiscardDeadJob(*diedAt, *jobID)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: %d/%s\n", args[0], *diedAt, *jobID)
+			return nil
+		}
+
+		if !hasFilter && !*all {
+			return errors.New("refusing to touch every dead job without a filter; pass -all to confirm")
+		}
+
+		var result *entity.DeadJobBatchResult
+		if args[0] == "retry" {
+			result, err = c.JobService.RetryDeadJobs(filter)
+		} else {
+			result, err = c.JobService.DiscardDeadJobs(filter)
+		}
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+
+	default:
+		return errors.New(deadJobsUsage)
+	}
+}
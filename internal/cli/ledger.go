@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+)
+
+const ledgerUsage = `usage: ledger <check|open-balances>
+
+  check          check that the postings balance and explain every wallet balance
+  open-balances  post opening balances for wallets older than the ledger`
+
+func (c Commands) ledger(args []string) error {
+	if len(args) != 1 {
+		return errors.New(ledgerUsage)
+	}
+
+	switch args[0] {
+	case "check":
+		report, err := c.LedgerService.Check()
+		if err != nil {
+			return err
+		}
+		if err := printJSON(report); err != nil {
+			return err
+		}
+		if !report.Balanced {
+			return fmt.Errorf("ledger is off by %s with %d mismatched wallets", report.Total, len(report.Mismatches))
+		}
+		return nil
+
+	case "open-balances":
+		journals, err := c.LedgerService.OpenBalances()
+		if printErr := printJSON(journals); printErr != nil && err == nil {
+			err = printErr
+		}
+		return err
+
+	default:
+		return errors.New(ledgerUsage)
+	}
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const setRoleUsage = `usage: set-role (-user-id=<id> | -phone=<number>) -role=<customer|support|finance-ops|admin>`
+
+// cliActor is recorded as the actor of changes made from the command line.
+const cliActor = "cli"
+
+// setRole grants a role without going through the API, e.g. to create the
+// first admin.
+func (c Commands) setRole(args []string) error {
+	flags := flag.NewFlagSet("set-role", flag.ContinueOnError)
+	userID := flags.String("user-id", "", "user_id of the user")
+	phoneNumber := flags.String("phone", "", "phone number of the user")
+	role := flags.String("role", "", "new role")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *role == "" || (*userID == "") == (*phoneNumber == "") {
+		return errors.New(setRoleUsage)
+	}
+
+	if *phoneNumber != "" {
+		account, err := c.UserService.FindUserByPhoneNumber(*phoneNumber)
+		if err != nil {
+			return err
+		}
+		*userID = account.UserID
+	}
+
+	err := c.UserService.SetRole(&entity.SetRoleRequest{
+		Role:    *role,
+		UserID:  *userID,
+		ActorID: cliActor,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("set-role: %s is now %s\n", *userID, *role)
+	return nil
+}
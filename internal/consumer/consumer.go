@@ -1,19 +1,21 @@
 package consumer
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/gocraft/work"
 	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
 	"github.com/leonardoong/e-wallet/internal/service"
 )
 
 type Consumer struct {
-	config      *config.Config
-	workerPool  *work.WorkerPool
-	TopUpWorker *topUpWorker
-	PaymentWorker *paymentWorker
+	config         *config.Config
+	workerPool     *work.WorkerPool
+	TopUpWorker    *topUpWorker
+	PaymentWorker  *paymentWorker
 	TransferWorker *transferWorker
 }
 
@@ -25,7 +27,7 @@ func NewConsumer(cfg *config.Config, svc service.ITransactionService) *Consumer
 	}
 	consumer := new(Consumer)
 	consumer.config = cfg
-	consumer.workerPool = work.NewWorkerPool(WorkerContext{}, uint(2), "ewallet", cfg.CachePool)
+	consumer.workerPool = work.NewWorkerPool(WorkerContext{}, uint(2), entity.JobNamespace, cfg.CachePool)
 	consumer.TopUpWorker = newTopUpWorker(svc, consumer.workerPool)
 	consumer.PaymentWorker = newPaymentWorker(svc, consumer.workerPool)
 	consumer.TransferWorker = newTransferWorker(svc, consumer.workerPool)
@@ -38,15 +40,15 @@ func (c *Consumer) Initialize() {
 	maxFails := uint(2)
 
 	c.TopUpWorker.workerPool = c.workerPool
-	c.TopUpWorker.jobName = "topup_job"
+	c.TopUpWorker.jobName = entity.JobNameTopUp
 	c.TopUpWorker.runTopupConsumer(maxFails)
 
 	c.PaymentWorker.workerPool = c.workerPool
-	c.PaymentWorker.jobName = "payment_job"
+	c.PaymentWorker.jobName = entity.JobNamePayment
 	c.PaymentWorker.runPaymentConsumer(maxFails)
 
 	c.TransferWorker.workerPool = c.workerPool
-	c.TransferWorker.jobName = "transfer_job"
+	c.TransferWorker.jobName = entity.JobNameTransfer
 	c.TransferWorker.runTransferConsumer(maxFails)
 
 	c.workerPool.Start()
@@ -55,3 +57,29 @@ func (c *Consumer) Initialize() {
 func (c *Consumer) Close() {
 	c.workerPool.Stop()
 }
+
+// handleProcessError decides whether gocraft/work should retry a job.
+// Business rule failures were already recorded on the transaction and are
+// acknowledged; other errors are retried until MaxFails, after which the
+// transaction is marked FAILED so it does not stay PENDING forever.
+func handleProcessError(svc service.ITransactionService, job *work.Job, maxFails uint, transactionID string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var failed *service.TransactionFailedError
+	if errors.As(err, &failed) {
+		log.Printf("transaction %s failed with %s", transactionID, failed.Reason)
+		return nil
+	}
+
+	// job.Fails counts the previous failed runs.
+	if uint(job.Fails)+1 >= maxFails {
+		log.Printf("giving up on transaction %s: %v", transactionID, err)
+		if failErr := svc.FailTransaction(transactionID, entity.FailureReasonProcessingError); failErr != nil {
+			log.Printf("failed to mark transaction %s as FAILED: %v", transactionID, failErr)
+		}
+	}
+
+	return err
+}
@@ -6,11 +6,11 @@ import (
 	"github.com/leonardoong/e-wallet/internal/service"
 )
 
-
 type paymentWorker struct {
 	transactionService service.ITransactionService
-	workerPool *work.WorkerPool
-	jobName string
+	workerPool         *work.WorkerPool
+	jobName            string
+	maxFails           uint
 }
 
 func newPaymentWorker(srv service.ITransactionService, pool *work.WorkerPool) *paymentWorker {
@@ -21,23 +21,21 @@ func newPaymentWorker(srv service.ITransactionService, pool *work.WorkerPool) *p
 }
 
 func (c *paymentWorker) runPaymentConsumer(maxFails uint) {
+	c.maxFails = maxFails
 	c.workerPool.JobWithOptions(c.jobName, work.JobOptions{MaxFails: maxFails}, c.processPayment)
 }
 
 func (c *paymentWorker) processPayment(job *work.Job) (err error) {
+	req := entity.PaymentRequest{
+		Amount:    entity.NewMoney(job.ArgInt64("amount"), job.ArgString("currency")),
+		PaymentID: job.ArgString("payment_id"),
+		UserID:    job.ArgString("user_id"),
+		Remarks:   job.ArgString("remarks"),
+	}
 	if err = job.ArgError(); err != nil {
 		return err
 	}
 
-	req := entity.PaymentRequest{
-		Amount:  job.ArgFloat64("amount"),
-		PaymentID: job.ArgString("payment_id"),
-		UserID:  job.ArgString("user_id"),
-		Remarks: job.ArgString("remarks"),
-	}
 	err = c.transactionService.ProcessPayment(req)
-	if err != nil {
-		return
-	}
-	return
-}
\ No newline at end of file
+	return handleProcessError(c.transactionService, job, c.maxFails, req.PaymentID, err)
+}
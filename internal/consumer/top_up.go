@@ -10,6 +10,7 @@ type topUpWorker struct {
 	transactionService service.ITransactionService
 	workerPool         *work.WorkerPool
 	jobName            string
+	maxFails           uint
 }
 
 func newTopUpWorker(srv service.ITransactionService, pool *work.WorkerPool) *topUpWorker {
@@ -20,22 +21,20 @@ func newTopUpWorker(srv service.ITransactionService, pool *work.WorkerPool) *top
 }
 
 func (c *topUpWorker) runTopupConsumer(maxFails uint) {
+	c.maxFails = maxFails
 	c.workerPool.JobWithOptions(c.jobName, work.JobOptions{MaxFails: maxFails}, c.processTopUp)
 }
 
 func (c *topUpWorker) processTopUp(job *work.Job) (err error) {
-	if err = job.ArgError(); err != nil {
-		return err
-	}
-
 	req := entity.PublishTopUpRequest{
-		Amount:  job.ArgFloat64("amount"),
+		Amount:  entity.NewMoney(job.ArgInt64("amount"), job.ArgString("currency")),
 		TopUpID: job.ArgString("top_up_id"),
 		UserID:  job.ArgString("user_id"),
 	}
-	err = c.transactionService.ProcessTopUp(req)
-	if err != nil {
-		return
+	if err = job.ArgError(); err != nil {
+		return err
 	}
-	return
+
+	err = c.transactionService.ProcessTopUp(req)
+	return handleProcessError(c.transactionService, job, c.maxFails, req.TopUpID, err)
 }
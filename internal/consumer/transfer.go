@@ -10,6 +10,7 @@ type transferWorker struct {
 	transactionService service.ITransactionService
 	workerPool         *work.WorkerPool
 	jobName            string
+	maxFails           uint
 }
 
 func newTransferWorker(srv service.ITransactionService, pool *work.WorkerPool) *transferWorker {
@@ -20,25 +21,23 @@ func newTransferWorker(srv service.ITransactionService, pool *work.WorkerPool) *
 }
 
 func (c *transferWorker) runTransferConsumer(maxFails uint) {
+	c.maxFails = maxFails
 	c.workerPool.JobWithOptions(c.jobName, work.JobOptions{MaxFails: maxFails}, c.processTransfer)
 }
 
 func (c *transferWorker) processTransfer(job *work.Job) (err error) {
+	req := entity.TransferRequest{
+		TransferID:       job.ArgString("transfer_id"),
+		TargetTransferID: job.ArgString("target_transfer_id"),
+		Amount:           entity.NewMoney(job.ArgInt64("amount"), job.ArgString("currency")),
+		UserID:           job.ArgString("user_id"),
+		TargetUser:       job.ArgString("target_user"),
+		Remarks:          job.ArgString("remarks"),
+	}
 	if err = job.ArgError(); err != nil {
 		return err
 	}
 
-	req := entity.TransferRequest{
-		TransferID: job.ArgString("transfer_id"),
-		TargetTransferID: job.ArgString("target_transfer_id"),
-		Amount:  job.ArgFloat64("amount"),
-		UserID:  job.ArgString("user_id"),
-		TargetUser:  job.ArgString("target_user"),
-		Remarks:  job.ArgString("remarks"),
-	}
 	err = c.transactionService.ProcessTransfer(req)
-	if err != nil {
-		return
-	}
-	return
+	return handleProcessError(c.transactionService, job, c.maxFails, req.TransferID, err)
 }
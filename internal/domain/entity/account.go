@@ -0,0 +1,53 @@
+package entity
+
+// Account statuses are kept on both the user and the wallet. The user status
+// decides who may sign in, the wallet status which money movements are
+// applied.
+const (
+	AccountStatusActive = "ACTIVE"
+	// AccountStatusFrozen accounts can sign in and receive money, but
+	// nothing can be debited.
+	AccountStatusFrozen = "FROZEN"
+	// AccountStatusSuspended accounts can neither sign in nor move money.
+	AccountStatusSuspended = "SUSPENDED"
+	// AccountStatusClosed is final and only reached through account closure.
+	AccountStatusClosed = "CLOSED"
+)
+
+// CanSend reports whether money may leave an account with the given status.
+func CanSend(status string) bool {
+	return status == AccountStatusActive
+}
+
+// CanReceive reports whether money may enter an account with the given status.
+func CanReceive(status string) bool {
+	return status == AccountStatusActive || status == AccountStatusFrozen
+}
+
+// CanSignIn reports whether a user with the given status may use the API.
+func CanSignIn(status string) bool {
+	return status == AccountStatusActive || status == AccountStatusFrozen
+}
+
+type AccountStatusRequest struct {
+	Status    string `json:"status" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+	UserID    string `json:"-"`
+	ActorID   string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type CloseAccountRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	// PayoutReference identifies the bank transfer that paid out the
+	// remaining balance. It is required unless the balance is zero.
+	PayoutReference string `json:"payout_reference"`
+	UserID          string `json:"-"`
+	ActorID         string `json:"-"`
+	IPAddress       string `json:"-"`
+}
+
+type CloseAccountResponse struct {
+	PayoutID     string `json:"payout_id,omitempty"`
+	PayoutAmount Money  `json:"payout_amount"`
+}
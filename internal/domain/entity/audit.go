@@ -0,0 +1,152 @@
+package entity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditEventLoginSucceeded = "LOGIN_SUCCEEDED"
+	AuditEventLoginFailed    = "LOGIN_FAILED"
+	AuditEventLoginLocked    = "LOGIN_LOCKED"
+	AuditEventLoginUnlocked  = "LOGIN_UNLOCKED"
+	AuditEventProfileUpdated = "PROFILE_UPDATED"
+	AuditEventPinChanged     = "PIN_CHANGED"
+	AuditEventPinReset       = "PIN_RESET"
+	AuditEventMFAEnabled     = "MFA_ENABLED"
+	AuditEventMFADisabled    = "MFA_DISABLED"
+
+	AuditEventTransactionCreated   = "TRANSACTION_CREATED"
+	AuditEventTransactionSucceeded = "TRANSACTION_SUCCEEDED"
+	AuditEventTransactionFailed    = "TRANSACTION_FAILED"
+	AuditEventTransactionReleased  = "TRANSACTION_RELEASED"
+	AuditEventTransactionRejected  = "TRANSACTION_REJECTED"
+
+	AuditEventPermissionDenied = "PERMISSION_DENIED"
+	AuditEventRoleChanged      = "ROLE_CHANGED"
+	AuditEventAccountStatus    = "ACCOUNT_STATUS_CHANGED"
+	AuditEventAccountClosed    = "ACCOUNT_CLOSED"
+
+	AuditEventKYCSubmitted = "KYC_SUBMITTED"
+	AuditEventKYCApproved  = "KYC_APPROVED"
+	AuditEventKYCRejected  = "KYC_REJECTED"
+
+	AuditEventCaseOpened            = "COMPLIANCE_CASE_OPENED"
+	AuditEventCaseAssigned          = "COMPLIANCE_CASE_ASSIGNED"
+	AuditEventCaseStatusChanged     = "COMPLIANCE_CASE_STATUS_CHANGED"
+	AuditEventCaseTransactionLinked = "COMPLIANCE_CASE_TRANSACTION_LINKED"
+	AuditEventCaseNoteAdded         = "COMPLIANCE_CASE_NOTE_ADDED"
+	AuditEventCaseAttachmentAdded   = "COMPLIANCE_CASE_ATTACHMENT_ADDED"
+	AuditEventCaseClosed            = "COMPLIANCE_CASE_CLOSED"
+)
+
+// AuditEvent records who did what. Stored events form a hash chain: Hash
+// covers the event and PrevHash, the Hash of the event before it, so
+// changing or removing a stored event breaks every hash after it.
+type AuditEvent struct {
+	Sequence int64  `json:"sequence,omitempty"`
+	EventID  string `json:"event_id,omitempty"`
+	Type     string `json:"type"`
+	// ActorID is who acted: the user themselves, a staff member, or "cli".
+	// Empty for events without a known actor, e.g. failed logins.
+	ActorID string `json:"actor_id,omitempty"`
+	// UserID is the user acted on, TargetID any other object acted on, e.g.
+	// a transaction.
+	UserID      string `json:"user_id,omitempty"`
+	TargetID    string `json:"target_id,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	UserAgent   string `json:"user_agent,omitempty"`
+
+	// Before and After are snapshots of what changed.
+	Before  interface{}            `json:"before,omitempty"`
+	After   interface{}            `json:"after,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	PrevHash  string    `json:"prev_hash,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ComputeHash returns the chain hash of the event. The snapshots are hashed
+// in their canonical JSON form, so an event read back from the database
+// hashes the same as when it was written. CreatedAt counts to the second.
+func (e *AuditEvent) ComputeHash() (string, error) {
+	before, err := CanonicalJSON(e.Before)
+	if err != nil {
+		return "", err
+	}
+	after, err := CanonicalJSON(e.After)
+	if err != nil {
+		return "", err
+	}
+	details, err := CanonicalJSON(e.Details)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.Sequence,
+		e.EventID,
+		e.Type,
+		e.ActorID,
+		e.UserID,
+		e.TargetID,
+		e.PhoneNumber,
+		e.IPAddress,
+		e.UserAgent,
+		before,
+		after,
+		details,
+		e.CreatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalJSON encodes v the way it encodes again after a round trip
+// through JSON: numbers keep their literal and object keys are sorted.
+func CanonicalJSON(v interface{}) (json.RawMessage, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
+
+type AuditEventFilter struct {
+	Type     string
+	ActorID  string
+	UserID   string
+	TargetID string
+	From     time.Time
+	To       time.Time
+	// BeforeSequence pages backwards: only events with a lower sequence.
+	BeforeSequence int64
+	Limit          int
+}
+
+// AuditVerification is the result of checking the hash chain.
+type AuditVerification struct {
+	Valid  bool  `json:"valid"`
+	Events int64 `json:"events"`
+	// BrokenSequence is the first sequence where the chain does not hold.
+	BrokenSequence int64  `json:"broken_sequence,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
@@ -0,0 +1,144 @@
+package entity
+
+import "time"
+
+// Case statuses. A case starts OPEN, moves between INVESTIGATING and
+// ESCALATED while staff work it, and CLOSED is final.
+const (
+	CaseStatusOpen          = "OPEN"
+	CaseStatusInvestigating = "INVESTIGATING"
+	CaseStatusEscalated     = "ESCALATED"
+	CaseStatusClosed        = "CLOSED"
+
+	// CaseSourceScreening cases are opened by watchlist hits.
+	CaseSourceScreening = "SCREENING"
+	// CaseSourceManual cases are opened by staff.
+	CaseSourceManual = "MANUAL"
+)
+
+// ComplianceCase tracks a flagged user until staff close it. A case that
+// froze the account keeps it FROZEN until it is closed.
+type ComplianceCase struct {
+	CaseID     string `json:"case_id"`
+	UserID     string `json:"user_id"`
+	Source     string `json:"source"`
+	Trigger    string `json:"trigger,omitempty"`
+	Summary    string `json:"summary"`
+	Status     string `json:"status"`
+	AssigneeID string `json:"assignee_id,omitempty"`
+	// Matches are the watchlist entries of a screening case.
+	Matches []ScreeningMatch `json:"matches"`
+	// TransactionIDs, Notes and Attachments are only set when a single case
+	// is read. TransactionIDs are the transactions the case is about, e.g.
+	// transfers to the flagged user held for review.
+	TransactionIDs []string          `json:"transaction_ids,omitempty"`
+	Notes          []*CaseNote       `json:"notes,omitempty"`
+	Attachments    []*CaseAttachment `json:"attachments,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	// Set once staff closed the case.
+	ClosedBy    string     `json:"closed_by,omitempty"`
+	ClosingNote string     `json:"closing_note,omitempty"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
+
+type CaseNote struct {
+	NoteID    string    `json:"note_id"`
+	CaseID    string    `json:"case_id"`
+	AuthorID  string    `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CaseAttachment struct {
+	AttachmentID string    `json:"attachment_id"`
+	CaseID       string    `json:"case_id"`
+	UploadedBy   string    `json:"uploaded_by"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	BlobKey      string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ComplianceCaseFilter struct {
+	Status        string
+	Source        string
+	UserID        string
+	AssigneeID    string
+	TransactionID string
+	Limit         int
+}
+
+type CreateCaseRequest struct {
+	UserID         string   `json:"user_id" binding:"required"`
+	Summary        string   `json:"summary"`
+	TransactionIDs []string `json:"transaction_ids"`
+	// Freeze makes an ACTIVE account FROZEN until the case is closed.
+	Freeze    bool   `json:"freeze"`
+	ActorID   string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type AssignCaseRequest struct {
+	// AssigneeID is empty to unassign the case.
+	AssigneeID string `json:"assignee_id"`
+	CaseID     string `json:"-"`
+	ActorID    string `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type CaseStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	// Note is optional and added to the case notes.
+	Note      string `json:"note"`
+	CaseID    string `json:"-"`
+	ActorID   string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type LinkCaseTransactionRequest struct {
+	TransactionID string `json:"transaction_id" binding:"required"`
+	CaseID        string `json:"-"`
+	ActorID       string `json:"-"`
+	IPAddress     string `json:"-"`
+}
+
+type AddCaseNoteRequest struct {
+	Body      string `json:"body"`
+	CaseID    string `json:"-"`
+	ActorID   string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type AddCaseAttachmentRequest struct {
+	CaseID   string
+	ActorID  string
+	FileName string
+	// Content is a PDF, JPEG, PNG or plain text file.
+	Content   []byte
+	IPAddress string
+}
+
+type CloseCaseRequest struct {
+	Note string `json:"note"`
+	// Unfreeze makes an account frozen by a compliance case ACTIVE again,
+	// e.g. when the hit was a false positive. It is refused while the user
+	// has other open cases; a freeze by staff stays.
+	Unfreeze bool `json:"unfreeze"`
+	// FalsePositive clears the watchlist entries of a screening case for
+	// the user, who is not flagged for them again until they are relisted.
+	FalsePositive bool `json:"false_positive"`
+	// ReleaseTransactions queues the linked transactions still held for
+	// review.
+	ReleaseTransactions bool   `json:"release_transactions"`
+	CaseID              string `json:"-"`
+	ActorID             string `json:"-"`
+	IPAddress           string `json:"-"`
+}
+
+type CloseCaseResponse struct {
+	// Unfrozen reports whether the account was made ACTIVE again.
+	Unfrozen               bool     `json:"unfrozen"`
+	ReleasedTransactionIDs []string `json:"released_transaction_ids"`
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+const (
+	ConfirmationTypePayment  = "PAYMENT"
+	ConfirmationTypeTransfer = "TRANSFER"
+)
+
+// TransactionConfirmation is what a confirmation token allows: one payment or
+// transfer of exactly this amount, to this target.
+type TransactionConfirmation struct {
+	UserID     string `json:"user_id"`
+	Type       string `json:"type"`
+	Amount     Money  `json:"amount"`
+	TargetUser string `json:"target_user,omitempty"`
+}
+
+type TransactionConfirmRequest struct {
+	Pin        string `json:"pin" binding:"required"`
+	Type       string `json:"type" binding:"required"`
+	Amount     Money  `json:"amount"`
+	TargetUser string `json:"target_user"`
+	UserID     string `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type TransactionConfirmResponse struct {
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
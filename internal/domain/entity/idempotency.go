@@ -0,0 +1,16 @@
+package entity
+
+const (
+	IdempotencyStatusInProgress = "IN_PROGRESS"
+	IdempotencyStatusCompleted  = "COMPLETED"
+)
+
+// IdempotencyRecord is the stored outcome of the first request made with an
+// Idempotency-Key, replayed verbatim when the client retries.
+type IdempotencyRecord struct {
+	RequestHash string `json:"request_hash"`
+	Status      string `json:"status"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
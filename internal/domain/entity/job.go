@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	JobNamespace = "ewallet"
+
+	JobNameTopUp    = "topup_job"
+	JobNamePayment  = "payment_job"
+	JobNameTransfer = "transfer_job"
+)
+
+// JobTransactionIDArg maps a job name to the argument holding the id of the
+// transaction the job applies.
+var JobTransactionIDArg = map[string]string{
+	JobNameTopUp:    "top_up_id",
+	JobNamePayment:  "payment_id",
+	JobNameTransfer: "transfer_id",
+}
+
+// FailedJob is a job waiting for a retry or, once it exhausted its retries,
+// sitting in gocraft/work's dead set. DiedAt and ID together identify a dead
+// job for retry or discard.
+type FailedJob struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Args       map[string]interface{} `json:"args"`
+	Fails      int64                  `json:"fails"`
+	LastError  string                 `json:"last_error"`
+	EnqueuedAt time.Time              `json:"enqueued_at"`
+	FailedAt   time.Time              `json:"failed_at"`
+	DiedAt     int64                  `json:"died_at,omitempty"`
+}
+
+type DeadJobFilter struct {
+	Name          string
+	ErrorContains string
+	DiedAfter     time.Time
+	DiedBefore    time.Time
+}
+
+func (f DeadJobFilter) Match(job *FailedJob) bool {
+	if f.Name != "" && job.Name != f.Name {
+		return false
+	}
+	if f.ErrorContains != "" && !strings.Contains(strings.ToLower(job.LastError), strings.ToLower(f.ErrorContains)) {
+		return false
+	}
+	diedAt := time.Unix(job.DiedAt, 0)
+	if !f.DiedAfter.IsZero() && diedAt.Before(f.DiedAfter) {
+		return false
+	}
+	if !f.DiedBefore.IsZero() && diedAt.After(f.DiedBefore) {
+		return false
+	}
+	return true
+}
+
+// JobTypeStats summarises one job type across the queue, retry and dead sets.
+type JobTypeStats struct {
+	Name       string `json:"name"`
+	Queued     int64  `json:"queued"`
+	Retrying   int64  `json:"retrying"`
+	Dead       int64  `json:"dead"`
+	TotalFails int64  `json:"total_fails"`
+}
+
+type DeadJobBatchResult struct {
+	Matched   int      `json:"matched"`
+	Succeeded int      `json:"succeeded"`
+	Errors    []string `json:"errors,omitempty"`
+}
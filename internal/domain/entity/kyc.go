@@ -0,0 +1,68 @@
+package entity
+
+import "time"
+
+// TierFull is granted when a KYC submission is approved; new users are
+// TierBasic.
+const TierFull = "FULL"
+
+const (
+	KYCStatusPending  = "PENDING"
+	KYCStatusApproved = "APPROVED"
+	KYCStatusRejected = "REJECTED"
+
+	KYCIDTypeKTP      = "KTP"
+	KYCIDTypePassport = "PASSPORT"
+	KYCIDTypeSIM      = "SIM"
+
+	// Documents of a submission, as named in the upload form and the
+	// document download route.
+	KYCDocumentIDImage = "id_image"
+	KYCDocumentSelfie  = "selfie"
+)
+
+type KYCSubmission struct {
+	SubmissionID   string                 `json:"submission_id"`
+	UserID         string                 `json:"user_id"`
+	Status         string                 `json:"status"`
+	IDType         string                 `json:"id_type"`
+	IDNumber       string                 `json:"id_number"`
+	IDImageKey     string                 `json:"-"`
+	SelfieKey      string                 `json:"-"`
+	SelfieMetadata map[string]interface{} `json:"selfie_metadata"`
+	ReviewerID     string                 `json:"reviewer_id,omitempty"`
+	ReviewNote     string                 `json:"review_note,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	ReviewedAt     *time.Time             `json:"reviewed_at,omitempty"`
+}
+
+type SubmitKYCRequest struct {
+	UserID   string
+	IDType   string
+	IDNumber string
+	// IDImage and Selfie are JPEG or PNG images.
+	IDImage        []byte
+	Selfie         []byte
+	SelfieMetadata map[string]interface{}
+	IPAddress      string
+	UserAgent      string
+}
+
+type KYCStatusResponse struct {
+	Tier       string         `json:"tier"`
+	Submission *KYCSubmission `json:"submission"`
+}
+
+type KYCSubmissionFilter struct {
+	Status string
+	UserID string
+	Limit  int
+}
+
+type ReviewKYCRequest struct {
+	// Note is shown to the user and required when rejecting.
+	Note         string `json:"note"`
+	SubmissionID string `json:"-"`
+	ActorID      string `json:"-"`
+	IPAddress    string `json:"-"`
+}
@@ -0,0 +1,80 @@
+package entity
+
+import "time"
+
+const (
+	LedgerAccountTypeWallet = "WALLET"
+	LedgerAccountTypeSystem = "SYSTEM"
+
+	// System accounts are the counterparties of money entering or leaving wallets.
+	SystemAccountTopUpSource = "SYSTEM:TOPUP_SOURCE"
+	SystemAccountPaymentSink = "SYSTEM:PAYMENT_SINK"
+	SystemAccountFees        = "SYSTEM:FEES"
+	// SystemAccountPayoutSink receives the balance of closed accounts that
+	// was paid out to the owner's bank account.
+	SystemAccountPayoutSink = "SYSTEM:PAYOUT_SINK"
+	// SystemAccountOpeningEquity is the counterparty of the balances wallets
+	// already held before the ledger was introduced.
+	SystemAccountOpeningEquity = "SYSTEM:OPENING_EQUITY"
+
+	PostingDirectionDebit  = "DEBIT"
+	PostingDirectionCredit = "CREDIT"
+
+	JournalTypeTopUp    = "TOPUP"
+	JournalTypePayment  = "PAYMENT"
+	JournalTypeTransfer = "TRANSFER"
+	JournalTypePayout   = "PAYOUT"
+	JournalTypeOpening  = "OPENING_BALANCE"
+)
+
+type LedgerAccount struct {
+	ID          uint      `json:"id"`
+	AccountCode string    `json:"account_code"`
+	Type        string    `json:"type"`
+	UserID      string    `json:"user_id,omitempty"`
+	Currency    string    `json:"currency"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// JournalEntry groups the postings of a single money movement. The sum of its
+// debit postings always equals the sum of its credit postings.
+type JournalEntry struct {
+	ID          uint      `json:"id"`
+	JournalID   string    `json:"journal_id"`
+	ReferenceID string    `json:"reference_id"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Postings    []Posting `json:"postings"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Posting struct {
+	ID          uint      `json:"id"`
+	JournalID   string    `json:"journal_id"`
+	AccountCode string    `json:"account_code"`
+	Direction   string    `json:"direction"`
+	Amount      Money     `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func WalletAccountCode(userID string) string {
+	return "WALLET:" + userID
+}
+
+// LedgerReport is the result of checking the ledger against the wallets.
+type LedgerReport struct {
+	// Total is credits minus debits over all postings and zero when money is
+	// conserved.
+	Total    Money `json:"total"`
+	Balanced bool  `json:"balanced"`
+	// Mismatches are the wallets whose balance differs from their postings.
+	Mismatches []WalletMismatch `json:"mismatches"`
+}
+
+type WalletMismatch struct {
+	UserID        string `json:"user_id"`
+	WalletBalance Money  `json:"wallet_balance"`
+	LedgerBalance Money  `json:"ledger_balance"`
+	// Opened is set once the wallet has an opening balance journal.
+	Opened bool `json:"opened"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// LimitRule bounds one kind of transaction for one tier. Zero values are not
+// limited.
+type LimitRule struct {
+	MinAmount     Money `json:"min_amount"`
+	MaxAmount     Money `json:"max_amount"`
+	DailyAmount   Money `json:"daily_amount"`
+	MonthlyAmount Money `json:"monthly_amount"`
+	HourlyCount   int64 `json:"hourly_count"`
+}
+
+// LimitUsage is what a started transaction counts against the limits of its
+// user.
+type LimitUsage struct {
+	TransactionID string
+	UserID        string
+	Kind          string
+	Amount        Money
+	CreatedAt     time.Time
+}
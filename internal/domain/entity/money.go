@@ -0,0 +1,263 @@
+package entity
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used whenever an amount arrives without a currency,
+// e.g. from an HTTP payload or a DECIMAL column.
+const DefaultCurrency = "IDR"
+
+// minorUnitScale is the number of decimal places stored in the database
+// (DECIMAL(15,2)), so one major unit is 100 minor units.
+const (
+	minorUnitScale     = 2
+	minorUnitsPerMajor = 100
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrMoneyOverflow    = errors.New("money amount overflow")
+	ErrInvalidMoney     = errors.New("invalid money amount")
+)
+
+// Money is an amount held as an integer number of minor units plus an
+// ISO 4217 currency code. It is encoded as a decimal string in JSON and SQL.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "1500", "-12.5" or "10000.00".
+// More than two fractional digits is rejected rather than rounded.
+func ParseMoney(s string, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalidMoney
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return Money{}, ErrInvalidMoney
+	}
+	if hasFrac && frac == "" {
+		return Money{}, ErrInvalidMoney
+	}
+	if len(frac) > minorUnitScale {
+		return Money{}, fmt.Errorf("%w: more than %d decimal places", ErrInvalidMoney, minorUnitScale)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrInvalidMoney
+	}
+
+	var major int64
+	if whole != "" {
+		var err error
+		major, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return Money{}, ErrMoneyOverflow
+		}
+	}
+	if major > math.MaxInt64/minorUnitsPerMajor {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	frac += strings.Repeat("0", minorUnitScale-len(frac))
+	minor, _ := strconv.ParseInt(frac, 10, 64)
+
+	amount := major*minorUnitsPerMajor + minor
+	if amount < 0 {
+		return Money{}, ErrMoneyOverflow
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoney(amount, currency), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.currency() != other.currency() {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency(), other.currency())
+	}
+	return nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return NewMoney(m.Amount+other.Amount, m.currency()), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m Money) LessThan(other Money) (bool, error) {
+	c, err := m.Cmp(other)
+	return c < 0, err
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// String formats the amount as a decimal string without the currency, e.g. "-12.50".
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	// Work on the unsigned value so math.MinInt64 does not overflow.
+	abs := uint64(amount)
+	if amount < 0 {
+		abs = uint64(-(amount + 1)) + 1
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, abs/minorUnitsPerMajor, abs%minorUnitsPerMajor)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts both a decimal string ("100.50") and a bare JSON
+// number (100.50). Numbers are parsed from their literal text, never through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = NewMoney(0, m.Currency)
+		return nil
+	}
+
+	if len(data) == 0 {
+		return ErrInvalidMoney
+	}
+
+	raw := string(data)
+	switch {
+	case data[0] == '"':
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	case data[0] != '-' && (data[0] < '0' || data[0] > '9'):
+		// true, false, objects and arrays.
+		return fmt.Errorf("%w: expected a decimal string or number", ErrInvalidMoney)
+	case strings.ContainsAny(raw, "eE"):
+		return fmt.Errorf("%w: exponent notation is not supported", ErrInvalidMoney)
+	}
+
+	parsed, err := ParseMoney(raw, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads a DECIMAL column. The MySQL driver returns DECIMAL as []byte.
+func (m *Money) Scan(src interface{}) error {
+	var (
+		parsed Money
+		err    error
+	)
+
+	switch v := src.(type) {
+	case nil:
+		parsed = NewMoney(0, m.Currency)
+	case []byte:
+		parsed, err = ParseMoney(string(v), m.Currency)
+	case string:
+		parsed, err = ParseMoney(v, m.Currency)
+	case int64:
+		if v > math.MaxInt64/minorUnitsPerMajor || v < math.MinInt64/minorUnitsPerMajor {
+			return ErrMoneyOverflow
+		}
+		parsed = NewMoney(v*minorUnitsPerMajor, m.Currency)
+	case float64:
+		parsed, err = ParseMoney(strconv.FormatFloat(v, 'f', minorUnitScale, 64), m.Currency)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
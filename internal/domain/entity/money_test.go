@@ -0,0 +1,250 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  error
+	}{
+		{in: "0", want: 0},
+		{in: "1500", want: 150000},
+		{in: "10000.00", want: 1000000},
+		{in: "-12.5", want: -1250},
+		{in: "+0.01", want: 1},
+		{in: ".5", want: 50},
+		{in: " 7.25 ", want: 725},
+		{in: "92233720368547758.07", want: math.MaxInt64},
+		{in: "92233720368547758.08", err: ErrMoneyOverflow},
+		{in: "92233720368547759", err: ErrMoneyOverflow},
+		{in: "99999999999999999999", err: ErrMoneyOverflow},
+		{in: "", err: ErrInvalidMoney},
+		{in: "-", err: ErrInvalidMoney},
+		{in: ".", err: ErrInvalidMoney},
+		{in: "1.", err: ErrInvalidMoney},
+		{in: "1.234", err: ErrInvalidMoney},
+		{in: "1,00", err: ErrInvalidMoney},
+		{in: "--1", err: ErrInvalidMoney},
+		{in: "1e3", err: ErrInvalidMoney},
+		{in: "abc", err: ErrInvalidMoney},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in, "")
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ParseMoney(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != DefaultCurrency {
+			t.Errorf("ParseMoney(%q) = %+v, want %d %s", tt.in, got, tt.want, DefaultCurrency)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func() (Money, error)
+		want int64
+		err  error
+	}{
+		{
+			name: "add",
+			op:   func() (Money, error) { return NewMoney(150, "").Add(NewMoney(-50, "")) },
+			want: 100,
+		},
+		{
+			name: "sub",
+			op:   func() (Money, error) { return NewMoney(150, "").Sub(NewMoney(200, "")) },
+			want: -50,
+		},
+		{
+			name: "add reaches max",
+			op:   func() (Money, error) { return NewMoney(math.MaxInt64-1, "").Add(NewMoney(1, "")) },
+			want: math.MaxInt64,
+		},
+		{
+			name: "add overflows",
+			op:   func() (Money, error) { return NewMoney(math.MaxInt64, "").Add(NewMoney(1, "")) },
+			err:  ErrMoneyOverflow,
+		},
+		{
+			name: "add underflows",
+			op:   func() (Money, error) { return NewMoney(math.MinInt64, "").Add(NewMoney(-1, "")) },
+			err:  ErrMoneyOverflow,
+		},
+		{
+			name: "sub overflows",
+			op:   func() (Money, error) { return NewMoney(math.MaxInt64, "").Sub(NewMoney(-1, "")) },
+			err:  ErrMoneyOverflow,
+		},
+		{
+			name: "sub min int",
+			op:   func() (Money, error) { return NewMoney(0, "").Sub(NewMoney(math.MinInt64, "")) },
+			err:  ErrMoneyOverflow,
+		},
+		{
+			name: "currency mismatch",
+			op:   func() (Money, error) { return NewMoney(1, "IDR").Add(NewMoney(1, "USD")) },
+			err:  ErrCurrencyMismatch,
+		},
+		{
+			name: "empty currency is the default",
+			op:   func() (Money, error) { return Money{Amount: 1}.Add(NewMoney(1, DefaultCurrency)) },
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.op()
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if got.Amount != tt.want {
+			t.Errorf("%s: amount = %d, want %d", tt.name, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: -1250, want: "-12.50"},
+		{amount: 1000000, want: "10000.00"},
+		{amount: math.MaxInt64, want: "92233720368547758.07"},
+		{amount: math.MinInt64, want: "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		if got := NewMoney(tt.amount, "").String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{in: `"100.50"`, want: 10050},
+		{in: `100.50`, want: 10050},
+		{in: `-3`, want: -300},
+		{in: `null`, want: 0},
+		{in: `1e3`, err: true},
+		{in: `100.001`, err: true},
+		{in: `true`, err: true},
+		{in: `false`, err: true},
+		{in: `{}`, err: true},
+		{in: `[1]`, err: true},
+		{in: `"abc"`, err: true},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %+v, want an error", tt.in, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if m.Amount != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, m.Amount, tt.want)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte("true"), &m); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Unmarshal(true) error = %v, want %v", err, ErrInvalidMoney)
+	}
+
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{NewMoney(-1250, "")})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"amount":"-12.50"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	var decoded struct {
+		Amount Money `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Amount.Amount != -1250 {
+		t.Errorf("round trip = %+v, %v", decoded.Amount, err)
+	}
+}
+
+func TestMoneySQL(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want int64
+		err  bool
+	}{
+		{src: []byte("10000.00"), want: 1000000},
+		{src: "-0.05", want: -5},
+		{src: int64(12), want: 1200},
+		{src: float64(3.1), want: 310},
+		{src: nil, want: 0},
+		{src: int64(math.MaxInt64), err: true},
+		{src: []byte("1.234"), err: true},
+		{src: true, err: true},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := m.Scan(tt.src)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %+v, want an error", tt.src, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Scan(%#v) error = %v", tt.src, err)
+			continue
+		}
+		if m.Amount != tt.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tt.src, m.Amount, tt.want)
+		}
+	}
+
+	value, err := NewMoney(-1250, "").Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	var m Money
+	if err := m.Scan([]byte(value.(string))); err != nil || m.Amount != -1250 {
+		t.Errorf("round trip = %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,12 @@
+package entity
+
+const (
+	OTPPurposePinReset     = "pin_reset"
+	OTPPurposeRegistration = "registration"
+	OTPPurposeNewDevice    = "new_device"
+)
+
+type OTPRecord struct {
+	Hash     string `json:"hash"`
+	Attempts int    `json:"attempts"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	OutboxStatusPending   = "PENDING"
+	OutboxStatusPublished = "PUBLISHED"
+	// Held messages wait for a transaction review. They move to
+	// OutboxStatusPending when released, or to OutboxStatusDiscarded.
+	OutboxStatusHeld      = "HELD"
+	OutboxStatusDiscarded = "DISCARDED"
+)
+
+// OutboxMessage is a job written in the same database transaction as the
+// state change that needs it, and relayed to the job queue after commit.
+type OutboxMessage struct {
+	ID          uint64                 `json:"id"`
+	JobName     string                 `json:"job_name"`
+	Args        map[string]interface{} `json:"args"`
+	Status      string                 `json:"status"`
+	Attempts    int                    `json:"attempts"`
+	LastError   string                 `json:"last_error"`
+	AvailableAt time.Time              `json:"available_at"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
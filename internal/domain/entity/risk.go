@@ -0,0 +1,96 @@
+package entity
+
+import "time"
+
+// Risk outcomes in increasing order of severity. REVIEW holds the
+// transaction until staff release or reject it, BLOCK fails it at once.
+const (
+	RiskOutcomeAllow  = "ALLOW"
+	RiskOutcomeReview = "REVIEW"
+	RiskOutcomeBlock  = "BLOCK"
+)
+
+const (
+	RiskRuleNewDeviceLargeAmount   = "NEW_DEVICE_LARGE_AMOUNT"
+	RiskRuleNewRecipientBurst      = "NEW_RECIPIENT_BURST"
+	RiskRuleTransferAfterPinReset  = "TRANSFER_AFTER_PIN_RESET"
+	RiskRuleRoundAmountStructuring = "ROUND_AMOUNT_STRUCTURING"
+	RiskRuleSanctionsMatch         = "SANCTIONS_MATCH"
+)
+
+// RiskSeverity orders outcomes so the most severe hit decides.
+func RiskSeverity(outcome string) int {
+	switch outcome {
+	case RiskOutcomeBlock:
+		return 2
+	case RiskOutcomeReview:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RiskInput is what the risk engine knows about a transaction that is about
+// to be queued. The signals are gathered beforehand, so rules do no I/O.
+type RiskInput struct {
+	TransactionID string
+	Kind          string
+	UserID        string
+	TargetUser    string
+	Amount        Money
+	SessionID     string
+	CreatedAt     time.Time
+
+	// DeviceFirstSeenAt is when the device of the session first signed in,
+	// zero when the request has no session.
+	DeviceFirstSeenAt time.Time
+	// PinResetAt is the last PIN reset, zero when there was none.
+	PinResetAt time.Time
+	// NewRecipient is set for transfers to a user never paid before.
+	NewRecipient bool
+	// CounterpartyMatches are the watchlist entries the recipient of a
+	// transfer resembles.
+	CounterpartyMatches []ScreeningMatch
+	// History holds the earlier assessed transactions of the user, newest
+	// first, going back as far as the rules look.
+	History []*RiskDecision
+}
+
+type RiskRuleHit struct {
+	Rule    string `json:"rule"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason"`
+}
+
+// RiskDecision is stored against every transaction that was assessed.
+type RiskDecision struct {
+	TransactionID string        `json:"transaction_id"`
+	UserID        string        `json:"user_id"`
+	Kind          string        `json:"kind"`
+	TargetUser    string        `json:"target_user,omitempty"`
+	Amount        Money         `json:"amount"`
+	NewRecipient  bool          `json:"new_recipient"`
+	Outcome       string        `json:"outcome"`
+	Hits          []RiskRuleHit `json:"hits"`
+	CreatedAt     time.Time     `json:"created_at"`
+	// Set once staff released or rejected a held transaction.
+	ReviewerID string     `json:"reviewer_id,omitempty"`
+	ReviewNote string     `json:"review_note,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+type RiskDecisionFilter struct {
+	Outcome string
+	UserID  string
+	// Unreviewed limits REVIEW decisions to those still waiting for staff.
+	Unreviewed bool
+	Limit      int
+}
+
+type ReviewTransactionRequest struct {
+	// Note is required when rejecting.
+	Note          string `json:"note"`
+	TransactionID string `json:"-"`
+	ActorID       string `json:"-"`
+	IPAddress     string `json:"-"`
+}
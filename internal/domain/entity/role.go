@@ -0,0 +1,23 @@
+package entity
+
+// Roles a user can hold. Every registered user starts as a customer; staff
+// roles are granted with `./main set-role` or PUT /admin/users/:user_id/role.
+const (
+	RoleCustomer   = "customer"
+	RoleSupport    = "support"
+	RoleFinanceOps = "finance-ops"
+	RoleAdmin      = "admin"
+)
+
+type SetRoleRequest struct {
+	Role      string `json:"role" binding:"required"`
+	UserID    string `json:"-"`
+	ActorID   string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// UserAccount is a user together with the wallet balance, as shown to staff.
+type UserAccount struct {
+	*User
+	Balance Money `json:"balance"`
+}
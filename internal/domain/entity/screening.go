@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// Screening triggers: when a name was checked against the watchlist.
+const (
+	ScreeningTriggerRegistration  = "REGISTRATION"
+	ScreeningTriggerProfileUpdate = "PROFILE_UPDATE"
+	// ScreeningTriggerCounterparty is the recipient of a transfer.
+	ScreeningTriggerCounterparty = "COUNTERPARTY"
+)
+
+// ScreeningMatch is a watchlist entry that a name resembles.
+type ScreeningMatch struct {
+	EntryID string `json:"entry_id"`
+	// EntryVersion identifies the listing the name was matched against.
+	EntryVersion string   `json:"entry_version,omitempty"`
+	ListedName   string   `json:"listed_name"`
+	Programs     []string `json:"programs,omitempty"`
+	// Score is the Jaro-Winkler similarity, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// ScreeningClearance records a watchlist entry that staff found not to be
+// the user when closing a screening case. The user is not flagged for that
+// entry again until its listing changes.
+type ScreeningClearance struct {
+	UserID       string
+	EntryID      string
+	EntryVersion string
+	CaseID       string
+	ClearedBy    string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Session is one login on one device. Its id is also the refresh token family
+// and the sid claim of every token issued for it.
+type Session struct {
+	SessionID  string     `json:"session_id"`
+	UserID     string     `json:"-"`
+	DeviceID   string     `json:"device_id"`
+	DeviceName string     `json:"device_name"`
+	Platform   string     `json:"platform"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"`
+}
@@ -2,73 +2,139 @@ package entity
 
 import "time"
 
+const (
+	TransactionTypeCredit = "CREDIT"
+	TransactionTypeDebit  = "DEBIT"
+
+	// Transaction kinds are what a user started; limits are set per kind.
+	TransactionKindTopUp    = "TOPUP"
+	TransactionKindPayment  = "PAYMENT"
+	TransactionKindTransfer = "TRANSFER"
+
+	TransactionStatusPending = "PENDING"
+	TransactionStatusSuccess = "SUCCESS"
+	TransactionStatusFailed  = "FAILED"
+	// TransactionStatusInReview is held by the risk engine and is not queued
+	// until staff release it.
+	TransactionStatusInReview = "IN_REVIEW"
+
+	// FailureReasonProcessingError is recorded when a worker gave up on a job
+	// after exhausting its retries. The other reasons are business rule
+	// failures that are recorded on the first attempt and never retried.
+	FailureReasonProcessingError   = "PROCESSING_ERROR"
+	FailureReasonInsufficientFunds = "INSUFFICIENT_FUNDS"
+	FailureReasonTargetNotFound    = "TARGET_NOT_FOUND"
+	FailureReasonInvalidTarget     = "INVALID_TARGET"
+	FailureReasonWalletNotFound    = "WALLET_NOT_FOUND"
+	FailureReasonInvalidAmount     = "INVALID_AMOUNT"
+	FailureReasonCurrencyMismatch  = "CURRENCY_MISMATCH"
+	// FailureReasonAccountRestricted and FailureReasonTargetRestricted mean
+	// the status of the wallet or the receiving wallet did not allow the
+	// movement when it was applied.
+	FailureReasonAccountRestricted = "ACCOUNT_RESTRICTED"
+	FailureReasonTargetRestricted  = "TARGET_RESTRICTED"
+	// FailureReasonBalanceCap and FailureReasonTargetBalanceCap mean the
+	// credit would have taken the wallet past the balance cap of its tier.
+	FailureReasonBalanceCap       = "BALANCE_CAP_EXCEEDED"
+	FailureReasonTargetBalanceCap = "TARGET_BALANCE_CAP_EXCEEDED"
+	// FailureReasonRiskBlocked and FailureReasonRiskRejected mean the risk
+	// engine blocked the transaction or staff rejected it after review.
+	FailureReasonRiskBlocked  = "RISK_BLOCKED"
+	FailureReasonRiskRejected = "RISK_REJECTED"
+)
+
 type Transaction struct {
 	ID            uint      `json:"id"`
 	TransactionID string    `json:"transaction_id"`
 	UserID        string    `json:"user_id"`
 	Type          string    `json:"type"`
-	Amount        float64   `json:"amount"`
-	BalanceBefore float64   `json:"balance_before"`
-	BalanceAfter  float64   `json:"balance_after"`
+	Amount        Money     `json:"amount"`
+	BalanceBefore Money     `json:"balance_before"`
+	BalanceAfter  Money     `json:"balance_after"`
 	Description   string    `json:"description"`
 	Status        string    `json:"status"`
+	FailureReason string    `json:"failure_reason,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// IsFinal reports whether the transaction has left the PENDING state.
+func (t *Transaction) IsFinal() bool {
+	return t.Status == TransactionStatusSuccess || t.Status == TransactionStatusFailed
+}
+
 type TopUpRequest struct {
-	Amount float64 `json:"amount"`
+	Amount Money `json:"amount"`
 }
 
 type TopUpResponse struct {
 	TopUpID       string    `json:"top_up_id"`
-	AmountTopUp   float64   `json:"amount_top_up"`
-	BalanceBefore float64   `json:"balance_before"`
-	BalanceAfter  float64   `json:"balance_after"`
+	AmountTopUp   Money     `json:"amount_top_up"`
+	BalanceBefore Money     `json:"balance_before"`
+	BalanceAfter  Money     `json:"balance_after"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 type PublishTopUpRequest struct {
-	TopUpID string  `json:"top_up_id"`
-	UserID  string  `json:"user_id"`
-	Amount  float64 `json:"amount"`
+	TopUpID   string `json:"top_up_id"`
+	UserID    string `json:"user_id"`
+	Amount    Money  `json:"amount"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+	SessionID string `json:"-"`
 }
 
 type PaymentRequest struct {
-	PaymentID string  `json:"payment_id"`
-	UserID  string  `json:"user_id"`
-	Amount  float64 `json:"amount"`
-	Remarks string  `json:"remarks"`
+	PaymentID string `json:"payment_id"`
+	UserID    string `json:"user_id"`
+	Amount    Money  `json:"amount"`
+	Remarks   string `json:"remarks"`
+	// Pin or ConfirmationToken authorize the payment, and OTPCode is the
+	// TOTP or recovery code above the step-up threshold. None of them is
+	// published to the job queue.
+	Pin               string `json:"pin,omitempty"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+	OTPCode           string `json:"otp_code,omitempty"`
+	IPAddress         string `json:"-"`
+	UserAgent         string `json:"-"`
+	SessionID         string `json:"-"`
 }
 
 type PaymentResponse struct {
 	PaymentID     string    `json:"payment_id"`
-	Amount        float64   `json:"amount"`
+	Amount        Money     `json:"amount"`
 	Remarks       string    `json:"remarks"`
-	BalanceBefore float64   `json:"balance_before"`
-	BalanceAfter  float64   `json:"balance_after"`
+	BalanceBefore Money     `json:"balance_before"`
+	BalanceAfter  Money     `json:"balance_after"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 type TransferRequest struct {
-	TransferID string `json:"transfer_id"`
+	TransferID       string `json:"transfer_id"`
 	TargetTransferID string `json:"target_transfer_id"`
-	UserID  string  `json:"user_id"`
-	TargetUser string  `json:"target_user"`
-	Amount     float64 `json:"amount"`
-	Remarks    string  `json:"remarks"`
+	UserID           string `json:"user_id"`
+	TargetUser       string `json:"target_user"`
+	Amount           Money  `json:"amount"`
+	Remarks          string `json:"remarks"`
+
+	Pin               string `json:"pin,omitempty"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+	OTPCode           string `json:"otp_code,omitempty"`
+	IPAddress         string `json:"-"`
+	UserAgent         string `json:"-"`
+	SessionID         string `json:"-"`
 }
 
 type StartTransferResponse struct {
-	TransferID string `json:"transfer_id"`
+	TransferID       string `json:"transfer_id"`
 	TargetTransferID string `json:"target_transfer_id"`
 }
 
 type TransferResponse struct {
 	TransferID    string    `json:"transfer_id"`
-	Amount        float64   `json:"amount"`
+	Amount        Money     `json:"amount"`
 	Remarks       string    `json:"remarks"`
-	BalanceBefore float64   `json:"balance_before"`
-	BalanceAfter  float64   `json:"balance_after"`
+	BalanceBefore Money     `json:"balance_before"`
+	BalanceAfter  Money     `json:"balance_after"`
 	CreatedAt     time.Time `json:"created_at"`
 }
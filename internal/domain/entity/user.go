@@ -2,16 +2,36 @@ package entity
 
 import "time"
 
+const (
+	UserVerificationStatusUnverified = "UNVERIFIED"
+	UserVerificationStatusVerified   = "VERIFIED"
+
+	// TierBasic is the tier of every new user. The tier selects the
+	// transaction limits that apply.
+	TierBasic = "BASIC"
+)
+
 type User struct {
-	ID          uint      `json:"id"`
-	UserID      string    `json:"user_id"`
-	PhoneNumber string    `json:"phone_number"`
-	Pin         string    `json:"-"`
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	Address     string    `json:"address"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 uint      `json:"id"`
+	UserID             string    `json:"user_id"`
+	PhoneNumber        string    `json:"phone_number"`
+	Pin                string    `json:"-"`
+	FirstName          string    `json:"first_name"`
+	LastName           string    `json:"last_name"`
+	Address            string    `json:"address"`
+	VerificationStatus string    `json:"verification_status"`
+	TOTPSecret         string    `json:"-"`
+	TOTPEnabled        bool      `json:"totp_enabled"`
+	Role               string    `json:"role"`
+	Status             string    `json:"status"`
+	StatusReason       string    `json:"status_reason,omitempty"`
+	Tier               string    `json:"tier"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	// PinResetAt is the last PIN reset through a texted code, nil when there
+	// was none.
+	PinResetAt *time.Time `json:"-"`
 }
 
 type RegisterUserRequest struct {
@@ -22,9 +42,29 @@ type RegisterUserRequest struct {
 	Pin         string `json:"pin"`
 }
 
+type VerifyRegistrationRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
 type LoginRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	Pin         string `json:"pin"`
+	// OTPCode is a TOTP or recovery code, required once TOTP is enabled.
+	OTPCode string `json:"otp_code"`
+
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+	// DeviceOTP is the texted code for a new device when
+	// NEW_DEVICE_OTP_REQUIRED is set.
+	DeviceOTP string `json:"device_otp"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LoginResponse struct {
@@ -37,6 +77,8 @@ type UpdateProfileRequest struct {
 	LastName  string `json:"last_name"`
 	Address   string `json:"address"`
 	UserID    string `json:"-"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type UpdateProfileResponse struct {
@@ -46,3 +88,42 @@ type UpdateProfileResponse struct {
 	Address   string    `json:"address"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type ChangePinRequest struct {
+	OldPin string `json:"old_pin" binding:"required"`
+	NewPin string `json:"new_pin" binding:"required"`
+	UserID string `json:"-"`
+	// IPAddress lets failed old PIN checks count towards the login lockout.
+	IPAddress string `json:"-"`
+}
+
+type PinResetRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+type PinResetConfirmRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPin      string `json:"new_pin" binding:"required"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code   string `json:"code" binding:"required"`
+	UserID string `json:"-"`
+}
+
+type DisableTOTPRequest struct {
+	Code      string `json:"code" binding:"required"`
+	Pin       string `json:"pin" binding:"required"`
+	UserID    string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type TOTPActivateResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
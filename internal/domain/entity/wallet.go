@@ -5,7 +5,8 @@ import "time"
 type Wallet struct {
 	ID        int       `json:"id"`
 	UserID    string    `json:"user_id"`
-	Balance   float64   `json:"balance"`
+	Balance   Money     `json:"balance"`
+	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
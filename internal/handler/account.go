@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+// AccountHandler serves the account status endpoints under /admin/users.
+type AccountHandler struct {
+	AccountService service.IAccountService
+}
+
+func (h *AccountHandler) SetStatus(c *gin.Context) {
+	var req entity.AccountStatusRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	err := h.AccountService.SetStatus(&req)
+	if respondAccountError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AccountHandler) Close(c *gin.Context) {
+	var req entity.CloseAccountRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	resp, err := h.AccountService.Close(&req)
+	if respondAccountError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": resp,
+	})
+}
+
+// respondAccountError writes the response for a failed status change and
+// reports whether err was not nil.
+func respondAccountError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrInvalidAccountStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrAccountClosed), errors.Is(err, service.ErrPayoutRequired), errors.Is(err, service.ErrOpenCases):
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+	return true
+}
+
+// respondAccountRestricted writes a 403 response when err is an
+// AccountRestrictedError and reports whether it did.
+func respondAccountRestricted(c *gin.Context, err error) bool {
+	var restricted *service.AccountRestrictedError
+	if !errors.As(err, &restricted) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"message": err.Error(), "code": restricted.Code()})
+	return true
+}
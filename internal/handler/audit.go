@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+type AuditHandler struct {
+	AuditService service.IAuditService
+}
+
+func (h *AuditHandler) FindEvents(c *gin.Context) {
+	filter, err := auditEventFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	events, err := h.AuditService.FindEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": events,
+	})
+}
+
+func auditEventFilterFromQuery(c *gin.Context) (filter entity.AuditEventFilter, err error) {
+	filter.Type = c.Query("type")
+	filter.ActorID = c.Query("actor_id")
+	filter.UserID = c.Query("user_id")
+	filter.TargetID = c.Query("target_id")
+
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("from must be an RFC 3339 time")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("to must be an RFC 3339 time")
+		}
+	}
+	if value := c.Query("before_sequence"); value != "" {
+		if filter.BeforeSequence, err = strconv.ParseInt(value, 10, 64); err != nil || filter.BeforeSequence < 1 {
+			return filter, errors.New("before_sequence must be a positive number")
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			return filter, errors.New("limit must be a positive number")
+		}
+	}
+
+	return filter, nil
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/leonardoong/e-wallet/internal/domain/entity"
 	"github.com/leonardoong/e-wallet/internal/service"
 )
@@ -17,6 +21,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
 	}
 
 	user, err := h.AuthService.Register(&req)
@@ -28,16 +33,59 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"status": "SUCCESS",
 		"result": gin.H{
-			"user_id":      user.UserID,
-			"first_name":   user.FirstName,
-			"last_name":    user.LastName,
-			"phone_number": user.PhoneNumber,
-			"address":      user.Address,
-			"created_date": user.CreatedAt,
+			"user_id":             user.UserID,
+			"first_name":          user.FirstName,
+			"last_name":           user.LastName,
+			"phone_number":        user.PhoneNumber,
+			"address":             user.Address,
+			"created_date":        user.CreatedAt,
+			"verification_status": user.VerificationStatus,
 		},
 	})
 }
 
+func (h *AuthHandler) VerifyRegistration(c *gin.Context) {
+	var req entity.VerifyRegistrationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := h.AuthService.VerifyRegistration(&req)
+	if errors.Is(err, service.ErrInvalidOTP) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req entity.ResendVerificationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := h.AuthService.ResendVerification(&req)
+	if errors.Is(err, service.ErrOTPRateLimited) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "SUCCESS"})
+}
+
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req entity.LoginRequest
 
@@ -46,7 +94,46 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
 	loginResponse, err := h.AuthService.Login(&req)
+	if respondLoginBlocked(c, err) || respondAccountRestricted(c, err) {
+		return
+	}
+	if respondMFAError(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrDeviceVerificationRequired) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error(), "code": service.ErrorCodeDeviceVerificationRequired})
+		return
+	}
+	if errors.Is(err, service.ErrPhoneNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error(), "code": "PHONE_NOT_VERIFIED"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"data":   loginResponse,
+	})
+}
+
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req entity.RefreshTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.IPAddress = c.ClientIP()
+
+	loginResponse, err := h.AuthService.RefreshToken(&req)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
 		return
@@ -58,6 +145,190 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("token_claims")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "token claims not found"})
+		return
+	}
+
+	if err := h.AuthService.Logout(claims.(jwt.MapClaims)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+
+	if err := h.AuthService.RevokeAllSessions(userID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+// RevokeUserSessions is the admin variant of LogoutAll for any user_id.
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user id mandatory"})
+		return
+	}
+
+	if err := h.AuthService.RevokeAllSessions(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) UnlockLogin(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user id mandatory"})
+		return
+	}
+
+	err := h.AuthService.UnlockLogin(userID)
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) ChangePin(c *gin.Context) {
+	var req entity.ChangePinRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
+
+	err := h.AuthService.ChangePin(&req)
+	if respondLoginBlocked(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrWrongPin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) RequestPinReset(c *gin.Context) {
+	var req entity.PinResetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := h.AuthService.RequestPinReset(&req)
+	if errors.Is(err, service.ErrOTPRateLimited) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) ConfirmPinReset(c *gin.Context) {
+	var req entity.PinResetConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := h.AuthService.ConfirmPinReset(&req)
+	if errors.Is(err, service.ErrInvalidOTP) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+
+	sessions, err := h.AuthService.ListSessions(userID.(string), c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": sessions,
+	})
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+
+	err := h.AuthService.RevokeSession(userID.(string), c.Param("session_id"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.AuthService.JWKS())
+}
+
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	var req entity.UpdateProfileRequest
 
@@ -69,8 +340,11 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id not found"})
+		return
 	}
 	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 
 	profileResp, err := h.AuthService.UpdateProfile(&req)
 	if err != nil {
@@ -83,3 +357,25 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		"data":   profileResp,
 	})
 }
+
+// respondLoginBlocked writes a 423 or 429 response when err is a
+// LoginBlockedError and reports whether it did.
+func respondLoginBlocked(c *gin.Context, err error) bool {
+	var blocked *service.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	status := http.StatusTooManyRequests
+	if blocked.Code == service.ErrorCodeAccountLocked {
+		status = http.StatusLocked
+	}
+	retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(status, gin.H{
+		"message":     err.Error(),
+		"code":        blocked.Code,
+		"retry_after": retryAfter,
+	})
+	return true
+}
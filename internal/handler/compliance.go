@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+type ComplianceHandler struct {
+	ComplianceService service.IComplianceService
+	// MaxUploadBytes bounds each attachment.
+	MaxUploadBytes int
+}
+
+func (h *ComplianceHandler) Create(c *gin.Context) {
+	var req entity.CreateCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	complianceCase, err := h.ComplianceService.CreateCase(&req)
+	if respondCaseError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "SUCCESS",
+		"result": complianceCase,
+	})
+}
+
+func (h *ComplianceHandler) FindCases(c *gin.Context) {
+	filter := entity.ComplianceCaseFilter{
+		Status:        strings.ToUpper(c.Query("status")),
+		Source:        strings.ToUpper(c.Query("source")),
+		UserID:        c.Query("user_id"),
+		AssigneeID:    c.Query("assignee_id"),
+		TransactionID: c.Query("transaction_id"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	cases, err := h.ComplianceService.FindCases(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": cases,
+	})
+}
+
+func (h *ComplianceHandler) FindCase(c *gin.Context) {
+	complianceCase, err := h.ComplianceService.FindCase(c.Param("case_id"))
+	if respondCaseError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": complianceCase,
+	})
+}
+
+func (h *ComplianceHandler) Assign(c *gin.Context) {
+	var req entity.AssignCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.CaseID = c.Param("case_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	if respondCaseError(c, h.ComplianceService.Assign(&req)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *ComplianceHandler) SetStatus(c *gin.Context) {
+	var req entity.CaseStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.Status = strings.ToUpper(req.Status)
+	req.CaseID = c.Param("case_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	if respondCaseError(c, h.ComplianceService.SetStatus(&req)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *ComplianceHandler) LinkTransaction(c *gin.Context) {
+	var req entity.LinkCaseTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.CaseID = c.Param("case_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	if respondCaseError(c, h.ComplianceService.LinkTransaction(&req)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *ComplianceHandler) AddNote(c *gin.Context) {
+	var req entity.AddCaseNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.CaseID = c.Param("case_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	note, err := h.ComplianceService.AddNote(&req)
+	if respondCaseError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "SUCCESS",
+		"result": note,
+	})
+}
+
+// AddAttachment takes a multipart form with the upload in the file field.
+func (h *ComplianceHandler) AddAttachment(c *gin.Context) {
+	// The file plus room for the form encoding.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(h.MaxUploadBytes)+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "file is required"})
+		return
+	}
+	if header.Size > int64(h.MaxUploadBytes) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "file must not be larger than " + strconv.Itoa(h.MaxUploadBytes) + " bytes"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, int64(h.MaxUploadBytes)+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	attachment, err := h.ComplianceService.AddAttachment(&entity.AddCaseAttachmentRequest{
+		CaseID:    c.Param("case_id"),
+		ActorID:   c.GetString("user_id"),
+		FileName:  header.Filename,
+		Content:   content,
+		IPAddress: c.ClientIP(),
+	})
+	if respondCaseError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "SUCCESS",
+		"result": attachment,
+	})
+}
+
+func (h *ComplianceHandler) Attachment(c *gin.Context) {
+	reader, attachment, err := h.ComplianceService.OpenAttachment(c.Param("case_id"), c.Param("attachment_id"))
+	if respondCaseError(c, err) {
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+	})
+}
+
+func (h *ComplianceHandler) Close(c *gin.Context) {
+	var req entity.CloseCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.CaseID = c.Param("case_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	resp, err := h.ComplianceService.CloseCase(&req)
+	if respondCaseError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": resp,
+	})
+}
+
+// respondCaseError writes the response for a failed case request and reports
+// whether err was not nil.
+func respondCaseError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrCaseNotFound),
+		errors.Is(err, service.ErrCaseAttachmentNotFound),
+		errors.Is(err, service.ErrUserNotFound),
+		errors.Is(err, service.ErrTransactionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrCaseClosed),
+		errors.Is(err, service.ErrCaseTransition),
+		errors.Is(err, service.ErrOtherCasesOpen):
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrCaseSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrCaseNoteRequired),
+		errors.Is(err, service.ErrClosingNoteTooLong),
+		errors.Is(err, service.ErrNotScreeningCase),
+		errors.Is(err, service.ErrCaseSummaryRequired),
+		errors.Is(err, service.ErrInvalidCaseStatus),
+		errors.Is(err, service.ErrInvalidAssignee),
+		errors.Is(err, service.ErrInvalidCaseAttachment),
+		errors.Is(err, service.ErrCaseAttachmentTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+	return true
+}
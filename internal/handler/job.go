@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+type JobHandler struct {
+	JobService service.IJobService
+}
+
+func (h *JobHandler) ListDeadJobs(c *gin.Context) {
+	filter, err := deadJobFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	jobs, err := h.JobService.ListDeadJobs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": jobs,
+	})
+}
+
+func (h *JobHandler) RetryDeadJob(c *gin.Context) {
+	diedAt, err := strconv.ParseInt(c.Param("died_at"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "died_at must be a unix timestamp"})
+		return
+	}
+
+	err = h.JobService.RetryDeadJob(diedAt, c.Param("job_id"))
+	if errors.Is(err, service.ErrDeadJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *JobHandler) DiscardDeadJob(c *gin.Context) {
+	diedAt, err := strconv.ParseInt(c.Param("died_at"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "died_at must be a unix timestamp"})
+		return
+	}
+
+	err = h.JobService.DiscardDeadJob(diedAt, c.Param("job_id"))
+	if errors.Is(err, service.ErrDeadJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+func (h *JobHandler) RetryDeadJobs(c *gin.Context) {
+	filter, err := deadJobFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	result, err := h.JobService.RetryDeadJobs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": result,
+	})
+}
+
+func (h *JobHandler) DiscardDeadJobs(c *gin.Context) {
+	filter, err := deadJobFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	result, err := h.JobService.DiscardDeadJobs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": result,
+	})
+}
+
+func (h *JobHandler) Stats(c *gin.Context) {
+	stats, err := h.JobService.Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": stats,
+	})
+}
+
+// deadJobFilterFromQuery reads ?name=, ?error=, ?died_after= and ?died_before=.
+// Times are RFC 3339.
+func deadJobFilterFromQuery(c *gin.Context) (filter entity.DeadJobFilter, err error) {
+	filter.Name = c.Query("name")
+	filter.ErrorContains = c.Query("error")
+
+	if value := c.Query("died_after"); value != "" {
+		if filter.DiedAfter, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("died_after must be an RFC 3339 time")
+		}
+	}
+	if value := c.Query("died_before"); value != "" {
+		if filter.DiedBefore, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("died_before must be an RFC 3339 time")
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+type KYCHandler struct {
+	KYCService service.IKYCService
+	// MaxImageBytes bounds each uploaded image.
+	MaxImageBytes int
+}
+
+func (h *KYCHandler) Status(c *gin.Context) {
+	status, err := h.KYCService.Status(c.GetString("user_id"))
+	if respondKYCError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": status,
+	})
+}
+
+// Submit takes a multipart form with id_type, id_number, the id_image and
+// selfie files and optional selfie_metadata as a JSON object.
+func (h *KYCHandler) Submit(c *gin.Context) {
+	// Two images plus room for the other fields.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(2*h.MaxImageBytes)+1<<20)
+
+	req := entity.SubmitKYCRequest{
+		UserID:    c.GetString("user_id"),
+		IDType:    strings.ToUpper(c.PostForm("id_type")),
+		IDNumber:  c.PostForm("id_number"),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	if metadata := c.PostForm("selfie_metadata"); metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &req.SelfieMetadata); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "selfie_metadata must be a JSON object"})
+			return
+		}
+	}
+
+	var err error
+	if req.IDImage, err = h.readImage(c, entity.KYCDocumentIDImage); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if req.Selfie, err = h.readImage(c, entity.KYCDocumentSelfie); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	submission, err := h.KYCService.Submit(&req)
+	if respondKYCError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "SUCCESS",
+		"result": submission,
+	})
+}
+
+func (h *KYCHandler) readImage(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, errors.New(field + " file is required")
+	}
+	if header.Size > int64(h.MaxImageBytes) {
+		return nil, errors.New(field + " must not be larger than " + strconv.Itoa(h.MaxImageBytes) + " bytes")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, int64(h.MaxImageBytes)+1))
+}
+
+func (h *KYCHandler) FindSubmissions(c *gin.Context) {
+	filter := entity.KYCSubmissionFilter{
+		Status: strings.ToUpper(c.Query("status")),
+		UserID: c.Query("user_id"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	submissions, err := h.KYCService.FindSubmissions(filter)
+	if respondKYCError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": submissions,
+	})
+}
+
+func (h *KYCHandler) FindSubmission(c *gin.Context) {
+	submission, err := h.KYCService.FindSubmission(c.Param("submission_id"))
+	if respondKYCError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": submission,
+	})
+}
+
+func (h *KYCHandler) Document(c *gin.Context) {
+	reader, contentType, err := h.KYCService.OpenDocument(c.Param("submission_id"), c.Param("document"))
+	if respondKYCError(c, err) {
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, nil)
+}
+
+func (h *KYCHandler) Approve(c *gin.Context) {
+	h.review(c, h.KYCService.Approve)
+}
+
+func (h *KYCHandler) Reject(c *gin.Context) {
+	h.review(c, h.KYCService.Reject)
+}
+
+func (h *KYCHandler) review(c *gin.Context, review func(*entity.ReviewKYCRequest) error) {
+	var req entity.ReviewKYCRequest
+
+	// The note is optional when approving, so an empty body is fine.
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	req.SubmissionID = c.Param("submission_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	if respondKYCError(c, review(&req)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+// respondKYCError writes the response for a failed KYC request and reports
+// whether err was not nil.
+func respondKYCError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrUserNotFound),
+		errors.Is(err, service.ErrKYCSubmissionNotFound),
+		errors.Is(err, service.ErrKYCDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrKYCPending),
+		errors.Is(err, service.ErrKYCAlreadyFull),
+		errors.Is(err, service.ErrKYCAlreadyReviewed):
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrKYCSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrKYCNoteRequired),
+		errors.Is(err, service.ErrInvalidIDType),
+		errors.Is(err, service.ErrInvalidIDNumber),
+		errors.Is(err, service.ErrInvalidKYCImage),
+		errors.Is(err, service.ErrKYCImageTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+type MFAHandler struct {
+	MFAService  service.IMFAService
+	AuthService service.IAuthService
+}
+
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+
+	enrollment, err := h.MFAService.EnrollTOTP(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": enrollment,
+	})
+}
+
+func (h *MFAHandler) ActivateTOTP(c *gin.Context) {
+	var req entity.TOTPCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+	req.UserID = userID.(string)
+
+	activation, err := h.MFAService.ActivateTOTP(&req)
+	if respondMFAError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": activation,
+	})
+}
+
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	var req entity.DisableTOTPRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return
+	}
+	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
+
+	err := h.AuthService.DisableTOTP(&req)
+	if respondLoginBlocked(c, err) || respondMFAError(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrWrongPin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+// respondMFAError writes a 401 response for missing or wrong two-factor
+// codes and reports whether it did.
+func respondMFAError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrMFARequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error(), "code": service.ErrorCodeMFARequired})
+	case errors.Is(err, service.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+// RiskHandler serves the risk decisions and the review of held transactions.
+type RiskHandler struct {
+	RiskService        service.IRiskService
+	TransactionService service.ITransactionService
+}
+
+func (h *RiskHandler) FindDecisions(c *gin.Context) {
+	filter := entity.RiskDecisionFilter{
+		Outcome: strings.ToUpper(c.Query("outcome")),
+		UserID:  c.Query("user_id"),
+	}
+	if value := c.Query("unreviewed"); value != "" {
+		unreviewed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "unreviewed must be true or false"})
+			return
+		}
+		filter.Unreviewed = unreviewed
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	decisions, err := h.RiskService.FindDecisions(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": decisions,
+	})
+}
+
+func (h *RiskHandler) FindDecision(c *gin.Context) {
+	decision, err := h.RiskService.FindDecision(c.Param("transaction_id"))
+	if respondReviewError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": decision,
+	})
+}
+
+func (h *RiskHandler) Release(c *gin.Context) {
+	h.review(c, h.TransactionService.ReleaseTransaction)
+}
+
+func (h *RiskHandler) Reject(c *gin.Context) {
+	h.review(c, h.TransactionService.RejectTransaction)
+}
+
+func (h *RiskHandler) review(c *gin.Context, review func(*entity.ReviewTransactionRequest) error) {
+	var req entity.ReviewTransactionRequest
+
+	// The note is optional when releasing, so an empty body is fine.
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	req.TransactionID = c.Param("transaction_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	if respondReviewError(c, review(&req)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
+
+// respondReviewError writes the response for a failed review request and
+// reports whether err was not nil.
+func respondReviewError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrTransactionNotFound), errors.Is(err, service.ErrRiskDecisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrTransactionNotHeld), errors.Is(err, service.ErrTransactionInCase):
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+	case errors.Is(err, service.ErrReviewNoteRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+	return true
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/leonardoong/e-wallet/internal/domain/entity"
@@ -9,7 +12,8 @@ import (
 )
 
 type TransactionHandler struct {
-	TransactionService service.ITransactionService
+	TransactionService  service.ITransactionService
+	ConfirmationService service.IConfirmationService
 }
 
 func (h *TransactionHandler) TopUp(c *gin.Context) {
@@ -17,19 +21,27 @@ func (h *TransactionHandler) TopUp(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
 	}
 
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id not found"})
+		return
 	}
 
 	payload := entity.PublishTopUpRequest{
-		Amount: req.Amount,
-		UserID: userID.(string),
+		Amount:    req.Amount,
+		UserID:    userID.(string),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		SessionID: c.GetString("session_id"),
 	}
 
 	topUpID, err := h.TransactionService.StartTopUp(&payload)
+	if respondAccountRestricted(c, err) || respondLimitExceeded(c, err) || respondRiskBlocked(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
@@ -50,15 +62,8 @@ func (h *TransactionHandler) FindTopUp(c *gin.Context) {
 		return
 	}
 
-	_, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
-		return
-	}
-
-	transaction, err := h.TransactionService.FindTransactionByID(topUpID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	transaction, ok := h.findTransaction(c, topUpID)
+	if !ok {
 		return
 	}
 
@@ -67,8 +72,10 @@ func (h *TransactionHandler) FindTopUp(c *gin.Context) {
 		"result": gin.H{
 			"top_up_id":      transaction.TransactionID,
 			"amount_top_up":  transaction.Amount,
-			"balance_before": transaction.BalanceBefore,
-			"balance_after":  transaction.BalanceAfter,
+			"balance_before": settledBalance(transaction, transaction.BalanceBefore),
+			"balance_after":  settledBalance(transaction, transaction.BalanceAfter),
+			"status":         transaction.Status,
+			"failure_reason": transaction.FailureReason,
 			"created_date":   transaction.CreatedAt,
 		},
 	})
@@ -79,16 +86,24 @@ func (h *TransactionHandler) Payment(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
 	}
 
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id not found"})
+		return
 	}
 
 	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.SessionID = c.GetString("session_id")
 
 	paymentID, err := h.TransactionService.StartPayment(&req)
+	if respondAuthorizationError(c, err) || respondLimitExceeded(c, err) || respondRiskBlocked(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
@@ -109,26 +124,21 @@ func (h *TransactionHandler) FindPayment(c *gin.Context) {
 		return
 	}
 
-	_, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
-		return
-	}
-
-	transaction, err := h.TransactionService.FindTransactionByID(paymentID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	transaction, ok := h.findTransaction(c, paymentID)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "SUCCESS",
 		"result": gin.H{
-			"payment_id":      transaction.TransactionID,
-			"amount":  transaction.Amount,
-			"balance_before": transaction.BalanceBefore,
-			"balance_after":  transaction.BalanceAfter,
-			"remarks": transaction.Description,
+			"payment_id":     transaction.TransactionID,
+			"amount":         transaction.Amount,
+			"balance_before": settledBalance(transaction, transaction.BalanceBefore),
+			"balance_after":  settledBalance(transaction, transaction.BalanceAfter),
+			"status":         transaction.Status,
+			"failure_reason": transaction.FailureReason,
+			"remarks":        transaction.Description,
 			"created_date":   transaction.CreatedAt,
 		},
 	})
@@ -139,16 +149,24 @@ func (h *TransactionHandler) Transfer(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
 	}
 
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id not found"})
+		return
 	}
 
 	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.SessionID = c.GetString("session_id")
 
 	transfer, err := h.TransactionService.StartTransfer(&req)
+	if respondAuthorizationError(c, err) || respondLimitExceeded(c, err) || respondRiskBlocked(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
@@ -167,28 +185,54 @@ func (h *TransactionHandler) FindTransfer(c *gin.Context) {
 		return
 	}
 
-	_, exists := c.Get("user_id")
+	transaction, ok := h.findTransaction(c, transferID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": gin.H{
+			"transfer_id":    transaction.TransactionID,
+			"amount":         transaction.Amount,
+			"balance_before": settledBalance(transaction, transaction.BalanceBefore),
+			"balance_after":  settledBalance(transaction, transaction.BalanceAfter),
+			"status":         transaction.Status,
+			"failure_reason": transaction.FailureReason,
+			"remarks":        transaction.Description,
+			"created_date":   transaction.CreatedAt,
+		},
+	})
+}
+
+func (h *TransactionHandler) Confirm(c *gin.Context) {
+	var req entity.TransactionConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
 		return
 	}
+	req.UserID = userID.(string)
+	req.IPAddress = c.ClientIP()
 
-	transaction, err := h.TransactionService.FindTransactionByID(transferID)
+	confirmation, err := h.ConfirmationService.Confirm(&req)
+	if respondAuthorizationError(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusCreated, gin.H{
 		"status": "SUCCESS",
-		"result": gin.H{
-			"transfer_id":      transaction.TransactionID,
-			"amount":  transaction.Amount,
-			"balance_before": transaction.BalanceBefore,
-			"balance_after":  transaction.BalanceAfter,
-			"remarks": transaction.Description,
-			"created_date":   transaction.CreatedAt,
-		},
+		"result": confirmation,
 	})
 }
 
@@ -199,7 +243,22 @@ func (h *TransactionHandler) FindTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions, err := h.TransactionService.FindTransactionsByUserID(userID.(string))
+	h.respondTransactions(c, userID.(string))
+}
+
+// FindUserTransactions is the admin variant of FindTransactions for any user_id.
+func (h *TransactionHandler) FindUserTransactions(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user id mandatory"})
+		return
+	}
+
+	h.respondTransactions(c, userID)
+}
+
+func (h *TransactionHandler) respondTransactions(c *gin.Context, userID string) {
+	transactions, err := h.TransactionService.FindTransactionsByUserID(userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
@@ -207,12 +266,14 @@ func (h *TransactionHandler) FindTransactions(c *gin.Context) {
 
 	resultTransactions := []gin.H{}
 	for _, transaction := range transactions {
-		resultTransactions = append(resultTransactions,gin.H{
-			"payment_id":      transaction.TransactionID,
-			"amount":  	transaction.Amount,
-			"balance_before": transaction.BalanceBefore,
-			"balance_after":  transaction.BalanceAfter,
-			"remarks": transaction.Description,
+		resultTransactions = append(resultTransactions, gin.H{
+			"payment_id":     transaction.TransactionID,
+			"amount":         transaction.Amount,
+			"balance_before": settledBalance(transaction, transaction.BalanceBefore),
+			"balance_after":  settledBalance(transaction, transaction.BalanceAfter),
+			"status":         transaction.Status,
+			"failure_reason": transaction.FailureReason,
+			"remarks":        transaction.Description,
 			"created_date":   transaction.CreatedAt,
 		})
 	}
@@ -221,4 +282,105 @@ func (h *TransactionHandler) FindTransactions(c *gin.Context) {
 		"status": "SUCCESS",
 		"result": resultTransactions,
 	})
-}
\ No newline at end of file
+}
+
+// findTransaction loads a transaction of the current user. With ?wait=N it
+// long-polls for up to N seconds until the transaction is SUCCESS or FAILED.
+func (h *TransactionHandler) findTransaction(c *gin.Context, transactionID string) (*entity.Transaction, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id not found"})
+		return nil, false
+	}
+
+	var (
+		transaction *entity.Transaction
+		err         error
+	)
+	if wait := c.Query("wait"); wait != "" {
+		seconds, convErr := strconv.Atoi(wait)
+		if convErr != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "wait must be a non-negative number of seconds"})
+			return nil, false
+		}
+		transaction, err = h.TransactionService.WaitForTransaction(c.Request.Context(), userID.(string), transactionID, time.Duration(seconds)*time.Second)
+	} else {
+		transaction, err = h.TransactionService.FindUserTransactionByID(userID.(string), transactionID)
+	}
+
+	if errors.Is(err, service.ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return nil, false
+	}
+
+	return transaction, true
+}
+
+// settledBalance hides balances of transactions that were never applied.
+func settledBalance(transaction *entity.Transaction, balance entity.Money) interface{} {
+	if transaction.Status != entity.TransactionStatusSuccess {
+		return nil
+	}
+	return balance
+}
+
+// respondAuthorizationError handles the account status, PIN, confirmation
+// token, lockout and two-factor errors of money-moving requests and reports whether it did.
+func respondAuthorizationError(c *gin.Context, err error) bool {
+	if respondAccountRestricted(c, err) || respondLoginBlocked(c, err) || respondMFAError(c, err) {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, service.ErrConfirmationRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error(), "code": service.ErrorCodeConfirmationRequired})
+	case errors.Is(err, service.ErrWrongPin), errors.Is(err, service.ErrInvalidConfirmationToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// respondLimitExceeded reports a broken transaction limit with the allowance
+// that is left and reports whether it did.
+func respondLimitExceeded(c *gin.Context, err error) bool {
+	var exceeded *service.LimitExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+
+	body := gin.H{
+		"message": err.Error(),
+		"code":    exceeded.Code,
+		"limit":   exceeded.Limit,
+	}
+	if exceeded.Remaining != "" {
+		body["remaining"] = exceeded.Remaining
+	}
+	if !exceeded.ResetAt.IsZero() {
+		body["reset_at"] = exceeded.ResetAt
+	}
+	c.JSON(http.StatusUnprocessableEntity, body)
+	return true
+}
+
+// respondRiskBlocked writes a 403 response when the risk engine blocked the
+// transaction and reports whether it did.
+func respondRiskBlocked(c *gin.Context, err error) bool {
+	var blocked *service.RiskBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"message":        err.Error(),
+		"code":           service.ErrorCodeRiskBlocked,
+		"transaction_id": blocked.TransactionID,
+	})
+	return true
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/service"
+	"github.com/leonardoong/e-wallet/internal/utils"
+)
+
+// UserHandler serves the staff endpoints under /admin/users.
+type UserHandler struct {
+	UserService service.IUserService
+}
+
+func (h *UserHandler) FindUser(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user id mandatory"})
+		return
+	}
+
+	account, err := h.UserService.FindUser(userID)
+	respondUserAccount(c, account, err)
+}
+
+func (h *UserHandler) FindUserByPhoneNumber(c *gin.Context) {
+	phoneNumber := c.Query("phone_number")
+	if phoneNumber == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "phone_number mandatory"})
+		return
+	}
+
+	account, err := h.UserService.FindUserByPhoneNumber(phoneNumber)
+	respondUserAccount(c, account, err)
+}
+
+func respondUserAccount(c *gin.Context, account *entity.UserAccount, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, utils.ErrInvalidPhoneNumber) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "SUCCESS",
+		"result": account,
+	})
+}
+
+func (h *UserHandler) SetRole(c *gin.Context) {
+	var req entity.SetRoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	req.UserID = c.Param("user_id")
+	req.ActorID = c.GetString("user_id")
+	req.IPAddress = c.ClientIP()
+
+	err := h.UserService.SetRole(&req)
+	if errors.Is(err, service.ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	respondStaffAction(c, err)
+}
+
+func respondStaffAction(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "SUCCESS"})
+}
@@ -0,0 +1,184 @@
+// Package jwtkeys holds the keys used to sign and verify JWTs. Tokens are
+// signed with one asymmetric key (RS256 or EdDSA) and carry its kid, while
+// every configured key is accepted for verification so keys can be rotated
+// without logging users out. Without a signing key it falls back to HS256
+// with a shared secret.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+type KeySet struct {
+	signing    *key
+	keys       map[string]*key
+	hmacSecret []byte
+}
+
+// Load reads the signing key and any additional verification keys from PEM
+// files. Verification files may hold public or private keys; the signing key
+// is always accepted for verification too.
+func Load(signingKeyFile string, verificationKeyFiles []string, hmacSecret string) (*KeySet, error) {
+	set := &KeySet{
+		keys:       map[string]*key{},
+		hmacSecret: []byte(hmacSecret),
+	}
+
+	if signingKeyFile != "" {
+		k, err := loadKey(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if k.private == nil {
+			return nil, fmt.Errorf("%s: signing key must be a private key", signingKeyFile)
+		}
+		set.signing = k
+		set.keys[k.id] = k
+	}
+
+	for _, file := range verificationKeyFiles {
+		k, err := loadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[k.id] = k
+	}
+
+	if set.signing == nil && len(set.keys) > 0 {
+		return nil, errors.New("verification keys are configured without a signing key")
+	}
+
+	return set, nil
+}
+
+// Sign signs claims with the signing key, or with HS256 when none is
+// configured.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	token.Header["kid"] = s.signing.id
+	return token.SignedString(s.signing.private)
+}
+
+// Keyfunc picks the verification key for a token by its kid header and makes
+// sure the token uses that key's algorithm.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if s.signing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return k.public, nil
+}
+
+// JWKS returns the public verification keys. It is empty in HS256 mode since
+// the shared secret must never be published.
+func (s *KeySet) JWKS() entity.JSONWebKeySet {
+	set := entity.JSONWebKeySet{Keys: []entity.JSONWebKey{}}
+	for _, k := range s.keys {
+		set.Keys = append(set.Keys, jwk(k))
+	}
+	return set
+}
+
+func loadKey(file string) (*key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &key{}
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		k.method, k.private, k.public = jwt.SigningMethodRS256, private, &private.PublicKey
+	} else if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		k.method, k.private, k.public = jwt.SigningMethodEdDSA, private, private.(ed25519.PrivateKey).Public()
+	} else if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		k.method, k.public = jwt.SigningMethodRS256, public
+	} else if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		k.method, k.public = jwt.SigningMethodEdDSA, public
+	} else {
+		return nil, fmt.Errorf("%s: not an RSA or Ed25519 key in PEM format", file)
+	}
+
+	if rsaKey, ok := k.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", file)
+	}
+
+	k.id = thumbprint(jwk(k))
+	return k, nil
+}
+
+func jwk(k *key) entity.JSONWebKey {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		return entity.JSONWebKey{
+			Kty: "RSA",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			N:   encode(public.N.Bytes()),
+			E:   encode(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return entity.JSONWebKey{
+			Kty: "OKP",
+			Kid: k.id,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			Crv: "Ed25519",
+			X:   encode(public),
+		}
+	}
+	return entity.JSONWebKey{}
+}
+
+// thumbprint is the RFC 7638 thumbprint of a key, used as its kid so key ids
+// never have to be configured by hand.
+func thumbprint(k entity.JSONWebKey) string {
+	var members map[string]string
+	if k.Kty == "RSA" {
+		members = map[string]string{"e": k.E, "kty": k.Kty, "n": k.N}
+	} else {
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X}
+	}
+
+	// encoding/json sorts map keys, which gives the required member order.
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
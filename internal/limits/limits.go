@@ -0,0 +1,210 @@
+// Package limits holds the transaction limit rules. They are read from a JSON
+// file that is reloaded whenever it changes, so limits can be tuned without a
+// restart.
+package limits
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// DefaultTier holds the rules for tiers that do not list a transaction kind
+// or a balance cap.
+const DefaultTier = "default"
+
+// Rules maps a tier and a transaction kind to its limits, and a tier to the
+// most its wallets may hold, e.g.
+//
+//	{"tiers": {"default": {"PAYMENT": {"max_amount": "5000000.00", "hourly_count": 10}}},
+//	 "balance_caps": {"BASIC": "2000000.00"}}
+type Rules struct {
+	Tiers       map[string]map[string]entity.LimitRule `json:"tiers"`
+	BalanceCaps map[string]entity.Money                `json:"balance_caps"`
+}
+
+// Rule returns the limits of kind for tier, falling back to DefaultTier. ok
+// is false when neither lists kind, i.e. kind is not limited.
+func (r *Rules) Rule(tier, kind string) (rule entity.LimitRule, ok bool) {
+	if r == nil {
+		return entity.LimitRule{}, false
+	}
+	if rule, ok = r.Tiers[tier][kind]; ok {
+		return rule, true
+	}
+	rule, ok = r.Tiers[DefaultTier][kind]
+	return rule, ok
+}
+
+// BalanceCap returns the balance cap of tier, falling back to DefaultTier. ok
+// is false when neither has one.
+func (r *Rules) BalanceCap(tier string) (balanceCap entity.Money, ok bool) {
+	if r == nil {
+		return entity.Money{}, false
+	}
+	if balanceCap, ok = r.BalanceCaps[tier]; ok {
+		return balanceCap, true
+	}
+	balanceCap, ok = r.BalanceCaps[DefaultTier]
+	return balanceCap, ok
+}
+
+// Load reads and validates a rules file.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	rules := &Rules{}
+	if err := decoder.Decode(rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+func (r *Rules) validate() error {
+	for tier, kinds := range r.Tiers {
+		for kind, rule := range kinds {
+			switch kind {
+			case entity.TransactionKindTopUp, entity.TransactionKindPayment, entity.TransactionKindTransfer:
+			default:
+				return fmt.Errorf("tier %s: unknown transaction kind %q", tier, kind)
+			}
+
+			if rule.MinAmount.IsNegative() || rule.MaxAmount.IsNegative() || rule.DailyAmount.IsNegative() ||
+				rule.MonthlyAmount.IsNegative() || rule.HourlyCount < 0 {
+				return fmt.Errorf("tier %s, %s: limits must not be negative", tier, kind)
+			}
+			if !rule.MaxAmount.IsZero() {
+				belowMin, err := rule.MaxAmount.LessThan(rule.MinAmount)
+				if err != nil {
+					return fmt.Errorf("tier %s, %s: %w", tier, kind, err)
+				}
+				if belowMin {
+					return fmt.Errorf("tier %s, %s: max_amount is below min_amount", tier, kind)
+				}
+			}
+		}
+	}
+
+	for tier, balanceCap := range r.BalanceCaps {
+		if !balanceCap.IsPositive() {
+			return fmt.Errorf("balance cap of tier %s must be greater than zero", tier)
+		}
+	}
+	return nil
+}
+
+// Store holds the rules currently in force. Without a file nothing is
+// limited.
+type Store struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	rules   *Rules
+	modTime time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStore loads the rules file at path, if any, and reloads it every
+// interval once started.
+func NewStore(path string, interval time.Duration) (*Store, error) {
+	s := &Store{
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if path == "" {
+		return s, nil
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Rules() *Rules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// Reload loads the file again if its modification time changed. On error the
+// previous rules stay in force.
+func (s *Store) Reload() (reloaded bool, err error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := s.rules != nil && info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	rules, err := Load(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Start polls the rules file for changes. It does nothing without a file.
+func (s *Store) Start() {
+	if s.path == "" {
+		close(s.done)
+		return
+	}
+	go s.run()
+}
+
+func (s *Store) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.Reload()
+		if err != nil {
+			log.Printf("limits: keeping previous rules: %v", err)
+		} else if reloaded {
+			log.Printf("limits: reloaded %s", s.path)
+		}
+	}
+}
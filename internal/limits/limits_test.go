@@ -0,0 +1,235 @@
+package limits
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+func money(t *testing.T, s string) entity.Money {
+	t.Helper()
+	m, err := entity.ParseMoney(s, "")
+	if err != nil {
+		t.Fatalf("ParseMoney(%q): %v", s, err)
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+		err   string
+	}{
+		{
+			name: "valid",
+			rules: Rules{
+				Tiers: map[string]map[string]entity.LimitRule{
+					DefaultTier: {
+						entity.TransactionKindTopUp:    {MinAmount: money(t, "10000"), MaxAmount: money(t, "10000000"), HourlyCount: 10},
+						entity.TransactionKindPayment:  {MinAmount: money(t, "1")},
+						entity.TransactionKindTransfer: {DailyAmount: money(t, "1000000"), MonthlyAmount: money(t, "5000000")},
+					},
+				},
+				BalanceCaps: map[string]entity.Money{"BASIC": money(t, "2000000")},
+			},
+		},
+		{
+			name:  "empty",
+			rules: Rules{},
+		},
+		{
+			name: "max equals min",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindTopUp: {MinAmount: money(t, "5"), MaxAmount: money(t, "5")}},
+			}},
+		},
+		{
+			name: "zero max is unlimited",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindTopUp: {MinAmount: money(t, "5")}},
+			}},
+		},
+		{
+			name: "unknown kind",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {"WITHDRAWAL": {}},
+			}},
+			err: `unknown transaction kind "WITHDRAWAL"`,
+		},
+		{
+			name: "negative amount",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindPayment: {DailyAmount: money(t, "-1")}},
+			}},
+			err: "must not be negative",
+		},
+		{
+			name: "negative count",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindPayment: {HourlyCount: -1}},
+			}},
+			err: "must not be negative",
+		},
+		{
+			name: "max below min",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindPayment: {MinAmount: money(t, "10"), MaxAmount: money(t, "9.99")}},
+			}},
+			err: "max_amount is below min_amount",
+		},
+		{
+			name: "currency mismatch",
+			rules: Rules{Tiers: map[string]map[string]entity.LimitRule{
+				"BASIC": {entity.TransactionKindPayment: {MinAmount: entity.NewMoney(1, "USD"), MaxAmount: entity.NewMoney(2, "IDR")}},
+			}},
+			err: "currency mismatch",
+		},
+		{
+			name:  "zero balance cap",
+			rules: Rules{BalanceCaps: map[string]entity.Money{"BASIC": entity.NewMoney(0, "")}},
+			err:   "balance cap of tier BASIC must be greater than zero",
+		},
+	}
+
+	for _, tt := range tests {
+		err := tt.rules.validate()
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: validate() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: validate() = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRuleFallback(t *testing.T) {
+	rules := &Rules{
+		Tiers: map[string]map[string]entity.LimitRule{
+			DefaultTier: {
+				entity.TransactionKindTopUp:   {HourlyCount: 10},
+				entity.TransactionKindPayment: {HourlyCount: 20},
+			},
+			"BASIC": {entity.TransactionKindTopUp: {HourlyCount: 5}},
+		},
+		BalanceCaps: map[string]entity.Money{
+			DefaultTier: money(t, "100"),
+			"BASIC":     money(t, "50"),
+		},
+	}
+
+	tests := []struct {
+		tier, kind string
+		count      int64
+		ok         bool
+	}{
+		{tier: "BASIC", kind: entity.TransactionKindTopUp, count: 5, ok: true},
+		{tier: "BASIC", kind: entity.TransactionKindPayment, count: 20, ok: true},
+		{tier: "FULL", kind: entity.TransactionKindTopUp, count: 10, ok: true},
+		{tier: "BASIC", kind: entity.TransactionKindTransfer},
+	}
+	for _, tt := range tests {
+		rule, ok := rules.Rule(tt.tier, tt.kind)
+		if ok != tt.ok || rule.HourlyCount != tt.count {
+			t.Errorf("Rule(%s, %s) = %d, %v, want %d, %v", tt.tier, tt.kind, rule.HourlyCount, ok, tt.count, tt.ok)
+		}
+	}
+
+	if balanceCap, ok := rules.BalanceCap("BASIC"); !ok || balanceCap.String() != "50.00" {
+		t.Errorf("BalanceCap(BASIC) = %s, %v", balanceCap, ok)
+	}
+	if balanceCap, ok := rules.BalanceCap("FULL"); !ok || balanceCap.String() != "100.00" {
+		t.Errorf("BalanceCap(FULL) = %s, %v", balanceCap, ok)
+	}
+	if _, ok := (&Rules{}).BalanceCap("FULL"); ok {
+		t.Error("BalanceCap without caps reported a cap")
+	}
+
+	var none *Rules
+	if _, ok := none.Rule("BASIC", entity.TransactionKindTopUp); ok {
+		t.Error("nil rules limit a transaction")
+	}
+	if _, ok := none.BalanceCap("BASIC"); ok {
+		t.Error("nil rules cap a balance")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := Load("../../limits.json")
+	if err != nil {
+		t.Fatalf("Load(limits.json): %v", err)
+	}
+	if rule, ok := rules.Rule("BASIC", entity.TransactionKindTransfer); !ok || rule.MaxAmount.String() != "500000.00" {
+		t.Errorf("BASIC transfer rule = %+v, %v", rule, ok)
+	}
+
+	if _, err := Load(write("unknown.json", `{"tiers": {}, "caps": {}}`)); err == nil {
+		t.Error("Load accepted an unknown field")
+	}
+	if _, err := Load(write("invalid.json", `{"tiers": {"BASIC": {"TOPUP": {"max_amount": "1.234"}}}}`)); err == nil {
+		t.Error("Load accepted an amount with three decimals")
+	}
+	if _, err := Load(write("negative.json", `{"balance_caps": {"BASIC": "-1"}}`)); err == nil {
+		t.Error("Load accepted a negative balance cap")
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"balance_caps": {"BASIC": "10"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if reloaded, err := store.Reload(); reloaded || err != nil {
+		t.Errorf("Reload of an unchanged file = %v, %v", reloaded, err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte(`{"balance_caps": {"BASIC": "0"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if _, err := store.Reload(); err == nil {
+		t.Error("Reload accepted invalid rules")
+	}
+	if balanceCap, _ := store.Rules().BalanceCap("BASIC"); balanceCap.String() != "10.00" {
+		t.Errorf("previous rules were replaced, cap = %s", balanceCap)
+	}
+
+	later = later.Add(time.Minute)
+	if err := os.WriteFile(path, []byte(`{"balance_caps": {"BASIC": "20"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if reloaded, err := store.Reload(); !reloaded || err != nil {
+		t.Errorf("Reload = %v, %v", reloaded, err)
+	}
+	if balanceCap, _ := store.Rules().BalanceCap("BASIC"); balanceCap.String() != "20.00" {
+		t.Errorf("cap = %s, want 20.00", balanceCap)
+	}
+
+	empty, err := NewStore("", time.Hour)
+	if err != nil || empty.Rules() != nil {
+		t.Errorf("NewStore without a file = %v, %v", empty.Rules(), err)
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/service"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+
+	// idempotencyReservationTTL bounds how long a key stays IN_PROGRESS, so a
+	// request that never finishes, e.g. when the process dies, does not block
+	// the key for the full TTL. Save stores the response for the full TTL.
+	idempotencyReservationTTL = 5 * time.Minute
+)
+
+type IdempotencyMiddleware struct {
+	Repository repository.IIdempotencyRepository
+	TTL        time.Duration
+}
+
+// Handle replays the stored response when a request is retried with the same
+// Idempotency-Key. It must run after AuthRequired because keys are scoped per user.
+func (m IdempotencyMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "user_id not found"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		storeKey := "idempotency:" + userID + ":" + key
+		existing, reserved, err := m.Repository.Reserve(storeKey, entity.IdempotencyRecord{
+			RequestHash: requestHash,
+			Status:      entity.IdempotencyStatusInProgress,
+		}, idempotencyReservationTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to check Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.RequestHash != requestHash:
+				c.JSON(http.StatusConflict, gin.H{"message": "Idempotency-Key was already used with a different request"})
+			case existing.Status == entity.IdempotencyStatusInProgress:
+				c.JSON(http.StatusConflict, gin.H{"message": "a request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header(idempotencyReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		defer func() {
+			if r := recover(); r != nil {
+				m.Repository.Release(storeKey)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// Server errors and authentication challenges are not stored so the
+		// client can retry them with the same key, e.g. adding a missing
+		// two-factor code.
+		if !isStorable(recorder.Status(), recorder.body.Bytes()) {
+			if err := m.Repository.Release(storeKey); err != nil {
+				log.Printf("failed to release idempotency key %s: %v", storeKey, err)
+			}
+			return
+		}
+
+		err = m.Repository.Save(storeKey, entity.IdempotencyRecord{
+			RequestHash: requestHash,
+			Status:      entity.IdempotencyStatusCompleted,
+			StatusCode:  recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}, m.TTL)
+		if err != nil {
+			log.Printf("failed to store idempotent response for %s: %v", storeKey, err)
+		}
+	}
+}
+
+func isStorable(status int, body []byte) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusLocked, http.StatusTooManyRequests:
+		return false
+	case http.StatusForbidden:
+		// A blocked transaction was stored as FAILED, so retrying it must
+		// replay the block instead of starting another one. Other refusals,
+		// e.g. a frozen account, may pass once the account is cleared.
+		var response struct {
+			Code string `json:"code"`
+		}
+		return json.Unmarshal(body, &response) == nil && response.Code == service.ErrorCodeRiskBlocked
+	}
+	return status < http.StatusInternalServerError
+}
+
+// responseRecorder copies everything written to the client so it can be replayed.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
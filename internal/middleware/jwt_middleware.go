@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
 	"github.com/leonardoong/e-wallet/internal/service"
 )
 
@@ -37,8 +39,35 @@ func (m JWTMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// Suspending or closing an account also revokes its tokens, but that
+		// happens after the status is committed and may fail; the status in
+		// the database is what counts.
+		err = m.AuthService.CheckAccountStatus(claims["user_id"].(string))
+		var restricted *service.AccountRestrictedError
+		if errors.As(err, &restricted) {
+			c.JSON(http.StatusForbidden, gin.H{"message": err.Error(), "code": restricted.Code()})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to check account status"})
+			c.Abort()
+			return
+		}
+
 		c.Set("phone_number", claims["phone_number"].(string))
 		c.Set("user_id", claims["user_id"].(string))
+		c.Set("token_claims", claims)
+		sessionID, _ := claims["sid"].(string)
+		c.Set("session_id", sessionID)
+		mfa, _ := claims["mfa"].(bool)
+		c.Set("mfa", mfa)
+		// Tokens issued before roles existed belong to customers.
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = entity.RoleCustomer
+		}
+		c.Set("role", role)
 		c.Next()
 	}
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/rbac"
+)
+
+type RBACMiddleware struct {
+	AuditLogger audit.Logger
+}
+
+// RequirePermission lets the request through only when the role of the
+// caller is granted permission. It must run after AuthRequired, which sets
+// the role from the token. Denials are audited.
+func (m RBACMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if rbac.Allowed(role, permission) {
+			c.Next()
+			return
+		}
+
+		m.AuditLogger.Log(entity.AuditEvent{
+			Type:      entity.AuditEventPermissionDenied,
+			UserID:    c.GetString("user_id"),
+			IPAddress: c.ClientIP(),
+			Details: map[string]interface{}{
+				"role":       role,
+				"permission": permission,
+				"method":     c.Request.Method,
+				"path":       c.FullPath(),
+			},
+			CreatedAt: time.Now(),
+		})
+
+		c.JSON(http.StatusForbidden, gin.H{"message": "permission denied", "code": "PERMISSION_DENIED"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,76 @@
+// Package rbac maps roles to the permissions they are granted.
+package rbac
+
+import "github.com/leonardoong/e-wallet/internal/domain/entity"
+
+// Permissions guard the /admin routes. Customers hold none of them.
+const (
+	PermissionUsersRead      = "users:read"
+	PermissionWalletsRead    = "wallets:read"
+	PermissionAccountsStatus = "accounts:status"
+	PermissionAccountsClose  = "accounts:close"
+	PermissionSessionsRevoke = "sessions:revoke"
+	PermissionLoginsUnlock   = "logins:unlock"
+	PermissionRolesManage    = "roles:manage"
+	PermissionJobsRead       = "jobs:read"
+	PermissionJobsManage     = "jobs:manage"
+	PermissionAuditRead      = "audit:read"
+	PermissionKYCReview      = "kyc:review"
+	PermissionRiskReview     = "risk:review"
+	PermissionCasesManage    = "cases:manage"
+)
+
+var rolePermissions = map[string][]string{
+	entity.RoleCustomer: {},
+	entity.RoleSupport: {
+		PermissionUsersRead,
+		PermissionWalletsRead,
+		PermissionAccountsStatus,
+		PermissionSessionsRevoke,
+		PermissionLoginsUnlock,
+		PermissionAuditRead,
+		PermissionKYCReview,
+	},
+	entity.RoleFinanceOps: {
+		PermissionUsersRead,
+		PermissionWalletsRead,
+		PermissionAccountsStatus,
+		PermissionAccountsClose,
+		PermissionJobsRead,
+		PermissionJobsManage,
+		PermissionRiskReview,
+		PermissionCasesManage,
+	},
+	entity.RoleAdmin: {
+		PermissionUsersRead,
+		PermissionWalletsRead,
+		PermissionAccountsStatus,
+		PermissionAccountsClose,
+		PermissionSessionsRevoke,
+		PermissionLoginsUnlock,
+		PermissionRolesManage,
+		PermissionJobsRead,
+		PermissionJobsManage,
+		PermissionAuditRead,
+		PermissionKYCReview,
+		PermissionRiskReview,
+		PermissionCasesManage,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Allowed reports whether role is granted permission. Unknown roles are
+// granted nothing.
+func Allowed(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
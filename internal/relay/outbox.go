@@ -0,0 +1,112 @@
+package relay
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/publisher"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const maxRetryDelay = 5 * time.Minute
+
+// OutboxRelay moves committed outbox messages into the gocraft/work queue.
+// Delivery is at-least-once: if the process dies after enqueueing but before
+// the message is marked as published, the job is enqueued again, so workers
+// must tolerate duplicates.
+type OutboxRelay struct {
+	db               *sql.DB
+	outboxRepository repository.IOutboxRepository
+	publisher        *publisher.Publisher
+	interval         time.Duration
+	batchSize        int
+	stop             chan struct{}
+	done             chan struct{}
+}
+
+func NewOutboxRelay(db *sql.DB, outboxRepo repository.IOutboxRepository, publisher *publisher.Publisher, interval time.Duration, batchSize int) *OutboxRelay {
+	return &OutboxRelay{
+		db:               db,
+		outboxRepository: outboxRepo,
+		publisher:        publisher,
+		interval:         interval,
+		batchSize:        batchSize,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+func (r *OutboxRelay) Start() {
+	go r.run()
+}
+
+func (r *OutboxRelay) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *OutboxRelay) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches back to back, then wait for the next tick.
+		relayed, err := r.RelayBatch()
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if err == nil && relayed == r.batchSize {
+			select {
+			case <-r.stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes up to batchSize due messages and returns how many were
+// handled. A message that fails to publish is rescheduled with a backoff.
+func (r *OutboxRelay) RelayBatch() (handled int, err error) {
+	err = repository.WithTransaction(r.db, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		messages, err := r.outboxRepository.LockPending(tx, now, r.batchSize)
+		if err != nil {
+			return err
+		}
+		handled = len(messages)
+
+		for _, message := range messages {
+			if err := r.publisher.Enqueue(message.JobName, message.Args); err != nil {
+				log.Printf("outbox relay: failed to publish message %d (%s): %v", message.ID, message.JobName, err)
+				if err := r.outboxRepository.MarkAttemptFailed(tx, message.ID, err.Error(), now.Add(retryDelay(message.Attempts))); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := r.outboxRepository.MarkPublished(tx, message.ID, now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	return handled, err
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := time.Second << uint(min(attempts, 16))
+	return min(delay, maxRetryDelay)
+}
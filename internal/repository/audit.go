@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const (
+	defaultAuditEventLimit = 100
+	maxAuditEventLimit     = 500
+)
+
+type IAuditRepository interface {
+	// Append stores event at the end of the hash chain, setting its
+	// EventID, Sequence, PrevHash and Hash. Appends are serialized on the
+	// chain head row, so concurrent writers cannot fork the chain.
+	Append(event *entity.AuditEvent) error
+
+	// Find returns matching events, newest first.
+	Find(filter entity.AuditEventFilter) ([]*entity.AuditEvent, error)
+
+	// Head returns the sequence and hash of the last appended event.
+	Head() (sequence int64, hash string, err error)
+
+	// Walk calls fn for every event up to and including sequence upTo, in
+	// sequence order.
+	Walk(upTo int64, fn func(event *entity.AuditEvent) error) error
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) IAuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Append(event *entity.AuditEvent) error {
+	before, err := entity.CanonicalJSON(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := entity.CanonicalJSON(event.After)
+	if err != nil {
+		return err
+	}
+	details, err := entity.CanonicalJSON(event.Details)
+	if err != nil {
+		return err
+	}
+
+	if event.EventID == "" {
+		event.EventID = uuid.New().String()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Second)
+
+	return WithTransaction(r.db, func(tx *sql.Tx) error {
+		var lastSequence int64
+		var lastHash string
+		err := tx.QueryRow(`SELECT last_sequence, last_hash FROM audit_chain WHERE id = 1 FOR UPDATE`).Scan(&lastSequence, &lastHash)
+		if err != nil {
+			return fmt.Errorf("failed to lock audit chain: %w", err)
+		}
+
+		event.Sequence = lastSequence + 1
+		event.PrevHash = lastHash
+		event.Hash, err = event.ComputeHash()
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO audit_events (sequence, event_id, action, actor_id, user_id, target_id, phone_number, ip_address, user_agent, before_snapshot, after_snapshot, details, prev_hash, hash, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err = tx.Exec(query, event.Sequence, event.EventID, event.Type, event.ActorID, event.UserID, event.TargetID,
+			event.PhoneNumber, event.IPAddress, event.UserAgent, string(before), string(after), string(details),
+			event.PrevHash, event.Hash, event.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE audit_chain SET last_sequence = ?, last_hash = ? WHERE id = 1`, event.Sequence, event.Hash)
+		return err
+	})
+}
+
+const auditEventColumns = `sequence, event_id, action, actor_id, user_id, target_id, phone_number, ip_address, user_agent, before_snapshot, after_snapshot, details, prev_hash, hash, created_at`
+
+func scanAuditEvent(row rowScanner) (*entity.AuditEvent, error) {
+	event := &entity.AuditEvent{}
+	var before, after, details, createdAtStr string
+	err := row.Scan(&event.Sequence, &event.EventID, &event.Type, &event.ActorID, &event.UserID, &event.TargetID,
+		&event.PhoneNumber, &event.IPAddress, &event.UserAgent, &before, &after, &details,
+		&event.PrevHash, &event.Hash, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeAuditJSON(before, &event.Before); err != nil {
+		return nil, fmt.Errorf("failed to decode before_snapshot: %w", err)
+	}
+	if err := decodeAuditJSON(after, &event.After); err != nil {
+		return nil, fmt.Errorf("failed to decode after_snapshot: %w", err)
+	}
+	if err := decodeAuditJSON(details, &event.Details); err != nil {
+		return nil, fmt.Errorf("failed to decode details: %w", err)
+	}
+
+	event.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	return event, nil
+}
+
+// decodeAuditJSON keeps numbers as json.Number so the snapshots hash the same
+// as when they were written.
+func decodeAuditJSON(data string, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func (r *auditRepository) Find(filter entity.AuditEventFilter) ([]*entity.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	for column, value := range map[string]string{
+		"action":    filter.Type,
+		"actor_id":  filter.ActorID,
+		"user_id":   filter.UserID,
+		"target_id": filter.TargetID,
+	} {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.BeforeSequence > 0 {
+		conditions = append(conditions, "sequence < ?")
+		args = append(args, filter.BeforeSequence)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditEventLimit
+	}
+	if limit > maxAuditEventLimit {
+		limit = maxAuditEventLimit
+	}
+
+	query := `
+		SELECT ` + auditEventColumns + `
+		FROM audit_events
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY sequence DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*entity.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *auditRepository) Head() (sequence int64, hash string, err error) {
+	err = r.db.QueryRow(`SELECT last_sequence, last_hash FROM audit_chain WHERE id = 1`).Scan(&sequence, &hash)
+	return sequence, hash, err
+}
+
+func (r *auditRepository) Walk(upTo int64, fn func(event *entity.AuditEvent) error) error {
+	query := `
+		SELECT ` + auditEventColumns + `
+		FROM audit_events
+		WHERE sequence <= ?
+		ORDER BY sequence
+	`
+	rows, err := r.db.Query(query, upTo)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
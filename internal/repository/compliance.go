@@ -0,0 +1,457 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const (
+	defaultComplianceCaseLimit = 50
+	maxComplianceCaseLimit     = 200
+)
+
+type IComplianceRepository interface {
+	Create(tx *sql.Tx, complianceCase entity.ComplianceCase) error
+	// LinkTransaction is a no-op when the transaction is already linked.
+	LinkTransaction(tx *sql.Tx, caseID, transactionID string, linkedAt time.Time) error
+
+	// FindByID returns the case with its linked transactions.
+	FindByID(caseID string) (*entity.ComplianceCase, error)
+	FindNotes(caseID string) ([]*entity.CaseNote, error)
+	FindAttachments(caseID string) ([]*entity.CaseAttachment, error)
+	FindAttachment(caseID, attachmentID string) (*entity.CaseAttachment, error)
+
+	// Find returns matching cases. Cases that are not closed come oldest
+	// first when filtered by status, so they are worked in order; others
+	// newest first.
+	Find(filter entity.ComplianceCaseFilter) ([]*entity.ComplianceCase, error)
+
+	// FindOpen returns the oldest case of userID from source that is not
+	// closed, nil when there is none.
+	FindOpen(tx *sql.Tx, userID, source string) (*entity.ComplianceCase, error)
+	// CountOpen counts the cases of userID that are not closed, other than
+	// exceptCaseID.
+	CountOpen(tx *sql.Tx, userID, exceptCaseID string) (int, error)
+	// CountOpenForTransaction counts the cases linking transactionID that
+	// are not closed.
+	CountOpenForTransaction(tx *sql.Tx, transactionID string) (int, error)
+
+	Lock(tx *sql.Tx, caseID string) (*entity.ComplianceCase, error)
+	UpdateAssignee(tx *sql.Tx, caseID, assigneeID string, updatedAt time.Time) error
+	UpdateStatus(tx *sql.Tx, caseID, status string, updatedAt time.Time) error
+	// Touch sets updated_at, e.g. when a note is added.
+	Touch(tx *sql.Tx, caseID string, updatedAt time.Time) error
+	AddNote(tx *sql.Tx, note entity.CaseNote) error
+	AddAttachment(tx *sql.Tx, attachment entity.CaseAttachment) error
+	Close(tx *sql.Tx, caseID, closedBy, note string, closedAt time.Time) error
+
+	// AddClearance records a cleared watchlist entry for a user, replacing
+	// an earlier clearance of the same entry.
+	AddClearance(tx *sql.Tx, clearance entity.ScreeningClearance) error
+	// FindClearances returns the entry versions cleared for userID by entry
+	// id.
+	FindClearances(userID string) (map[string]string, error)
+}
+
+type complianceRepository struct {
+	db *sql.DB
+}
+
+func NewComplianceRepository(db *sql.DB) IComplianceRepository {
+	return &complianceRepository{db: db}
+}
+
+const complianceCaseColumns = `case_id, user_id, source, trigger_type, summary, status, assignee_id, matches, created_at, updated_at, closed_by, closing_note, closed_at`
+
+func scanComplianceCase(row rowScanner) (*entity.ComplianceCase, error) {
+	complianceCase := &entity.ComplianceCase{}
+	var matches, createdAtStr, updatedAtStr string
+	var assigneeID, closedBy, closingNote, closedAtStr sql.NullString
+	err := row.Scan(&complianceCase.CaseID, &complianceCase.UserID, &complianceCase.Source, &complianceCase.Trigger, &complianceCase.Summary,
+		&complianceCase.Status, &assigneeID, &matches, &createdAtStr, &updatedAtStr, &closedBy, &closingNote, &closedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(matches), &complianceCase.Matches); err != nil {
+		return nil, fmt.Errorf("failed to decode matches: %w", err)
+	}
+	complianceCase.AssigneeID = assigneeID.String
+	complianceCase.ClosedBy = closedBy.String
+	complianceCase.ClosingNote = closingNote.String
+
+	complianceCase.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	complianceCase.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
+	}
+
+	if closedAtStr.Valid {
+		closedAt, err := time.Parse("2006-01-02 15:04:05", closedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse closed_at: %w", err)
+		}
+		complianceCase.ClosedAt = &closedAt
+	}
+
+	return complianceCase, nil
+}
+
+func (r *complianceRepository) Create(tx *sql.Tx, complianceCase entity.ComplianceCase) error {
+	matches := complianceCase.Matches
+	if matches == nil {
+		matches = []entity.ScreeningMatch{}
+	}
+	encoded, err := json.Marshal(matches)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO compliance_cases (case_id, user_id, source, trigger_type, summary, status, matches, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, complianceCase.CaseID, complianceCase.UserID, complianceCase.Source, complianceCase.Trigger, complianceCase.Summary,
+		complianceCase.Status, string(encoded), complianceCase.CreatedAt, complianceCase.UpdatedAt)
+	return err
+}
+
+func (r *complianceRepository) LinkTransaction(tx *sql.Tx, caseID, transactionID string, linkedAt time.Time) error {
+	query := `
+		INSERT IGNORE INTO compliance_case_transactions (case_id, transaction_id, created_at)
+		VALUES (?, ?, ?)
+	`
+	_, err := tx.Exec(query, caseID, transactionID, linkedAt)
+	return err
+}
+
+func (r *complianceRepository) FindByID(caseID string) (*entity.ComplianceCase, error) {
+	query := `
+		SELECT ` + complianceCaseColumns + `
+		FROM compliance_cases
+		WHERE case_id = ?
+	`
+	complianceCase, err := scanComplianceCase(r.db.QueryRow(query, caseID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT transaction_id
+		FROM compliance_case_transactions
+		WHERE case_id = ?
+		ORDER BY created_at, transaction_id
+	`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID string
+		if err := rows.Scan(&transactionID); err != nil {
+			return nil, err
+		}
+		complianceCase.TransactionIDs = append(complianceCase.TransactionIDs, transactionID)
+	}
+
+	return complianceCase, rows.Err()
+}
+
+func (r *complianceRepository) Find(filter entity.ComplianceCaseFilter) ([]*entity.ComplianceCase, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, filter.Source)
+	}
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.AssigneeID != "" {
+		conditions = append(conditions, "assignee_id = ?")
+		args = append(args, filter.AssigneeID)
+	}
+	if filter.TransactionID != "" {
+		conditions = append(conditions, "case_id IN (SELECT case_id FROM compliance_case_transactions WHERE transaction_id = ?)")
+		args = append(args, filter.TransactionID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultComplianceCaseLimit
+	}
+	if limit > maxComplianceCaseLimit {
+		limit = maxComplianceCaseLimit
+	}
+
+	order := "DESC"
+	if filter.Status != "" && filter.Status != entity.CaseStatusClosed {
+		order = "ASC"
+	}
+
+	query := `
+		SELECT ` + complianceCaseColumns + `
+		FROM compliance_cases
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at " + order + ", id " + order + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cases := []*entity.ComplianceCase{}
+	for rows.Next() {
+		complianceCase, err := scanComplianceCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, complianceCase)
+	}
+
+	return cases, rows.Err()
+}
+
+func (r *complianceRepository) FindOpen(tx *sql.Tx, userID, source string) (*entity.ComplianceCase, error) {
+	query := `
+		SELECT ` + complianceCaseColumns + `
+		FROM compliance_cases
+		WHERE user_id = ? AND source = ? AND status <> ?
+		ORDER BY created_at, id
+		LIMIT 1
+	`
+	complianceCase, err := scanComplianceCase(tx.QueryRow(query, userID, source, entity.CaseStatusClosed))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return complianceCase, err
+}
+
+func (r *complianceRepository) CountOpen(tx *sql.Tx, userID, exceptCaseID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM compliance_cases
+		WHERE user_id = ? AND case_id <> ? AND status <> ?
+	`
+	var count int
+	err := tx.QueryRow(query, userID, exceptCaseID, entity.CaseStatusClosed).Scan(&count)
+	return count, err
+}
+
+func (r *complianceRepository) CountOpenForTransaction(tx *sql.Tx, transactionID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM compliance_case_transactions ct
+		JOIN compliance_cases c ON c.case_id = ct.case_id
+		WHERE ct.transaction_id = ? AND c.status <> ?
+	`
+	var count int
+	err := tx.QueryRow(query, transactionID, entity.CaseStatusClosed).Scan(&count)
+	return count, err
+}
+
+func (r *complianceRepository) Lock(tx *sql.Tx, caseID string) (*entity.ComplianceCase, error) {
+	query := `
+		SELECT ` + complianceCaseColumns + `
+		FROM compliance_cases
+		WHERE case_id = ?
+		FOR UPDATE
+	`
+	return scanComplianceCase(tx.QueryRow(query, caseID))
+}
+
+func (r *complianceRepository) UpdateAssignee(tx *sql.Tx, caseID, assigneeID string, updatedAt time.Time) error {
+	query := `
+		UPDATE compliance_cases
+		SET assignee_id = NULLIF(?, ''), updated_at = ?
+		WHERE case_id = ?
+	`
+	_, err := tx.Exec(query, assigneeID, updatedAt, caseID)
+	return err
+}
+
+func (r *complianceRepository) UpdateStatus(tx *sql.Tx, caseID, status string, updatedAt time.Time) error {
+	query := `
+		UPDATE compliance_cases
+		SET status = ?, updated_at = ?
+		WHERE case_id = ?
+	`
+	_, err := tx.Exec(query, status, updatedAt, caseID)
+	return err
+}
+
+func (r *complianceRepository) Touch(tx *sql.Tx, caseID string, updatedAt time.Time) error {
+	query := `
+		UPDATE compliance_cases
+		SET updated_at = ?
+		WHERE case_id = ?
+	`
+	_, err := tx.Exec(query, updatedAt, caseID)
+	return err
+}
+
+func (r *complianceRepository) AddNote(tx *sql.Tx, note entity.CaseNote) error {
+	query := `
+		INSERT INTO compliance_case_notes (note_id, case_id, author_id, body, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := tx.Exec(query, note.NoteID, note.CaseID, note.AuthorID, note.Body, note.CreatedAt)
+	return err
+}
+
+func (r *complianceRepository) FindNotes(caseID string) ([]*entity.CaseNote, error) {
+	query := `
+		SELECT note_id, case_id, author_id, body, created_at
+		FROM compliance_case_notes
+		WHERE case_id = ?
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*entity.CaseNote{}
+	for rows.Next() {
+		note := &entity.CaseNote{}
+		var createdAtStr string
+		if err := rows.Scan(&note.NoteID, &note.CaseID, &note.AuthorID, &note.Body, &createdAtStr); err != nil {
+			return nil, err
+		}
+		note.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+const caseAttachmentColumns = `attachment_id, case_id, uploaded_by, file_name, content_type, size, blob_key, created_at`
+
+func scanCaseAttachment(row rowScanner) (*entity.CaseAttachment, error) {
+	attachment := &entity.CaseAttachment{}
+	var createdAtStr string
+	err := row.Scan(&attachment.AttachmentID, &attachment.CaseID, &attachment.UploadedBy, &attachment.FileName,
+		&attachment.ContentType, &attachment.Size, &attachment.BlobKey, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (r *complianceRepository) AddAttachment(tx *sql.Tx, attachment entity.CaseAttachment) error {
+	query := `
+		INSERT INTO compliance_case_attachments (attachment_id, case_id, uploaded_by, file_name, content_type, size, blob_key, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := tx.Exec(query, attachment.AttachmentID, attachment.CaseID, attachment.UploadedBy, attachment.FileName,
+		attachment.ContentType, attachment.Size, attachment.BlobKey, attachment.CreatedAt)
+	return err
+}
+
+func (r *complianceRepository) FindAttachments(caseID string) ([]*entity.CaseAttachment, error) {
+	query := `
+		SELECT ` + caseAttachmentColumns + `
+		FROM compliance_case_attachments
+		WHERE case_id = ?
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*entity.CaseAttachment{}
+	for rows.Next() {
+		attachment, err := scanCaseAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *complianceRepository) FindAttachment(caseID, attachmentID string) (*entity.CaseAttachment, error) {
+	query := `
+		SELECT ` + caseAttachmentColumns + `
+		FROM compliance_case_attachments
+		WHERE case_id = ? AND attachment_id = ?
+	`
+	return scanCaseAttachment(r.db.QueryRow(query, caseID, attachmentID))
+}
+
+func (r *complianceRepository) Close(tx *sql.Tx, caseID, closedBy, note string, closedAt time.Time) error {
+	query := `
+		UPDATE compliance_cases
+		SET status = ?, closed_by = ?, closing_note = ?, closed_at = ?, updated_at = ?
+		WHERE case_id = ?
+	`
+	_, err := tx.Exec(query, entity.CaseStatusClosed, closedBy, note, closedAt, closedAt, caseID)
+	return err
+}
+
+func (r *complianceRepository) AddClearance(tx *sql.Tx, clearance entity.ScreeningClearance) error {
+	query := `
+		INSERT INTO screening_clearances (user_id, entry_id, entry_version, case_id, cleared_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE entry_version = VALUES(entry_version), case_id = VALUES(case_id),
+			cleared_by = VALUES(cleared_by), created_at = VALUES(created_at)
+	`
+	_, err := tx.Exec(query, clearance.UserID, clearance.EntryID, clearance.EntryVersion, clearance.CaseID,
+		clearance.ClearedBy, clearance.CreatedAt)
+	return err
+}
+
+func (r *complianceRepository) FindClearances(userID string) (map[string]string, error) {
+	rows, err := r.db.Query(`
+		SELECT entry_id, entry_version
+		FROM screening_clearances
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clearances := make(map[string]string)
+	for rows.Next() {
+		var entryID, entryVersion string
+		if err := rows.Scan(&entryID, &entryVersion); err != nil {
+			return nil, err
+		}
+		clearances[entryID] = entryVersion
+	}
+	return clearances, rows.Err()
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type IConfirmationRepository interface {
+	Save(token string, confirmation entity.TransactionConfirmation, ttl time.Duration) error
+
+	// Consume returns and deletes the confirmation stored under token, or nil
+	// when there is none, so every token can be used once.
+	Consume(token string) (*entity.TransactionConfirmation, error)
+}
+
+type confirmationRepository struct {
+	pool *redis.Pool
+}
+
+func NewConfirmationRepository(pool *redis.Pool) IConfirmationRepository {
+	return &confirmationRepository{pool: pool}
+}
+
+func confirmationKey(token string) string {
+	return "tx_confirmation:" + token
+}
+
+func (r *confirmationRepository) Save(token string, confirmation entity.TransactionConfirmation, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	payload, err := json.Marshal(confirmation)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Do("SET", confirmationKey(token), payload, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (r *confirmationRepository) Consume(token string) (*entity.TransactionConfirmation, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("GET", confirmationKey(token))
+	conn.Send("DEL", confirmationKey(token))
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := redis.Bytes(values[0], nil)
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := &entity.TransactionConfirmation{}
+	if err := json.Unmarshal(stored, confirmation); err != nil {
+		return nil, err
+	}
+
+	return confirmation, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type IIdempotencyRepository interface {
+	// Reserve stores record under key if the key is unused. When the key is
+	// already taken it returns the existing record and reserved is false.
+	Reserve(key string, record entity.IdempotencyRecord, ttl time.Duration) (existing *entity.IdempotencyRecord, reserved bool, err error)
+	Save(key string, record entity.IdempotencyRecord, ttl time.Duration) error
+	Release(key string) error
+}
+
+type idempotencyRepository struct {
+	pool *redis.Pool
+}
+
+func NewIdempotencyRepository(pool *redis.Pool) IIdempotencyRepository {
+	return &idempotencyRepository{pool: pool}
+}
+
+func (r *idempotencyRepository) Reserve(key string, record entity.IdempotencyRecord, ttl time.Duration) (*entity.IdempotencyRecord, bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = redis.String(conn.Do("SET", key, payload, "NX", "EX", int64(ttl.Seconds())))
+	if err == nil {
+		return nil, true, nil
+	}
+	if err != redis.ErrNil {
+		return nil, false, err
+	}
+
+	stored, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		// The previous reservation expired between SET and GET, so try again.
+		return r.Reserve(key, record, ttl)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing := &entity.IdempotencyRecord{}
+	if err := json.Unmarshal(stored, existing); err != nil {
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+func (r *idempotencyRepository) Save(key string, record entity.IdempotencyRecord, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Do("SET", key, payload, "EX", int64(ttl.Seconds()))
+	return err
+}
+
+func (r *idempotencyRepository) Release(key string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", key)
+	return err
+}
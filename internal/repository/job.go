@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// ErrJobNotRetried means the job was no longer in the dead set, e.g. because
+// it was retried or deleted meanwhile.
+var ErrJobNotRetried = errors.New("job is no longer dead")
+
+type IJobRepository interface {
+	// DeadJobs returns one page of the dead set and the total number of dead jobs.
+	DeadJobs(page uint) ([]*entity.FailedJob, int64, error)
+	RetryingJobs(page uint) ([]*entity.FailedJob, int64, error)
+	QueuedCounts() (map[string]int64, error)
+	RetryDeadJob(diedAt int64, jobID string) error
+	DeleteDeadJob(diedAt int64, jobID string) error
+}
+
+type jobRepository struct {
+	client *work.Client
+}
+
+func NewJobRepository(namespace string, pool *redis.Pool) IJobRepository {
+	return &jobRepository{client: work.NewClient(namespace, pool)}
+}
+
+func (r *jobRepository) DeadJobs(page uint) ([]*entity.FailedJob, int64, error) {
+	jobs, count, err := r.client.DeadJobs(page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deadJobs := make([]*entity.FailedJob, 0, len(jobs))
+	for _, job := range jobs {
+		deadJob := toFailedJob(job.Job)
+		deadJob.DiedAt = job.DiedAt
+		deadJobs = append(deadJobs, deadJob)
+	}
+
+	return deadJobs, count, nil
+}
+
+func (r *jobRepository) RetryingJobs(page uint) ([]*entity.FailedJob, int64, error) {
+	jobs, count, err := r.client.RetryJobs(page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	retryJobs := make([]*entity.FailedJob, 0, len(jobs))
+	for _, job := range jobs {
+		retryJobs = append(retryJobs, toFailedJob(job.Job))
+	}
+
+	return retryJobs, count, nil
+}
+
+func (r *jobRepository) QueuedCounts() (map[string]int64, error) {
+	queues, err := r.client.Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(queues))
+	for _, queue := range queues {
+		counts[queue.JobName] = queue.Count
+	}
+
+	return counts, nil
+}
+
+func (r *jobRepository) RetryDeadJob(diedAt int64, jobID string) error {
+	err := r.client.RetryDeadJob(diedAt, jobID)
+	if err == work.ErrNotRetried {
+		return ErrJobNotRetried
+	}
+	return err
+}
+
+func (r *jobRepository) DeleteDeadJob(diedAt int64, jobID string) error {
+	return r.client.DeleteDeadJob(diedAt, jobID)
+}
+
+func toFailedJob(job *work.Job) *entity.FailedJob {
+	deadJob := &entity.FailedJob{
+		ID:         job.ID,
+		Name:       job.Name,
+		Args:       job.Args,
+		Fails:      job.Fails,
+		LastError:  job.LastErr,
+		EnqueuedAt: time.Unix(job.EnqueuedAt, 0),
+	}
+	if job.FailedAt > 0 {
+		deadJob.FailedAt = time.Unix(job.FailedAt, 0)
+	}
+	return deadJob
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const (
+	defaultKYCSubmissionLimit = 50
+	maxKYCSubmissionLimit     = 200
+)
+
+type IKYCRepository interface {
+	Create(submission entity.KYCSubmission) error
+	FindByID(submissionID string) (*entity.KYCSubmission, error)
+	// FindLatestByUserID returns nil when the user never submitted.
+	FindLatestByUserID(userID string) (*entity.KYCSubmission, error)
+
+	// Find returns matching submissions. Pending submissions come oldest
+	// first, so the review queue is worked in order; others newest first.
+	Find(filter entity.KYCSubmissionFilter) ([]*entity.KYCSubmission, error)
+
+	Lock(tx *sql.Tx, submissionID string) (*entity.KYCSubmission, error)
+	UpdateReview(tx *sql.Tx, submission entity.KYCSubmission) error
+}
+
+type kycRepository struct {
+	db *sql.DB
+}
+
+func NewKYCRepository(db *sql.DB) IKYCRepository {
+	return &kycRepository{db: db}
+}
+
+const kycSubmissionColumns = `submission_id, user_id, status, id_type, id_number, id_image_key, selfie_key, selfie_metadata, reviewer_id, review_note, created_at, reviewed_at`
+
+func scanKYCSubmission(row rowScanner) (*entity.KYCSubmission, error) {
+	submission := &entity.KYCSubmission{}
+	var selfieMetadata, createdAtStr string
+	var reviewerID, reviewNote, reviewedAtStr sql.NullString
+	err := row.Scan(&submission.SubmissionID, &submission.UserID, &submission.Status, &submission.IDType, &submission.IDNumber,
+		&submission.IDImageKey, &submission.SelfieKey, &selfieMetadata, &reviewerID, &reviewNote, &createdAtStr, &reviewedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(selfieMetadata), &submission.SelfieMetadata); err != nil {
+		return nil, fmt.Errorf("failed to decode selfie_metadata: %w", err)
+	}
+	submission.ReviewerID = reviewerID.String
+	submission.ReviewNote = reviewNote.String
+
+	submission.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	if reviewedAtStr.Valid {
+		reviewedAt, err := time.Parse("2006-01-02 15:04:05", reviewedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reviewed_at: %w", err)
+		}
+		submission.ReviewedAt = &reviewedAt
+	}
+
+	return submission, nil
+}
+
+func (r *kycRepository) Create(submission entity.KYCSubmission) error {
+	selfieMetadata, err := json.Marshal(submission.SelfieMetadata)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO kyc_submissions (submission_id, user_id, status, id_type, id_number, id_image_key, selfie_key, selfie_metadata, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = r.db.Exec(query, submission.SubmissionID, submission.UserID, submission.Status, submission.IDType, submission.IDNumber,
+		submission.IDImageKey, submission.SelfieKey, string(selfieMetadata), submission.CreatedAt)
+	return err
+}
+
+func (r *kycRepository) FindByID(submissionID string) (*entity.KYCSubmission, error) {
+	query := `
+		SELECT ` + kycSubmissionColumns + `
+		FROM kyc_submissions
+		WHERE submission_id = ?
+	`
+	return scanKYCSubmission(r.db.QueryRow(query, submissionID))
+}
+
+func (r *kycRepository) FindLatestByUserID(userID string) (*entity.KYCSubmission, error) {
+	query := `
+		SELECT ` + kycSubmissionColumns + `
+		FROM kyc_submissions
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	submission, err := scanKYCSubmission(r.db.QueryRow(query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return submission, err
+}
+
+func (r *kycRepository) Find(filter entity.KYCSubmissionFilter) ([]*entity.KYCSubmission, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultKYCSubmissionLimit
+	}
+	if limit > maxKYCSubmissionLimit {
+		limit = maxKYCSubmissionLimit
+	}
+
+	order := "DESC"
+	if filter.Status == entity.KYCStatusPending {
+		order = "ASC"
+	}
+
+	query := `
+		SELECT ` + kycSubmissionColumns + `
+		FROM kyc_submissions
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at " + order + ", id " + order + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := []*entity.KYCSubmission{}
+	for rows.Next() {
+		submission, err := scanKYCSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, rows.Err()
+}
+
+func (r *kycRepository) Lock(tx *sql.Tx, submissionID string) (*entity.KYCSubmission, error) {
+	query := `
+		SELECT ` + kycSubmissionColumns + `
+		FROM kyc_submissions
+		WHERE submission_id = ?
+		FOR UPDATE
+	`
+	return scanKYCSubmission(tx.QueryRow(query, submissionID))
+}
+
+func (r *kycRepository) UpdateReview(tx *sql.Tx, submission entity.KYCSubmission) error {
+	query := `
+		UPDATE kyc_submissions
+		SET status = ?, reviewer_id = ?, review_note = ?, reviewed_at = ?
+		WHERE submission_id = ?
+	`
+	_, err := tx.Exec(query, submission.Status, submission.ReviewerID, submission.ReviewNote, submission.ReviewedAt, submission.SubmissionID)
+	return err
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type ILedgerRepository interface {
+	EnsureAccount(tx *sql.Tx, account entity.LedgerAccount) error
+	InsertJournal(tx *sql.Tx, journal entity.JournalEntry) error
+	GetAccountBalance(accountCode string) (entity.Money, error)
+	FindJournalsByReferenceID(referenceID string) ([]*entity.JournalEntry, error)
+	SumAllPostings() (entity.Money, error)
+	// FindWalletMismatches returns the wallets whose balance differs from
+	// the balance of their ledger account.
+	FindWalletMismatches() ([]entity.WalletMismatch, error)
+	HasJournal(tx *sql.Tx, referenceID, journalType string) (bool, error)
+	// HasTransferred reports whether userID ever completed a transfer to
+	// targetUser.
+	HasTransferred(userID, targetUser string) (bool, error)
+}
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) ILedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) EnsureAccount(tx *sql.Tx, account entity.LedgerAccount) error {
+	query := `
+		INSERT IGNORE INTO ledger_accounts (account_code, type, user_id, currency, created_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?)
+	`
+	_, err := tx.Exec(query, account.AccountCode, account.Type, account.UserID, account.Currency, account.CreatedAt)
+	return err
+}
+
+func (r *ledgerRepository) InsertJournal(tx *sql.Tx, journal entity.JournalEntry) error {
+	journalQuery := `
+		INSERT INTO journal_entries (journal_id, reference_id, type, description, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := tx.Exec(journalQuery, journal.JournalID, journal.ReferenceID, journal.Type, journal.Description, journal.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	postingQuery := `
+		INSERT INTO postings (journal_id, account_code, direction, amount, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	for _, posting := range journal.Postings {
+		_, err = tx.Exec(postingQuery, journal.JournalID, posting.AccountCode, posting.Direction, posting.Amount, posting.Amount.Currency, journal.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAccountBalance derives the balance of an account from its postings as
+// credits minus debits, which is the natural balance of a wallet.
+func (r *ledgerRepository) GetAccountBalance(accountCode string) (balance entity.Money, err error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'CREDIT' THEN amount ELSE -amount END), 0)
+		FROM postings
+		WHERE account_code = ?
+	`
+	balance = entity.NewMoney(0, entity.DefaultCurrency)
+	err = r.db.QueryRow(query, accountCode).Scan(&balance)
+	return balance, err
+}
+
+// SumAllPostings returns credits minus debits across the whole ledger. Money is
+// conserved when this is zero.
+func (r *ledgerRepository) SumAllPostings() (total entity.Money, err error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'CREDIT' THEN amount ELSE -amount END), 0)
+		FROM postings
+	`
+	total = entity.NewMoney(0, entity.DefaultCurrency)
+	err = r.db.QueryRow(query).Scan(&total)
+	return total, err
+}
+
+func (r *ledgerRepository) FindJournalsByReferenceID(referenceID string) ([]*entity.JournalEntry, error) {
+	query := `
+		SELECT j.journal_id, j.reference_id, j.type, j.description, j.created_at,
+			p.account_code, p.direction, p.amount, p.currency
+		FROM journal_entries j
+		JOIN postings p ON p.journal_id = j.journal_id
+		WHERE j.reference_id = ?
+		ORDER BY j.id, p.id
+	`
+	rows, err := r.db.Query(query, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var journals []*entity.JournalEntry
+	byID := map[string]*entity.JournalEntry{}
+
+	for rows.Next() {
+		var (
+			journal      entity.JournalEntry
+			posting      entity.Posting
+			currency     string
+			createdAtStr string
+		)
+		if err := rows.Scan(&journal.JournalID, &journal.ReferenceID, &journal.Type, &journal.Description, &createdAtStr,
+			&posting.AccountCode, &posting.Direction, &posting.Amount, &currency); err != nil {
+			return nil, err
+		}
+		posting.Amount.Currency = currency
+
+		existing, ok := byID[journal.JournalID]
+		if !ok {
+			journal.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+			if err != nil {
+				return nil, err
+			}
+			existing = &journal
+			byID[journal.JournalID] = existing
+			journals = append(journals, existing)
+		}
+		posting.JournalID = existing.JournalID
+		posting.CreatedAt = existing.CreatedAt
+		existing.Postings = append(existing.Postings, posting)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return journals, nil
+}
+
+func (r *ledgerRepository) HasTransferred(userID, targetUser string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM journal_entries j
+			JOIN postings debit ON debit.journal_id = j.journal_id
+			JOIN postings credit ON credit.journal_id = j.journal_id
+			WHERE j.type = ?
+				AND debit.account_code = ? AND debit.direction = ?
+				AND credit.account_code = ? AND credit.direction = ?
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, entity.JournalTypeTransfer,
+		entity.WalletAccountCode(userID), entity.PostingDirectionDebit,
+		entity.WalletAccountCode(targetUser), entity.PostingDirectionCredit).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *ledgerRepository) FindWalletMismatches() ([]entity.WalletMismatch, error) {
+	query := `
+		SELECT w.user_id, w.balance,
+			COALESCE(SUM(CASE WHEN p.direction = 'CREDIT' THEN p.amount ELSE -p.amount END), 0) AS ledger_balance,
+			EXISTS (
+				SELECT 1 FROM journal_entries j
+				WHERE j.reference_id = w.user_id AND j.type = ?
+			) AS opened
+		FROM wallets w
+		LEFT JOIN postings p ON p.account_code = CONCAT(?, w.user_id)
+		GROUP BY w.user_id, w.balance
+		HAVING w.balance <> ledger_balance
+		ORDER BY w.user_id
+	`
+	rows, err := r.db.Query(query, entity.JournalTypeOpening, entity.WalletAccountCode(""))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []entity.WalletMismatch{}
+	for rows.Next() {
+		mismatch := entity.WalletMismatch{
+			WalletBalance: entity.NewMoney(0, entity.DefaultCurrency),
+			LedgerBalance: entity.NewMoney(0, entity.DefaultCurrency),
+		}
+		if err := rows.Scan(&mismatch.UserID, &mismatch.WalletBalance, &mismatch.LedgerBalance, &mismatch.Opened); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	return mismatches, rows.Err()
+}
+
+func (r *ledgerRepository) HasJournal(tx *sql.Tx, referenceID, journalType string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM journal_entries
+			WHERE reference_id = ? AND type = ?
+		)
+	`
+	var exists bool
+	err := tx.QueryRow(query, referenceID, journalType).Scan(&exists)
+	return exists, err
+}
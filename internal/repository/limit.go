@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// ErrLimitCounterMissing means a counter is not in Redis yet, e.g. for the
+// first transaction of a period or after Redis lost its data, and has to be
+// seeded from limit_usage.
+var ErrLimitCounterMissing = errors.New("limit counter missing")
+
+type ILimitUsageRepository interface {
+	Record(tx *sql.Tx, usage entity.LimitUsage) error
+
+	// Release stops the usage of a transaction from counting and Restore
+	// undoes that. Both return the usage, or nil when the transaction has
+	// none in that state.
+	Release(tx *sql.Tx, transactionID string, now time.Time) (*entity.LimitUsage, error)
+	Restore(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error)
+
+	// Sum returns the amount and number of counting transactions of userID
+	// and kind created in [from, to).
+	Sum(userID, kind string, from, to time.Time) (entity.Money, int64, error)
+}
+
+type limitUsageRepository struct {
+	db *sql.DB
+}
+
+func NewLimitUsageRepository(db *sql.DB) ILimitUsageRepository {
+	return &limitUsageRepository{db: db}
+}
+
+func (r *limitUsageRepository) Record(tx *sql.Tx, usage entity.LimitUsage) error {
+	query := `
+		INSERT INTO limit_usage (transaction_id, user_id, kind, amount, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := tx.Exec(query, usage.TransactionID, usage.UserID, usage.Kind, usage.Amount, usage.CreatedAt)
+	return err
+}
+
+func (r *limitUsageRepository) Release(tx *sql.Tx, transactionID string, now time.Time) (*entity.LimitUsage, error) {
+	usage, err := r.lock(tx, transactionID, "released_at IS NULL")
+	if err != nil || usage == nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE limit_usage SET released_at = ? WHERE transaction_id = ?`, now, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (r *limitUsageRepository) Restore(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error) {
+	usage, err := r.lock(tx, transactionID, "released_at IS NOT NULL")
+	if err != nil || usage == nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE limit_usage SET released_at = NULL WHERE transaction_id = ?`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (r *limitUsageRepository) lock(tx *sql.Tx, transactionID, condition string) (*entity.LimitUsage, error) {
+	query := `
+		SELECT transaction_id, user_id, kind, amount, created_at
+		FROM limit_usage
+		WHERE transaction_id = ? AND ` + condition + `
+		FOR UPDATE
+	`
+	usage := &entity.LimitUsage{}
+	var createdAtStr string
+	err := tx.QueryRow(query, transactionID).Scan(&usage.TransactionID, &usage.UserID, &usage.Kind, &usage.Amount, &createdAtStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (r *limitUsageRepository) Sum(userID, kind string, from, to time.Time) (entity.Money, int64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0), COUNT(*)
+		FROM limit_usage
+		WHERE user_id = ? AND kind = ? AND created_at >= ? AND created_at < ? AND released_at IS NULL
+	`
+	var amount entity.Money
+	var count int64
+	err := r.db.QueryRow(query, userID, kind, from, to).Scan(&amount, &count)
+	return amount, count, err
+}
+
+// LimitCounter is a Redis counter of an amount in minor units or of a number
+// of transactions. Max 0 is unlimited.
+type LimitCounter struct {
+	Key string
+	Add int64
+	Max int64
+	TTL time.Duration
+}
+
+type ILimitCounterRepository interface {
+	// Get returns the value of each key and whether it exists.
+	Get(keys ...string) (values []int64, exists []bool, err error)
+
+	// Seed sets key unless it already exists.
+	Seed(key string, value int64, ttl time.Duration) error
+
+	// Reserve adds to every counter unless one would pass its Max, in which
+	// case it returns the index and value of the first such counter and
+	// changes nothing; exceeded is -1 on success. It returns
+	// ErrLimitCounterMissing when any counter does not exist.
+	Reserve(counters []LimitCounter) (exceeded int, current int64, err error)
+
+	// Adjust adds Add, which may be negative, to the counters that exist.
+	// Missing counters are seeded from MySQL later, which already reflects
+	// the change.
+	Adjust(counters []LimitCounter) error
+}
+
+type limitCounterRepository struct {
+	pool *redis.Pool
+}
+
+func NewLimitCounterRepository(pool *redis.Pool) ILimitCounterRepository {
+	return &limitCounterRepository{pool: pool}
+}
+
+func (r *limitCounterRepository) Get(keys ...string) ([]int64, []bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	replies, err := redis.Values(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]int64, len(keys))
+	exists := make([]bool, len(keys))
+	for i, reply := range replies {
+		if reply == nil {
+			continue
+		}
+		if values[i], err = redis.Int64(reply, nil); err != nil {
+			return nil, nil, err
+		}
+		exists[i] = true
+	}
+	return values, exists, nil
+}
+
+func (r *limitCounterRepository) Seed(key string, value int64, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", key, value, "PX", ttl.Milliseconds(), "NX")
+	return err
+}
+
+// reserveScript checks every counter before changing any, so a transaction is
+// counted against all of its limits or none.
+var reserveScript = redis.NewScript(-1, `
+for i, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 0 then
+		return {-2, 0}
+	end
+end
+for i, key in ipairs(KEYS) do
+	local current = tonumber(redis.call('GET', key))
+	local max = tonumber(ARGV[i * 3 - 1])
+	if max > 0 and current + tonumber(ARGV[i * 3 - 2]) > max then
+		return {i - 1, current}
+	end
+end
+for i, key in ipairs(KEYS) do
+	redis.call('INCRBY', key, ARGV[i * 3 - 2])
+	redis.call('PEXPIRE', key, ARGV[i * 3])
+end
+return {-1, 0}
+`)
+
+func (r *limitCounterRepository) Reserve(counters []LimitCounter) (int, int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	args := []interface{}{len(counters)}
+	for _, counter := range counters {
+		args = append(args, counter.Key)
+	}
+	for _, counter := range counters {
+		args = append(args, counter.Add, counter.Max, counter.TTL.Milliseconds())
+	}
+
+	result, err := redis.Int64s(reserveScript.Do(conn, args...))
+	if err != nil {
+		return 0, 0, err
+	}
+	if result[0] == -2 {
+		return 0, 0, ErrLimitCounterMissing
+	}
+	return int(result[0]), result[1], nil
+}
+
+var adjustScript = redis.NewScript(-1, `
+for i, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('INCRBY', key, ARGV[i])
+	end
+end
+return 0
+`)
+
+func (r *limitCounterRepository) Adjust(counters []LimitCounter) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	args := []interface{}{len(counters)}
+	for _, counter := range counters {
+		args = append(args, counter.Key)
+	}
+	for _, counter := range counters {
+		args = append(args, counter.Add)
+	}
+
+	_, err := adjustScript.Do(conn, args...)
+	return err
+}
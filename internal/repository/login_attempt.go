@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type ILoginAttemptRepository interface {
+	// RecordFailure increments the failure counter of subject and returns the
+	// new count. The counter expires window after the first failure.
+	RecordFailure(subject string, window time.Duration) (int64, error)
+	// ForgiveFailure takes back one failure recorded for an attempt that
+	// turned out to succeed. A counter that expired in between stays gone.
+	ForgiveFailure(subject string) error
+	ResetFailures(subject string) error
+
+	// Block rejects logins for subject until ttl passes. Blocked returns the
+	// reason given to Block and the remaining time, or an empty reason when
+	// subject is not blocked.
+	Block(subject, reason string, ttl time.Duration) error
+	Blocked(subject string) (reason string, remaining time.Duration, err error)
+	Unblock(subject string) error
+
+	// IncrementLocks counts how often subject was locked within window, so
+	// repeated locks can last longer.
+	IncrementLocks(subject string, window time.Duration) (int64, error)
+	ResetLocks(subject string) error
+}
+
+type loginAttemptRepository struct {
+	pool *redis.Pool
+}
+
+func NewLoginAttemptRepository(pool *redis.Pool) ILoginAttemptRepository {
+	return &loginAttemptRepository{pool: pool}
+}
+
+func loginFailuresKey(subject string) string {
+	return "login_failures:" + subject
+}
+
+func loginBlockKey(subject string) string {
+	return "login_block:" + subject
+}
+
+func loginLocksKey(subject string) string {
+	return "login_locks:" + subject
+}
+
+func (r *loginAttemptRepository) RecordFailure(subject string, window time.Duration) (int64, error) {
+	return r.incrementWithExpiry(loginFailuresKey(subject), window)
+}
+
+// forgiveScript decrements a counter that still exists, so it keeps its TTL
+// and never goes below zero.
+var forgiveScript = redis.NewScript(1, `
+local count = tonumber(redis.call('GET', KEYS[1]))
+if count and count > 0 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+func (r *loginAttemptRepository) ForgiveFailure(subject string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := forgiveScript.Do(conn, loginFailuresKey(subject))
+	return err
+}
+
+func (r *loginAttemptRepository) ResetFailures(subject string) error {
+	return r.del(loginFailuresKey(subject))
+}
+
+func (r *loginAttemptRepository) Block(subject, reason string, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", loginBlockKey(subject), reason, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (r *loginAttemptRepository) Blocked(subject string) (string, time.Duration, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("GET", loginBlockKey(subject))
+	conn.Send("PTTL", loginBlockKey(subject))
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return "", 0, err
+	}
+
+	reason, err := redis.String(values[0], nil)
+	if err == redis.ErrNil {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	ttl, err := redis.Int64(values[1], nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if ttl < 0 {
+		return "", 0, nil
+	}
+
+	return reason, time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (r *loginAttemptRepository) Unblock(subject string) error {
+	return r.del(loginBlockKey(subject))
+}
+
+func (r *loginAttemptRepository) IncrementLocks(subject string, window time.Duration) (int64, error) {
+	return r.incrementWithExpiry(loginLocksKey(subject), window)
+}
+
+func (r *loginAttemptRepository) ResetLocks(subject string) error {
+	return r.del(loginLocksKey(subject))
+}
+
+// incrementScript counts within a window that starts with the first
+// increment. Both steps run in one call, so a counter never outlives its
+// window, and a counter left without one is given it again.
+var incrementScript = redis.NewScript(1, `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 or redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+func (r *loginAttemptRepository) incrementWithExpiry(key string, window time.Duration) (int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(incrementScript.Do(conn, key, window.Milliseconds()))
+}
+
+func (r *loginAttemptRepository) del(key string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", key)
+	return err
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type IMFARepository interface {
+	// SaveTOTPSecret stores a new secret that is not enabled yet.
+	SaveTOTPSecret(userID, secret string, updatedAt time.Time) error
+
+	// EnableTOTP enables the stored secret and replaces the recovery codes.
+	EnableTOTP(userID string, recoveryCodeHashes []string, updatedAt time.Time) error
+	DisableTOTP(userID string, updatedAt time.Time) error
+
+	// UseTOTPStep records step as the last accepted TOTP step and reports
+	// false when the same or a later step was already used, so a code cannot
+	// be replayed.
+	UseTOTPStep(userID string, step int64) (bool, error)
+
+	// UseRecoveryCode marks an unused recovery code as used and reports
+	// whether one matched.
+	UseRecoveryCode(userID, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type mfaRepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) IMFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) SaveTOTPSecret(userID, secret string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET totp_secret = ?, totp_enabled = FALSE, totp_last_step = NULL, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, secret, updatedAt, userID)
+
+	return err
+}
+
+func (r *mfaRepository) EnableTOTP(userID string, recoveryCodeHashes []string, updatedAt time.Time) error {
+	return WithTransaction(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE users
+			SET totp_enabled = TRUE, updated_at = ?
+			WHERE user_id = ?
+		`, updatedAt, userID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+
+		for _, codeHash := range recoveryCodeHashes {
+			_, err := tx.Exec(`
+				INSERT INTO recovery_codes (user_id, code_hash, created_at)
+				VALUES (?, ?, ?)
+			`, userID, codeHash, updatedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *mfaRepository) DisableTOTP(userID string, updatedAt time.Time) error {
+	return WithTransaction(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE users
+			SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL, updated_at = ?
+			WHERE user_id = ?
+		`, updatedAt, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+		return err
+	})
+}
+
+func (r *mfaRepository) UseTOTPStep(userID string, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_step = ?
+		WHERE user_id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)
+	`
+	return execAffectsRow(r.db, query, step, userID, step)
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID, codeHash string, usedAt time.Time) (bool, error) {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+	return execAffectsRow(r.db, query, usedAt, userID, codeHash)
+}
+
+func execAffectsRow(db *sql.DB, query string, args ...interface{}) (bool, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
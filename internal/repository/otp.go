@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type IOTPRepository interface {
+	// Save replaces any pending code for purpose and subject.
+	Save(purpose, subject string, record entity.OTPRecord, ttl time.Duration) error
+
+	// Consume deletes the pending code and reports true when its hash is
+	// hash. Otherwise a wrong attempt is counted and the code is deleted on
+	// the maxAttempts-th. Both happen in one step, so parallel guesses cannot
+	// exceed maxAttempts.
+	Consume(purpose, subject, hash string, maxAttempts int) (bool, error)
+
+	// IncrementRequests counts how many codes were requested for purpose and
+	// subject within window.
+	IncrementRequests(purpose, subject string, window time.Duration) (int64, error)
+}
+
+type otpRepository struct {
+	pool *redis.Pool
+}
+
+func NewOTPRepository(pool *redis.Pool) IOTPRepository {
+	return &otpRepository{pool: pool}
+}
+
+func otpKey(purpose, subject string) string {
+	return "otp:" + purpose + ":" + subject
+}
+
+func otpRequestsKey(purpose, subject string) string {
+	return "otp_requests:" + purpose + ":" + subject
+}
+
+func (r *otpRepository) Save(purpose, subject string, record entity.OTPRecord, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	key := otpKey(purpose, subject)
+	conn.Send("MULTI")
+	conn.Send("DEL", key)
+	conn.Send("HSET", key, "hash", record.Hash, "attempts", record.Attempts)
+	conn.Send("PEXPIRE", key, ttl.Milliseconds())
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+// consumeScript only counts attempts on a pending code, so an expired or
+// deleted code is never recreated without its TTL. The hashes are HMACs that
+// callers cannot choose, so the plain comparison leaks nothing useful.
+var consumeScript = redis.NewScript(1, `
+local stored = redis.call('HGET', KEYS[1], 'hash')
+if not stored then
+	return 0
+end
+if stored == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+if redis.call('HINCRBY', KEYS[1], 'attempts', 1) >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (r *otpRepository) Consume(purpose, subject, hash string, maxAttempts int) (bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(consumeScript.Do(conn, otpKey(purpose, subject), hash, maxAttempts))
+}
+
+func (r *otpRepository) IncrementRequests(purpose, subject string, window time.Duration) (int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(incrementScript.Do(conn, otpRequestsKey(purpose, subject), window.Milliseconds()))
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+type IOutboxRepository interface {
+	// Insert queues a job. referenceID is the id of what the job works on,
+	// e.g. the transaction, so the message can be held and released.
+	Insert(tx *sql.Tx, jobName, referenceID string, args map[string]interface{}, now time.Time) error
+	// Hold keeps the pending messages of referenceID from being relayed,
+	// Release queues them again and Discard drops them for good.
+	Hold(tx *sql.Tx, referenceID string) error
+	Release(tx *sql.Tx, referenceID string, now time.Time) error
+	Discard(tx *sql.Tx, referenceID string) error
+	LockPending(tx *sql.Tx, now time.Time, limit int) ([]*entity.OutboxMessage, error)
+	MarkPublished(tx *sql.Tx, id uint64, now time.Time) error
+	MarkAttemptFailed(tx *sql.Tx, id uint64, lastError string, nextAttemptAt time.Time) error
+}
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) IOutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Insert(tx *sql.Tx, jobName, referenceID string, args map[string]interface{}, now time.Time) error {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox_messages (job_name, reference_id, payload, status, attempts, available_at, created_at)
+		VALUES (?, ?, ?, ?, 0, ?, ?)
+	`
+	_, err = tx.Exec(query, jobName, referenceID, payload, entity.OutboxStatusPending, now, now)
+	return err
+}
+
+func (r *outboxRepository) Hold(tx *sql.Tx, referenceID string) error {
+	return r.setStatus(tx, referenceID, entity.OutboxStatusPending, entity.OutboxStatusHeld)
+}
+
+func (r *outboxRepository) Release(tx *sql.Tx, referenceID string, now time.Time) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = ?, available_at = ?
+		WHERE reference_id = ? AND status = ?
+	`
+	_, err := tx.Exec(query, entity.OutboxStatusPending, now, referenceID, entity.OutboxStatusHeld)
+	return err
+}
+
+func (r *outboxRepository) Discard(tx *sql.Tx, referenceID string) error {
+	return r.setStatus(tx, referenceID, entity.OutboxStatusHeld, entity.OutboxStatusDiscarded)
+}
+
+func (r *outboxRepository) setStatus(tx *sql.Tx, referenceID, from, to string) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = ?
+		WHERE reference_id = ? AND status = ?
+	`
+	_, err := tx.Exec(query, to, referenceID, from)
+	return err
+}
+
+// LockPending returns due messages and locks them until tx ends. SKIP LOCKED
+// lets several relays run side by side without picking the same rows.
+func (r *outboxRepository) LockPending(tx *sql.Tx, now time.Time, limit int) ([]*entity.OutboxMessage, error) {
+	query := `
+		SELECT id, job_name, payload, attempts
+		FROM outbox_messages
+		WHERE status = ? AND available_at <= ?
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, entity.OutboxStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*entity.OutboxMessage
+
+	for rows.Next() {
+		message := &entity.OutboxMessage{Status: entity.OutboxStatusPending}
+		var payload []byte
+		if err := rows.Scan(&message.ID, &message.JobName, &payload, &message.Attempts); err != nil {
+			return nil, err
+		}
+
+		// Keep numbers as json.Number so minor-unit amounts are not rounded through float64.
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber()
+		if err := decoder.Decode(&message.Args); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox message %d: %w", message.ID, err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkPublished(tx *sql.Tx, id uint64, now time.Time) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = ?, published_at = ?
+		WHERE id = ?
+	`
+	_, err := tx.Exec(query, entity.OutboxStatusPublished, now, id)
+	return err
+}
+
+func (r *outboxRepository) MarkAttemptFailed(tx *sql.Tx, id uint64, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE outbox_messages
+		SET attempts = attempts + 1, last_error = ?, available_at = ?
+		WHERE id = ?
+	`
+	_, err := tx.Exec(query, lastError, nextAttemptAt, id)
+	return err
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const (
+	defaultRiskDecisionLimit = 50
+	maxRiskDecisionLimit     = 200
+)
+
+type IRiskRepository interface {
+	Save(tx *sql.Tx, decision entity.RiskDecision) error
+	FindByTransactionID(transactionID string) (*entity.RiskDecision, error)
+
+	// FindRecent returns the decisions of userID since the given time,
+	// newest first.
+	FindRecent(userID string, since time.Time) ([]*entity.RiskDecision, error)
+
+	// Find returns matching decisions. Unreviewed REVIEW decisions come
+	// oldest first, so held transactions are worked in order; others newest
+	// first.
+	Find(filter entity.RiskDecisionFilter) ([]*entity.RiskDecision, error)
+
+	UpdateReview(tx *sql.Tx, transactionID, reviewerID, note string, reviewedAt time.Time) error
+}
+
+type riskRepository struct {
+	db *sql.DB
+}
+
+func NewRiskRepository(db *sql.DB) IRiskRepository {
+	return &riskRepository{db: db}
+}
+
+const riskDecisionColumns = `transaction_id, user_id, kind, target_user, amount, currency, new_recipient, outcome, hits, created_at, reviewer_id, review_note, reviewed_at`
+
+func scanRiskDecision(row rowScanner) (*entity.RiskDecision, error) {
+	decision := &entity.RiskDecision{}
+	var currency, hits, createdAtStr string
+	var reviewerID, reviewNote, reviewedAtStr sql.NullString
+	err := row.Scan(&decision.TransactionID, &decision.UserID, &decision.Kind, &decision.TargetUser, &decision.Amount, &currency,
+		&decision.NewRecipient, &decision.Outcome, &hits, &createdAtStr, &reviewerID, &reviewNote, &reviewedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Amount.Currency = currency
+	if err := json.Unmarshal([]byte(hits), &decision.Hits); err != nil {
+		return nil, fmt.Errorf("failed to decode hits: %w", err)
+	}
+	decision.ReviewerID = reviewerID.String
+	decision.ReviewNote = reviewNote.String
+
+	decision.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	if reviewedAtStr.Valid {
+		reviewedAt, err := time.Parse("2006-01-02 15:04:05", reviewedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reviewed_at: %w", err)
+		}
+		decision.ReviewedAt = &reviewedAt
+	}
+
+	return decision, nil
+}
+
+func scanRiskDecisions(rows *sql.Rows) ([]*entity.RiskDecision, error) {
+	defer rows.Close()
+
+	decisions := []*entity.RiskDecision{}
+	for rows.Next() {
+		decision, err := scanRiskDecision(rows)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, rows.Err()
+}
+
+func (r *riskRepository) Save(tx *sql.Tx, decision entity.RiskDecision) error {
+	hits, err := json.Marshal(decision.Hits)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO risk_decisions (transaction_id, user_id, kind, target_user, amount, currency, new_recipient, outcome, hits, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, decision.TransactionID, decision.UserID, decision.Kind, decision.TargetUser, decision.Amount, decision.Amount.Currency,
+		decision.NewRecipient, decision.Outcome, string(hits), decision.CreatedAt)
+	return err
+}
+
+func (r *riskRepository) FindByTransactionID(transactionID string) (*entity.RiskDecision, error) {
+	query := `
+		SELECT ` + riskDecisionColumns + `
+		FROM risk_decisions
+		WHERE transaction_id = ?
+	`
+	return scanRiskDecision(r.db.QueryRow(query, transactionID))
+}
+
+func (r *riskRepository) FindRecent(userID string, since time.Time) ([]*entity.RiskDecision, error) {
+	query := `
+		SELECT ` + riskDecisionColumns + `
+		FROM risk_decisions
+		WHERE user_id = ? AND created_at >= ?
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	return scanRiskDecisions(rows)
+}
+
+func (r *riskRepository) Find(filter entity.RiskDecisionFilter) ([]*entity.RiskDecision, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.Unreviewed {
+		conditions = append(conditions, "outcome = ?", "reviewed_at IS NULL")
+		args = append(args, entity.RiskOutcomeReview)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRiskDecisionLimit
+	}
+	if limit > maxRiskDecisionLimit {
+		limit = maxRiskDecisionLimit
+	}
+
+	order := "DESC"
+	if filter.Unreviewed {
+		order = "ASC"
+	}
+
+	query := `
+		SELECT ` + riskDecisionColumns + `
+		FROM risk_decisions
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at " + order + ", id " + order + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRiskDecisions(rows)
+}
+
+func (r *riskRepository) UpdateReview(tx *sql.Tx, transactionID, reviewerID, note string, reviewedAt time.Time) error {
+	query := `
+		UPDATE risk_decisions
+		SET reviewer_id = ?, review_note = ?, reviewed_at = ?
+		WHERE transaction_id = ?
+	`
+	_, err := tx.Exec(query, reviewerID, note, reviewedAt, transactionID)
+	return err
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type ISessionRepository interface {
+	Create(session entity.Session) error
+	FindByID(sessionID string) (*entity.Session, error)
+
+	// FindActiveByUserID returns sessions that are neither revoked nor
+	// expired at now, most recently used first.
+	FindActiveByUserID(userID string, now time.Time) ([]*entity.Session, error)
+
+	// Touch records a refresh of the session.
+	Touch(sessionID, ipAddress string, lastSeenAt, expiresAt time.Time) error
+	Revoke(sessionID string, revokedAt time.Time) error
+	RevokeAllByUserID(userID string, revokedAt time.Time) error
+
+	// HasDevice reports whether the user ever logged in from deviceID.
+	HasDevice(userID, deviceID string) (bool, error)
+	// DeviceFirstSeen returns when the user first logged in from deviceID.
+	DeviceFirstSeen(userID, deviceID string) (time.Time, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) ISessionRepository {
+	return &sessionRepository{db: db}
+}
+
+const sessionColumns = `session_id, user_id, device_id, device_name, platform, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*entity.Session, error) {
+	session := &entity.Session{}
+	var createdAtStr, lastSeenAtStr, expiresAtStr string
+	var revokedAtStr sql.NullString
+	err := row.Scan(&session.SessionID, &session.UserID, &session.DeviceID, &session.DeviceName, &session.Platform, &session.IPAddress, &createdAtStr, &lastSeenAtStr, &expiresAtStr, &revokedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	session.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	session.LastSeenAt, err = time.Parse("2006-01-02 15:04:05", lastSeenAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse last_seen_at: %w", err)
+	}
+
+	session.ExpiresAt, err = time.Parse("2006-01-02 15:04:05", expiresAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expires_at: %w", err)
+	}
+
+	if revokedAtStr.Valid {
+		revokedAt, err := time.Parse("2006-01-02 15:04:05", revokedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revoked_at: %w", err)
+		}
+		session.RevokedAt = &revokedAt
+	}
+
+	return session, nil
+}
+
+func (r *sessionRepository) Create(session entity.Session) error {
+	query := `
+		INSERT INTO sessions (session_id, user_id, device_id, device_name, platform, ip_address, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query, session.SessionID, session.UserID, session.DeviceID, session.DeviceName, session.Platform, session.IPAddress, session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+
+	return err
+}
+
+func (r *sessionRepository) FindByID(sessionID string) (*entity.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE session_id = ?
+	`
+	session, err := scanSession(r.db.QueryRow(query, sessionID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+
+	return session, err
+}
+
+func (r *sessionRepository) FindActiveByUserID(userID string, now time.Time) ([]*entity.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.Query(query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*entity.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepository) Touch(sessionID, ipAddress string, lastSeenAt, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET ip_address = ?, last_seen_at = ?, expires_at = ?
+		WHERE session_id = ?
+	`
+	_, err := r.db.Exec(query, ipAddress, lastSeenAt, expiresAt, sessionID)
+
+	return err
+}
+
+func (r *sessionRepository) Revoke(sessionID string, revokedAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = ?
+		WHERE session_id = ? AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(query, revokedAt, sessionID)
+
+	return err
+}
+
+func (r *sessionRepository) RevokeAllByUserID(userID string, revokedAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(query, revokedAt, userID)
+
+	return err
+}
+
+func (r *sessionRepository) HasDevice(userID, deviceID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions
+			WHERE user_id = ? AND device_id = ?
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, userID, deviceID).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *sessionRepository) DeviceFirstSeen(userID, deviceID string) (time.Time, error) {
+	query := `
+		SELECT MIN(created_at)
+		FROM sessions
+		WHERE user_id = ? AND device_id = ?
+	`
+	var createdAtStr sql.NullString
+	if err := r.db.QueryRow(query, userID, deviceID).Scan(&createdAtStr); err != nil {
+		return time.Time{}, err
+	}
+	if !createdAtStr.Valid {
+		return time.Time{}, sql.ErrNoRows
+	}
+
+	firstSeen, err := time.Parse("2006-01-02 15:04:05", createdAtStr.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	return firstSeen, nil
+}
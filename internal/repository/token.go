@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token already used")
+)
+
+// rotateRefreshScript swaps the current token of a family for the new one.
+// It returns 1 on success, 0 when the family no longer exists and -1 when the
+// presented token is not the current one, in which case the family is deleted.
+var rotateRefreshScript = redis.NewScript(1, `
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+return 1
+`)
+
+type ITokenRepository interface {
+	// CreateRefreshFamily starts a new refresh token family whose current
+	// token is jti.
+	CreateRefreshFamily(familyID, jti string, ttl time.Duration) error
+
+	// RotateRefreshFamily replaces the current token of a family. Presenting
+	// any token other than the current one revokes the whole family and
+	// returns ErrRefreshTokenReused.
+	RotateRefreshFamily(familyID, oldJTI, newJTI string, ttl time.Duration) error
+	RevokeRefreshFamily(familyID string) error
+
+	// RevokeToken denylists a single token id until it would have expired
+	// anyway.
+	RevokeToken(jti string, ttl time.Duration) error
+	IsTokenRevoked(jti string) (bool, error)
+
+	// RevokeSession denylists every token carrying the session id for ttl,
+	// which must cover the lifetime of an access token.
+	RevokeSession(sessionID string, ttl time.Duration) error
+	IsSessionRevoked(sessionID string) (bool, error)
+
+	// Tokens carry the user's token version at issue time. Bumping the
+	// version invalidates every token issued before.
+	GetTokenVersion(userID string) (int64, error)
+	IncrementTokenVersion(userID string) (int64, error)
+}
+
+type tokenRepository struct {
+	pool *redis.Pool
+}
+
+func NewTokenRepository(pool *redis.Pool) ITokenRepository {
+	return &tokenRepository{pool: pool}
+}
+
+func refreshFamilyKey(familyID string) string {
+	return "refresh_family:" + familyID
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked_token:" + jti
+}
+
+func revokedSessionKey(sessionID string) string {
+	return "revoked_session:" + sessionID
+}
+
+func tokenVersionKey(userID string) string {
+	return "token_version:" + userID
+}
+
+func (r *tokenRepository) CreateRefreshFamily(familyID, jti string, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", refreshFamilyKey(familyID), jti, "EX", int64(ttl.Seconds()))
+	return err
+}
+
+func (r *tokenRepository) RotateRefreshFamily(familyID, oldJTI, newJTI string, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	result, err := redis.Int(rotateRefreshScript.Do(conn, refreshFamilyKey(familyID), oldJTI, newJTI, int64(ttl.Seconds())))
+	if err != nil {
+		return err
+	}
+
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		return ErrRefreshTokenReused
+	default:
+		return ErrRefreshTokenRevoked
+	}
+}
+
+func (r *tokenRepository) RevokeRefreshFamily(familyID string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", refreshFamilyKey(familyID))
+	return err
+}
+
+func (r *tokenRepository) RevokeToken(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	// EX needs at least one second; round up so the entry outlives the token.
+	_, err := conn.Do("SET", revokedTokenKey(jti), 1, "EX", int64((ttl+time.Second-1)/time.Second))
+	return err
+}
+
+func (r *tokenRepository) IsTokenRevoked(jti string) (bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", revokedTokenKey(jti)))
+}
+
+func (r *tokenRepository) RevokeSession(sessionID string, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", revokedSessionKey(sessionID), 1, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (r *tokenRepository) IsSessionRevoked(sessionID string) (bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", revokedSessionKey(sessionID)))
+}
+
+func (r *tokenRepository) GetTokenVersion(userID string) (int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	version, err := redis.Int64(conn.Do("GET", tokenVersionKey(userID)))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (r *tokenRepository) IncrementTokenVersion(userID string) (int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(conn.Do("INCR", tokenVersionKey(userID)))
+}
@@ -7,94 +7,118 @@ import (
 
 	"github.com/gocraft/work"
 	"github.com/leonardoong/e-wallet/internal/domain/entity"
-	"github.com/leonardoong/e-wallet/internal/publisher"
 )
 
 type ITransactionRepository interface {
 	InsertTransaction(tx *sql.Tx, transaction entity.Transaction) error
-
-	PublishTopUp(payload entity.PublishTopUpRequest) error
-	PublishPayment(payload entity.PaymentRequest) error
-	PublishTransfer(payload entity.TransferRequest) error
+	LockTransaction(tx *sql.Tx, transactionID string) (*entity.Transaction, error)
+	UpdateTransaction(tx *sql.Tx, transaction entity.Transaction) error
+
+	PublishTopUp(tx *sql.Tx, payload entity.PublishTopUpRequest) error
+	PublishPayment(tx *sql.Tx, payload entity.PaymentRequest) error
+	PublishTransfer(tx *sql.Tx, payload entity.TransferRequest) error
+	// HoldJob keeps the published job of a transaction in the outbox until
+	// ReleaseJob queues it or DiscardJob drops it.
+	HoldJob(tx *sql.Tx, transactionID string) error
+	ReleaseJob(tx *sql.Tx, transactionID string) error
+	DiscardJob(tx *sql.Tx, transactionID string) error
 	FindTransactionByID(topUpID string) (*entity.Transaction, error)
 	FindTransactionsByUserID(userID string) ([]*entity.Transaction, error)
 }
 
 type transactionRepository struct {
-	db             *sql.DB
-	redisPublisher *publisher.Publisher
+	db               *sql.DB
+	outboxRepository IOutboxRepository
 }
 
-func NewTransactionRepository(db *sql.DB, redisPublisher *publisher.Publisher) ITransactionRepository {
-	return &transactionRepository{db: db, redisPublisher: redisPublisher}
+func NewTransactionRepository(db *sql.DB, outboxRepo IOutboxRepository) ITransactionRepository {
+	return &transactionRepository{db: db, outboxRepository: outboxRepo}
 }
 
-func (r *transactionRepository) PublishTopUp(payload entity.PublishTopUpRequest) error {
-	err := r.redisPublisher.Enqueue("topup_job", work.Q{
+// The Publish methods only write to the outbox inside tx. The job reaches the
+// queue once tx commits and the outbox relay picks it up.
+
+func (r *transactionRepository) PublishTopUp(tx *sql.Tx, payload entity.PublishTopUpRequest) error {
+	return r.outboxRepository.Insert(tx, entity.JobNameTopUp, payload.TopUpID, work.Q{
 		"top_up_id": payload.TopUpID,
-		"amount":    payload.Amount,
+		"amount":    payload.Amount.Amount,
+		"currency":  payload.Amount.Currency,
 		"user_id":   payload.UserID,
-	})
-	return err
+	}, time.Now())
 }
 
-func (r *transactionRepository) PublishPayment(payload entity.PaymentRequest) error {
-	err := r.redisPublisher.Enqueue("payment_job", work.Q{
+func (r *transactionRepository) PublishPayment(tx *sql.Tx, payload entity.PaymentRequest) error {
+	return r.outboxRepository.Insert(tx, entity.JobNamePayment, payload.PaymentID, work.Q{
 		"payment_id": payload.PaymentID,
-		"amount":    payload.Amount,
-		"user_id":   payload.UserID,
-		"remarks" : payload.Remarks,
-	})
-	return err
+		"amount":     payload.Amount.Amount,
+		"currency":   payload.Amount.Currency,
+		"user_id":    payload.UserID,
+		"remarks":    payload.Remarks,
+	}, time.Now())
 }
 
-func (r *transactionRepository) PublishTransfer(payload entity.TransferRequest) error {
-	err := r.redisPublisher.Enqueue("transfer_job", work.Q{
-		"transfer_id": payload.TransferID,
-		"target_transfer_id": payload.TransferID,
-		"amount":    payload.Amount,
-		"user_id":   payload.UserID,
-		"target_user": payload.TargetUser,
-		"remarks" : payload.Remarks,
-	})
-	return err
+func (r *transactionRepository) PublishTransfer(tx *sql.Tx, payload entity.TransferRequest) error {
+	return r.outboxRepository.Insert(tx, entity.JobNameTransfer, payload.TransferID, work.Q{
+		"transfer_id":        payload.TransferID,
+		"target_transfer_id": payload.TargetTransferID,
+		"amount":             payload.Amount.Amount,
+		"currency":           payload.Amount.Currency,
+		"user_id":            payload.UserID,
+		"target_user":        payload.TargetUser,
+		"remarks":            payload.Remarks,
+	}, time.Now())
+}
+
+func (r *transactionRepository) HoldJob(tx *sql.Tx, transactionID string) error {
+	return r.outboxRepository.Hold(tx, transactionID)
+}
+
+func (r *transactionRepository) ReleaseJob(tx *sql.Tx, transactionID string) error {
+	return r.outboxRepository.Release(tx, transactionID, time.Now())
+}
+
+func (r *transactionRepository) DiscardJob(tx *sql.Tx, transactionID string) error {
+	return r.outboxRepository.Discard(tx, transactionID)
 }
 
 func (r *transactionRepository) InsertTransaction(tx *sql.Tx, transaction entity.Transaction) error {
 	query := `
-		INSERT INTO transactions (transaction_id, user_id, type, amount, balance_before, balance_after, status, description, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO transactions (transaction_id, user_id, type, amount, balance_before, balance_after, status, failure_reason, description, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
 	`
-	_, err := tx.Exec(query, transaction.TransactionID, transaction.UserID, transaction.Type, transaction.Amount, transaction.BalanceBefore, transaction.BalanceAfter, transaction.Status, transaction.Description, transaction.CreatedAt, transaction.UpdatedAt)
+	// Balances are unknown until a worker applies the transaction.
+	var balanceBefore, balanceAfter interface{}
+	if transaction.Status == entity.TransactionStatusSuccess {
+		balanceBefore, balanceAfter = transaction.BalanceBefore, transaction.BalanceAfter
+	}
+
+	_, err := tx.Exec(query, transaction.TransactionID, transaction.UserID, transaction.Type, transaction.Amount, balanceBefore, balanceAfter, transaction.Status, transaction.FailureReason, transaction.Description, transaction.CreatedAt, transaction.UpdatedAt)
 	if err != nil {
 		return err
 	}
 	return err
 }
 
-func (r *transactionRepository) TransactionHistory() error {
-	return nil
-}
+const transactionColumns = `id, transaction_id, user_id, type, amount, balance_before, balance_after, description, status, failure_reason, created_at, updated_at`
 
-func (r *transactionRepository) FindTransactionByID(transactionID string) (*entity.Transaction, error) {
-	query := `
-	SELECT *
-	FROM transactions
-	WHERE transaction_id = ?
-	`
-	row := r.db.QueryRow(query, transactionID)
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanTransaction(row rowScanner) (*entity.Transaction, error) {
 	transaction := &entity.Transaction{}
-	var createdAtStr, updatedAtStr string
+	var (
+		description, failureReason sql.NullString
+		createdAtStr, updatedAtStr string
+	)
 	err := row.Scan(&transaction.ID, &transaction.TransactionID, &transaction.UserID, &transaction.Type,
-		&transaction.Amount, &transaction.BalanceBefore, &transaction.BalanceAfter, &transaction.Description,
-		&transaction.Status, &createdAtStr, &updatedAtStr)
+		&transaction.Amount, &transaction.BalanceBefore, &transaction.BalanceAfter, &description,
+		&transaction.Status, &failureReason, &createdAtStr, &updatedAtStr)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, err
-		}
 		return nil, err
 	}
+	transaction.Description = description.String
+	transaction.FailureReason = failureReason.String
 
 	transaction.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
 	if err != nil {
@@ -109,9 +133,45 @@ func (r *transactionRepository) FindTransactionByID(transactionID string) (*enti
 	return transaction, nil
 }
 
+// LockTransaction locks the row so a redelivered job waits for, and then
+// sees, the outcome of the first delivery.
+func (r *transactionRepository) LockTransaction(tx *sql.Tx, transactionID string) (*entity.Transaction, error) {
+	query := `
+	SELECT ` + transactionColumns + `
+	FROM transactions
+	WHERE transaction_id = ?
+	FOR UPDATE
+	`
+	return scanTransaction(tx.QueryRow(query, transactionID))
+}
+
+func (r *transactionRepository) UpdateTransaction(tx *sql.Tx, transaction entity.Transaction) error {
+	query := `
+		UPDATE transactions
+		SET balance_before = ?, balance_after = ?, status = ?, failure_reason = NULLIF(?, ''), updated_at = ?
+		WHERE transaction_id = ?
+	`
+	var balanceBefore, balanceAfter interface{}
+	if transaction.Status == entity.TransactionStatusSuccess {
+		balanceBefore, balanceAfter = transaction.BalanceBefore, transaction.BalanceAfter
+	}
+
+	_, err := tx.Exec(query, balanceBefore, balanceAfter, transaction.Status, transaction.FailureReason, transaction.UpdatedAt, transaction.TransactionID)
+	return err
+}
+
+func (r *transactionRepository) FindTransactionByID(transactionID string) (*entity.Transaction, error) {
+	query := `
+	SELECT ` + transactionColumns + `
+	FROM transactions
+	WHERE transaction_id = ?
+	`
+	return scanTransaction(r.db.QueryRow(query, transactionID))
+}
+
 func (r *transactionRepository) FindTransactionsByUserID(userID string) ([]*entity.Transaction, error) {
 	query := `
-	SELECT *
+	SELECT ` + transactionColumns + `
 	FROM transactions
 	WHERE user_id = ?
 	`
@@ -124,22 +184,9 @@ func (r *transactionRepository) FindTransactionsByUserID(userID string) ([]*enti
 	var transactions []*entity.Transaction
 
 	for rows.Next() {
-		transaction := &entity.Transaction{}
-		var createdAtStr, updatedAtStr string
-		if err := rows.Scan(&transaction.ID, &transaction.TransactionID, &transaction.UserID, &transaction.Type,
-			&transaction.Amount, &transaction.BalanceBefore, &transaction.BalanceAfter, &transaction.Description,
-			&transaction.Status, &createdAtStr, &updatedAtStr); err != nil {
-			return nil, err
-		}
-
-		transaction.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse created_at: %w", err)
-		}
-
-		transaction.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse updated_at: %w", err)
+			return nil, err
 		}
 
 		transactions = append(transactions, transaction)
@@ -150,4 +197,4 @@ func (r *transactionRepository) FindTransactionsByUserID(userID string) ([]*enti
 	}
 
 	return transactions, nil
-}
\ No newline at end of file
+}
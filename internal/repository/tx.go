@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+
+	maxTransactionAttempts = 3
+)
+
+// WithTransaction runs fn inside a transaction and commits it. The whole
+// transaction is retried when MySQL picks it as a deadlock victim or a lock
+// wait times out, since nothing has been committed in that case.
+func WithTransaction(db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = runTransaction(db, fn)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func runTransaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func isRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+	}
+	return false
+}
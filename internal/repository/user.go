@@ -13,6 +13,17 @@ type IUserRepository interface {
 	FindByPhoneNumber(phoneNumber string) (*entity.User, error)
 	FindByID(id string) (*entity.User, error)
 	Update(user entity.User) error
+	UpdatePin(userID, hashedPin string, updatedAt time.Time) error
+	// ResetPin sets a PIN that was reset and records when.
+	ResetPin(userID, hashedPin string, resetAt time.Time) error
+	UpdateVerificationStatus(userID, status string, updatedAt time.Time) error
+	UpdateRole(userID, role string, updatedAt time.Time) error
+	UpdateStatus(tx *sql.Tx, userID, status, reason string, updatedAt time.Time) error
+	UpdateTier(tx *sql.Tx, userID, tier string, updatedAt time.Time) error
+
+	// DeleteUnverified removes a user, and with it the empty wallet, as long
+	// as the phone number was never verified.
+	DeleteUnverified(userID string) error
 }
 
 type userRepository struct {
@@ -25,10 +36,10 @@ func NewUserRepository(db *sql.DB) IUserRepository {
 
 func (r *userRepository) Register(user *entity.User) error {
 	query := `
-		INSERT INTO users (user_id, first_name, last_name, phone_number, pin, address, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (user_id, first_name, last_name, phone_number, pin, address, verification_status, role, status, tier, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := r.db.Exec(query, user.UserID, user.FirstName, user.LastName, user.PhoneNumber, user.Pin, user.Address, user.CreatedAt, user.UpdatedAt)
+	_, err := r.db.Exec(query, user.UserID, user.FirstName, user.LastName, user.PhoneNumber, user.Pin, user.Address, user.VerificationStatus, user.Role, user.Status, user.Tier, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -41,23 +52,26 @@ func (r *userRepository) Register(user *entity.User) error {
 	return err
 }
 
-func (r *userRepository) FindByPhoneNumber(phoneNumber string) (user *entity.User, err error) {
-	query := `
-		SELECT *
-		FROM users 
-		WHERE phone_number = ?
-	`
-	row := r.db.QueryRow(query, phoneNumber)
+const userColumns = `id, user_id, phone_number, pin, first_name, last_name, address, verification_status, totp_secret, totp_enabled, role, status, status_reason, tier, pin_reset_at, created_at, updated_at`
 
-	user = &entity.User{}
+func scanUser(row rowScanner) (*entity.User, error) {
+	user := &entity.User{}
 	var createdAtStr, updatedAtStr string
-	err = row.Scan(&user.ID, &user.UserID, &user.PhoneNumber, &user.Pin, &user.FirstName, &user.LastName, &user.Address, &createdAtStr, &updatedAtStr)
+	var totpSecret, statusReason, pinResetAtStr sql.NullString
+	err := row.Scan(&user.ID, &user.UserID, &user.PhoneNumber, &user.Pin, &user.FirstName, &user.LastName, &user.Address, &user.VerificationStatus, &totpSecret, &user.TOTPEnabled, &user.Role, &user.Status, &statusReason, &user.Tier, &pinResetAtStr, &createdAtStr, &updatedAtStr)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	user.TOTPSecret = totpSecret.String
+	user.StatusReason = statusReason.String
+
+	if pinResetAtStr.Valid {
+		pinResetAt, err := time.Parse("2006-01-02 15:04:05", pinResetAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pin_reset_at: %w", err)
+		}
+		user.PinResetAt = &pinResetAt
+	}
 
 	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
 	if err != nil {
@@ -72,32 +86,27 @@ func (r *userRepository) FindByPhoneNumber(phoneNumber string) (user *entity.Use
 	return user, nil
 }
 
-func (r *userRepository) FindByID(id string) (user *entity.User, err error) {
+func (r *userRepository) FindByPhoneNumber(phoneNumber string) (user *entity.User, err error) {
 	query := `
-		SELECT *
+		SELECT ` + userColumns + `
 		FROM users 
-		WHERE user_id = ?
+		WHERE phone_number = ?
 	`
-	row := r.db.QueryRow(query, id)
-
-	user = &entity.User{}
-	var createdAtStr, updatedAtStr string
-	err = row.Scan(&user.ID, &user.UserID, &user.PhoneNumber, &user.Pin, &user.FirstName, &user.LastName, &user.Address, &createdAtStr, &updatedAtStr)
-	if err != nil {
-		return nil, err
+	user, err = scanUser(r.db.QueryRow(query, phoneNumber))
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
 
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse created_at: %w", err)
-	}
-
-	user.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
-	}
+	return user, err
+}
 
-	return user, nil
+func (r *userRepository) FindByID(id string) (user *entity.User, err error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users 
+		WHERE user_id = ?
+	`
+	return scanUser(r.db.QueryRow(query, id))
 }
 
 func (r *userRepository) Update(user entity.User) error {
@@ -110,3 +119,80 @@ func (r *userRepository) Update(user entity.User) error {
 
 	return err
 }
+
+func (r *userRepository) UpdatePin(userID, hashedPin string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET pin = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, hashedPin, updatedAt, userID)
+
+	return err
+}
+
+func (r *userRepository) ResetPin(userID, hashedPin string, resetAt time.Time) error {
+	query := `
+		UPDATE users
+		SET pin = ?, pin_reset_at = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, hashedPin, resetAt, resetAt, userID)
+
+	return err
+}
+
+func (r *userRepository) UpdateVerificationStatus(userID, status string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET verification_status = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, status, updatedAt, userID)
+
+	return err
+}
+
+func (r *userRepository) UpdateRole(userID, role string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET role = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, role, updatedAt, userID)
+
+	return err
+}
+
+// UpdateStatus runs in tx so the user and wallet status change together.
+func (r *userRepository) UpdateStatus(tx *sql.Tx, userID, status, reason string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET status = ?, status_reason = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := tx.Exec(query, status, reason, updatedAt, userID)
+
+	return err
+}
+
+func (r *userRepository) UpdateTier(tx *sql.Tx, userID, tier string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET tier = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	_, err := tx.Exec(query, tier, updatedAt, userID)
+
+	return err
+}
+
+func (r *userRepository) DeleteUnverified(userID string) error {
+	query := `
+		DELETE FROM users
+		WHERE user_id = ? AND verification_status = ?
+	`
+	_, err := r.db.Exec(query, userID, entity.UserVerificationStatusUnverified)
+
+	return err
+}
@@ -2,12 +2,32 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"sort"
 	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
 )
 
 type IWalletRepository interface {
-	GetCurrentBalance(userID string) (float64, error)
-	UpdateBalance(tx *sql.Tx, userID string, balance float64, updateAt time.Time) error
+	// GetCurrentBalance is a plain read for pre-checks. It must not be used to
+	// compute a new balance; use LockWallets inside the transaction instead.
+	GetCurrentBalance(userID string) (entity.Money, error)
+
+	// LockWallets takes a row lock on every given wallet in ascending user_id
+	// order and returns their balances and statuses. Callers that touch more
+	// than one wallet must lock them all with a single call so the order
+	// stays consistent and two transfers in opposite directions cannot
+	// deadlock. Users without a wallet are left out of the returned map.
+	LockWallets(tx *sql.Tx, userIDs ...string) (map[string]entity.Wallet, error)
+	Credit(tx *sql.Tx, userID string, amount entity.Money, updateAt time.Time) error
+	Debit(tx *sql.Tx, userID string, amount entity.Money, updateAt time.Time) error
+	UpdateStatus(tx *sql.Tx, userID, status string, updateAt time.Time) error
 }
 
 type walletRepository struct {
@@ -18,7 +38,7 @@ func NewWalletRepository(db *sql.DB) IWalletRepository {
 	return &walletRepository{db: db}
 }
 
-func (r *walletRepository) GetCurrentBalance(userID string) (balance float64, err error) {
+func (r *walletRepository) GetCurrentBalance(userID string) (balance entity.Money, err error) {
 	query := `
 		SELECT balance
 		FROM wallets
@@ -26,6 +46,7 @@ func (r *walletRepository) GetCurrentBalance(userID string) (balance float64, er
 	`
 	row := r.db.QueryRow(query, userID)
 
+	balance = entity.NewMoney(0, entity.DefaultCurrency)
 	err = row.Scan(&balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -37,13 +58,103 @@ func (r *walletRepository) GetCurrentBalance(userID string) (balance float64, er
 	return balance, nil
 }
 
-func (r *walletRepository) UpdateBalance(tx *sql.Tx, userID string, balance float64, updateAt time.Time) error {
+func (r *walletRepository) LockWallets(tx *sql.Tx, userIDs ...string) (map[string]entity.Wallet, error) {
+	ordered := make([]string, 0, len(userIDs))
+	seen := map[string]bool{}
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			ordered = append(ordered, userID)
+		}
+	}
+	sort.Strings(ordered)
+
+	query := `
+		SELECT balance, status
+		FROM wallets
+		WHERE user_id = ?
+		FOR UPDATE
+	`
+	wallets := make(map[string]entity.Wallet, len(ordered))
+	for _, userID := range ordered {
+		wallet := entity.Wallet{UserID: userID, Balance: entity.NewMoney(0, entity.DefaultCurrency)}
+		err := tx.QueryRow(query, userID).Scan(&wallet.Balance, &wallet.Status)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		wallets[userID] = wallet
+	}
+
+	return wallets, nil
+}
+
+func (r *walletRepository) Credit(tx *sql.Tx, userID string, amount entity.Money, updateAt time.Time) error {
 	query := `
 		UPDATE wallets
-		SET balance = ?, updated_at = ?
+		SET balance = balance + CAST(? AS DECIMAL(15,2)), updated_at = ?
 		WHERE user_id = ?
 	`
-	_, err := tx.Exec(query, balance, updateAt, userID)
+	result, err := tx.Exec(query, amount, updateAt, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrWalletNotFound
+	}
+
+	return nil
+}
+
+// Debit subtracts amount only when the balance covers it, so the balance can
+// never go negative even if the caller skipped LockWallets.
+func (r *walletRepository) Debit(tx *sql.Tx, userID string, amount entity.Money, updateAt time.Time) error {
+	query := `
+		UPDATE wallets
+		SET balance = balance - CAST(? AS DECIMAL(15,2)), updated_at = ?
+		WHERE user_id = ? AND balance >= CAST(? AS DECIMAL(15,2))
+	`
+	result, err := tx.Exec(query, amount, updateAt, userID, amount)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrInsufficientBalance
+	}
+
+	return nil
+}
+
+func (r *walletRepository) UpdateStatus(tx *sql.Tx, userID, status string, updateAt time.Time) error {
+	query := `
+		UPDATE wallets
+		SET status = ?, updated_at = ?
+		WHERE user_id = ?
+	`
+	result, err := tx.Exec(query, status, updateAt, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrWalletNotFound
+	}
 
-	return err
+	return nil
 }
@@ -0,0 +1,93 @@
+// Package risk decides whether a money movement may be queued. An Engine is
+// a set of rules over an entity.RiskInput; the most severe hit decides.
+package risk
+
+import (
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// Engine is the extension point for other scorers, e.g. a remote service.
+type Engine interface {
+	Evaluate(input *entity.RiskInput) *entity.RiskDecision
+}
+
+// Rule returns a hit when input matches, or nil.
+type Rule interface {
+	Evaluate(input *entity.RiskInput) *entity.RiskRuleHit
+}
+
+type ruleEngine struct {
+	rules []Rule
+}
+
+func NewEngine(rules ...Rule) Engine {
+	return &ruleEngine{rules: rules}
+}
+
+func (e *ruleEngine) Evaluate(input *entity.RiskInput) *entity.RiskDecision {
+	decision := &entity.RiskDecision{
+		TransactionID: input.TransactionID,
+		UserID:        input.UserID,
+		Kind:          input.Kind,
+		TargetUser:    input.TargetUser,
+		Amount:        input.Amount,
+		NewRecipient:  input.NewRecipient,
+		Outcome:       entity.RiskOutcomeAllow,
+		Hits:          []entity.RiskRuleHit{},
+		CreatedAt:     input.CreatedAt,
+	}
+
+	for _, rule := range e.rules {
+		hit := rule.Evaluate(input)
+		if hit == nil {
+			continue
+		}
+		decision.Hits = append(decision.Hits, *hit)
+		if entity.RiskSeverity(hit.Outcome) > entity.RiskSeverity(decision.Outcome) {
+			decision.Outcome = hit.Outcome
+		}
+	}
+
+	return decision
+}
+
+// Config holds the thresholds of the default rules. A zero value turns the
+// rule that uses it off.
+type Config struct {
+	// LargeAmount is what counts as a large payment or transfer.
+	LargeAmount entity.Money
+	// NewDeviceAge is how long a device counts as new after its first
+	// sign-in.
+	NewDeviceAge time.Duration
+	// PinResetWindow blocks transfers for this long after a PIN reset.
+	PinResetWindow time.Duration
+	// BurstWindow and BurstNewRecipients: transfers to this many new
+	// recipients within the window are held.
+	BurstWindow        time.Duration
+	BurstNewRecipients int
+	// RoundAmountUnit, StructuringWindow and StructuringCount: this many
+	// transactions of one kind in multiples of the unit within the window,
+	// each below LargeAmount but together reaching it, are held.
+	RoundAmountUnit   entity.Money
+	StructuringWindow time.Duration
+	StructuringCount  int
+}
+
+// HistoryWindow is how far back the rules look at earlier transactions.
+func (c Config) HistoryWindow() time.Duration {
+	return max(c.BurstWindow, c.StructuringWindow)
+}
+
+// DefaultRules returns the built-in rules. SANCTIONS_MATCH has no threshold
+// and is always on.
+func DefaultRules(cfg Config) []Rule {
+	return []Rule{
+		newDeviceLargeAmount{cfg},
+		newRecipientBurst{cfg},
+		transferAfterPinReset{cfg},
+		roundAmountStructuring{cfg},
+		sanctionsMatch{},
+	}
+}
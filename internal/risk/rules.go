@@ -0,0 +1,164 @@
+package risk
+
+import (
+	"fmt"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// newDeviceLargeAmount holds large payments and transfers from a device that
+// signed in for the first time only recently.
+type newDeviceLargeAmount struct {
+	cfg Config
+}
+
+func (r newDeviceLargeAmount) Evaluate(input *entity.RiskInput) *entity.RiskRuleHit {
+	if input.Kind == entity.TransactionKindTopUp || r.cfg.NewDeviceAge <= 0 || input.DeviceFirstSeenAt.IsZero() {
+		return nil
+	}
+	if !atLeast(input.Amount, r.cfg.LargeAmount) {
+		return nil
+	}
+	if input.CreatedAt.Sub(input.DeviceFirstSeenAt) >= r.cfg.NewDeviceAge {
+		return nil
+	}
+
+	return &entity.RiskRuleHit{
+		Rule:    entity.RiskRuleNewDeviceLargeAmount,
+		Outcome: entity.RiskOutcomeReview,
+		Reason:  fmt.Sprintf("%s of %s from a device first seen %s", input.Kind, input.Amount, input.DeviceFirstSeenAt.Format("2006-01-02 15:04:05")),
+	}
+}
+
+// newRecipientBurst holds a transfer to a new recipient when the user has
+// recently sent money to several other new recipients.
+type newRecipientBurst struct {
+	cfg Config
+}
+
+func (r newRecipientBurst) Evaluate(input *entity.RiskInput) *entity.RiskRuleHit {
+	if input.Kind != entity.TransactionKindTransfer || !input.NewRecipient {
+		return nil
+	}
+	if r.cfg.BurstWindow <= 0 || r.cfg.BurstNewRecipients <= 0 {
+		return nil
+	}
+
+	since := input.CreatedAt.Add(-r.cfg.BurstWindow)
+	recipients := map[string]bool{input.TargetUser: true}
+	for _, earlier := range input.History {
+		if earlier.CreatedAt.Before(since) {
+			break
+		}
+		if earlier.Kind == entity.TransactionKindTransfer && earlier.NewRecipient {
+			recipients[earlier.TargetUser] = true
+		}
+	}
+	if len(recipients) < r.cfg.BurstNewRecipients {
+		return nil
+	}
+
+	return &entity.RiskRuleHit{
+		Rule:    entity.RiskRuleNewRecipientBurst,
+		Outcome: entity.RiskOutcomeReview,
+		Reason:  fmt.Sprintf("transfers to %d new recipients within %s", len(recipients), r.cfg.BurstWindow),
+	}
+}
+
+// transferAfterPinReset blocks transfers shortly after a PIN reset, when a
+// stolen phone number is most likely used to drain the wallet.
+type transferAfterPinReset struct {
+	cfg Config
+}
+
+func (r transferAfterPinReset) Evaluate(input *entity.RiskInput) *entity.RiskRuleHit {
+	if input.Kind != entity.TransactionKindTransfer || r.cfg.PinResetWindow <= 0 || input.PinResetAt.IsZero() {
+		return nil
+	}
+	if input.CreatedAt.Sub(input.PinResetAt) >= r.cfg.PinResetWindow {
+		return nil
+	}
+
+	return &entity.RiskRuleHit{
+		Rule:    entity.RiskRuleTransferAfterPinReset,
+		Outcome: entity.RiskOutcomeBlock,
+		Reason:  fmt.Sprintf("transfer within %s of a PIN reset at %s", r.cfg.PinResetWindow, input.PinResetAt.Format("2006-01-02 15:04:05")),
+	}
+}
+
+// roundAmountStructuring holds a series of round amounts that each stay
+// below the large amount but add up to it.
+type roundAmountStructuring struct {
+	cfg Config
+}
+
+func (r roundAmountStructuring) Evaluate(input *entity.RiskInput) *entity.RiskRuleHit {
+	if r.cfg.StructuringWindow <= 0 || r.cfg.StructuringCount <= 0 || !r.structured(input.Amount) {
+		return nil
+	}
+
+	since := input.CreatedAt.Add(-r.cfg.StructuringWindow)
+	count, total := 1, input.Amount
+	for _, earlier := range input.History {
+		if earlier.CreatedAt.Before(since) {
+			break
+		}
+		if earlier.Kind != input.Kind || earlier.Outcome == entity.RiskOutcomeBlock || !r.structured(earlier.Amount) {
+			continue
+		}
+		sum, err := total.Add(earlier.Amount)
+		if err != nil {
+			continue
+		}
+		count, total = count+1, sum
+	}
+	if count < r.cfg.StructuringCount || !atLeast(total, r.cfg.LargeAmount) {
+		return nil
+	}
+
+	return &entity.RiskRuleHit{
+		Rule:    entity.RiskRuleRoundAmountStructuring,
+		Outcome: entity.RiskOutcomeReview,
+		Reason:  fmt.Sprintf("%d round %s amounts totalling %s within %s", count, input.Kind, total, r.cfg.StructuringWindow),
+	}
+}
+
+// structured reports whether amount is a multiple of the round amount unit
+// below the large amount.
+func (r roundAmountStructuring) structured(amount entity.Money) bool {
+	unit := r.cfg.RoundAmountUnit
+	if !unit.IsPositive() || amount.Amount%unit.Amount != 0 {
+		return false
+	}
+	if _, err := amount.Cmp(unit); err != nil {
+		return false
+	}
+	return !atLeast(amount, r.cfg.LargeAmount)
+}
+
+// sanctionsMatch holds transfers to a user who resembles a watchlist entry
+// until compliance staff have looked at the hit.
+type sanctionsMatch struct{}
+
+func (sanctionsMatch) Evaluate(input *entity.RiskInput) *entity.RiskRuleHit {
+	if len(input.CounterpartyMatches) == 0 {
+		return nil
+	}
+
+	best := input.CounterpartyMatches[0]
+	return &entity.RiskRuleHit{
+		Rule:    entity.RiskRuleSanctionsMatch,
+		Outcome: entity.RiskOutcomeReview,
+		Reason:  fmt.Sprintf("recipient resembles watchlist entry %s (%s, score %.3f)", best.EntryID, best.ListedName, best.Score),
+	}
+}
+
+// atLeast reports whether amount reaches a positive threshold of the same
+// currency.
+func atLeast(amount, threshold entity.Money) bool {
+	if !threshold.IsPositive() {
+		return false
+	}
+	cmp, err := amount.Cmp(threshold)
+	return err == nil && cmp >= 0
+}
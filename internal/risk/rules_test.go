@@ -0,0 +1,431 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+var now = time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+func idr(major int64) entity.Money {
+	return entity.NewMoney(major*100, "")
+}
+
+func testConfig() Config {
+	return Config{
+		LargeAmount:        idr(5000000),
+		NewDeviceAge:       24 * time.Hour,
+		PinResetWindow:     24 * time.Hour,
+		BurstWindow:        time.Hour,
+		BurstNewRecipients: 3,
+		RoundAmountUnit:    idr(100000),
+		StructuringWindow:  24 * time.Hour,
+		StructuringCount:   3,
+	}
+}
+
+// earlier is a decision assessed ago before now.
+func earlier(kind, targetUser string, amount entity.Money, newRecipient bool, outcome string, ago time.Duration) *entity.RiskDecision {
+	return &entity.RiskDecision{
+		Kind:         kind,
+		TargetUser:   targetUser,
+		Amount:       amount,
+		NewRecipient: newRecipient,
+		Outcome:      outcome,
+		CreatedAt:    now.Add(-ago),
+	}
+}
+
+func checkHit(t *testing.T, name string, hit *entity.RiskRuleHit, rule, outcome string) {
+	t.Helper()
+	if rule == "" {
+		if hit != nil {
+			t.Errorf("%s: unexpected hit %+v", name, hit)
+		}
+		return
+	}
+	if hit == nil {
+		t.Errorf("%s: no hit, want %s", name, rule)
+		return
+	}
+	if hit.Rule != rule || hit.Outcome != outcome || hit.Reason == "" {
+		t.Errorf("%s: hit %+v, want %s %s", name, hit, rule, outcome)
+	}
+}
+
+func TestNewDeviceLargeAmount(t *testing.T) {
+	cfg := testConfig()
+	tests := []struct {
+		name      string
+		cfg       Config
+		kind      string
+		amount    entity.Money
+		firstSeen time.Time
+		hit       bool
+	}{
+		{name: "large transfer from a new device", kind: entity.TransactionKindTransfer, amount: idr(5000000), firstSeen: now.Add(-time.Hour), hit: true},
+		{name: "large payment from a new device", kind: entity.TransactionKindPayment, amount: idr(6000000), firstSeen: now.Add(-time.Hour), hit: true},
+		{name: "just below the large amount", kind: entity.TransactionKindTransfer, amount: entity.NewMoney(idr(5000000).Amount-1, ""), firstSeen: now.Add(-time.Hour)},
+		{name: "device just old enough", kind: entity.TransactionKindTransfer, amount: idr(5000000), firstSeen: now.Add(-24 * time.Hour)},
+		{name: "device almost old enough", kind: entity.TransactionKindTransfer, amount: idr(5000000), firstSeen: now.Add(-24*time.Hour + time.Second), hit: true},
+		{name: "top ups are not checked", kind: entity.TransactionKindTopUp, amount: idr(9000000), firstSeen: now.Add(-time.Hour)},
+		{name: "no session", kind: entity.TransactionKindTransfer, amount: idr(9000000)},
+		{name: "rule off", cfg: Config{LargeAmount: cfg.LargeAmount}, kind: entity.TransactionKindTransfer, amount: idr(9000000), firstSeen: now.Add(-time.Hour)},
+		{name: "no large amount", cfg: Config{NewDeviceAge: time.Hour}, kind: entity.TransactionKindTransfer, amount: idr(9000000), firstSeen: now.Add(-time.Minute)},
+	}
+
+	for _, tt := range tests {
+		ruleCfg := cfg
+		if tt.cfg != (Config{}) {
+			ruleCfg = tt.cfg
+		}
+		hit := newDeviceLargeAmount{ruleCfg}.Evaluate(&entity.RiskInput{
+			Kind:              tt.kind,
+			Amount:            tt.amount,
+			CreatedAt:         now,
+			DeviceFirstSeenAt: tt.firstSeen,
+		})
+		want := ""
+		if tt.hit {
+			want = entity.RiskRuleNewDeviceLargeAmount
+		}
+		checkHit(t, tt.name, hit, want, entity.RiskOutcomeReview)
+	}
+}
+
+func TestNewRecipientBurst(t *testing.T) {
+	transfer := entity.TransactionKindTransfer
+	tests := []struct {
+		name         string
+		kind         string
+		newRecipient bool
+		history      []*entity.RiskDecision
+		hit          bool
+	}{
+		{
+			name:         "third new recipient",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, 20*time.Minute),
+			},
+			hit: true,
+		},
+		{
+			name:         "second new recipient",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+			},
+		},
+		{
+			name:         "same recipient counts once",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 20*time.Minute),
+				earlier(transfer, "a", idr(10), true, entity.RiskOutcomeAllow, 30*time.Minute),
+			},
+		},
+		{
+			name:         "known recipients do not count",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), false, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, 20*time.Minute),
+			},
+		},
+		{
+			name:         "oldest transfer exactly at the window start",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, time.Hour),
+			},
+			hit: true,
+		},
+		{
+			name:         "oldest transfer outside the window",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, time.Hour+time.Second),
+			},
+		},
+		{
+			name: "known recipient is never held",
+			kind: transfer,
+			history: []*entity.RiskDecision{
+				earlier(transfer, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, 20*time.Minute),
+			},
+		},
+		{
+			name:         "payments do not count",
+			kind:         transfer,
+			newRecipient: true,
+			history: []*entity.RiskDecision{
+				earlier(entity.TransactionKindPayment, "b", idr(10), true, entity.RiskOutcomeAllow, 10*time.Minute),
+				earlier(transfer, "c", idr(10), true, entity.RiskOutcomeAllow, 20*time.Minute),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		hit := newRecipientBurst{testConfig()}.Evaluate(&entity.RiskInput{
+			Kind:         tt.kind,
+			TargetUser:   "a",
+			Amount:       idr(10),
+			CreatedAt:    now,
+			NewRecipient: tt.newRecipient,
+			History:      tt.history,
+		})
+		want := ""
+		if tt.hit {
+			want = entity.RiskRuleNewRecipientBurst
+		}
+		checkHit(t, tt.name, hit, want, entity.RiskOutcomeReview)
+	}
+
+	off := newRecipientBurst{Config{BurstWindow: time.Hour}}.Evaluate(&entity.RiskInput{
+		Kind:         transfer,
+		TargetUser:   "a",
+		CreatedAt:    now,
+		NewRecipient: true,
+	})
+	checkHit(t, "rule off", off, "", "")
+}
+
+func TestTransferAfterPinReset(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		resetAt time.Time
+		hit     bool
+	}{
+		{name: "transfer right after a reset", kind: entity.TransactionKindTransfer, resetAt: now.Add(-time.Minute), hit: true},
+		{name: "transfer almost a day later", kind: entity.TransactionKindTransfer, resetAt: now.Add(-24*time.Hour + time.Second), hit: true},
+		{name: "transfer a day later", kind: entity.TransactionKindTransfer, resetAt: now.Add(-24 * time.Hour)},
+		{name: "payment after a reset", kind: entity.TransactionKindPayment, resetAt: now.Add(-time.Minute)},
+		{name: "never reset", kind: entity.TransactionKindTransfer},
+	}
+
+	for _, tt := range tests {
+		hit := transferAfterPinReset{testConfig()}.Evaluate(&entity.RiskInput{
+			Kind:       tt.kind,
+			Amount:     idr(10),
+			CreatedAt:  now,
+			PinResetAt: tt.resetAt,
+		})
+		want := ""
+		if tt.hit {
+			want = entity.RiskRuleTransferAfterPinReset
+		}
+		checkHit(t, tt.name, hit, want, entity.RiskOutcomeBlock)
+	}
+
+	off := transferAfterPinReset{Config{}}.Evaluate(&entity.RiskInput{
+		Kind:       entity.TransactionKindTransfer,
+		CreatedAt:  now,
+		PinResetAt: now,
+	})
+	checkHit(t, "rule off", off, "", "")
+}
+
+func TestRoundAmountStructuring(t *testing.T) {
+	payment := entity.TransactionKindPayment
+	allow := entity.RiskOutcomeAllow
+	tests := []struct {
+		name    string
+		amount  entity.Money
+		history []*entity.RiskDecision
+		hit     bool
+	}{
+		{
+			name:   "three round amounts reaching the large amount",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(1000000), false, allow, 2*time.Hour),
+			},
+			hit: true,
+		},
+		{
+			name:   "three round amounts below the large amount",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(900000), false, allow, 2*time.Hour),
+			},
+		},
+		{
+			name:   "two round amounts",
+			amount: idr(4000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(4000000), false, allow, time.Hour),
+			},
+		},
+		{
+			name:   "current amount is not round",
+			amount: idr(2000001),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 2*time.Hour),
+			},
+		},
+		{
+			name:   "current amount is large",
+			amount: idr(5000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 2*time.Hour),
+			},
+		},
+		{
+			name:   "earlier amounts that are not round or are large",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000050), false, allow, time.Hour),
+				earlier(payment, "", idr(5000000), false, allow, 2*time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 3*time.Hour),
+			},
+		},
+		{
+			name:   "blocked and other kinds do not count",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, entity.RiskOutcomeBlock, time.Hour),
+				earlier(entity.TransactionKindTransfer, "b", idr(2000000), false, allow, 2*time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 3*time.Hour),
+			},
+		},
+		{
+			name:   "held transactions count",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, entity.RiskOutcomeReview, time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 3*time.Hour),
+			},
+			hit: true,
+		},
+		{
+			name:   "oldest exactly at the window start",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 24*time.Hour),
+			},
+			hit: true,
+		},
+		{
+			name:   "oldest outside the window",
+			amount: idr(2000000),
+			history: []*entity.RiskDecision{
+				earlier(payment, "", idr(2000000), false, allow, time.Hour),
+				earlier(payment, "", idr(2000000), false, allow, 24*time.Hour+time.Second),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		hit := roundAmountStructuring{testConfig()}.Evaluate(&entity.RiskInput{
+			Kind:      payment,
+			Amount:    tt.amount,
+			CreatedAt: now,
+			History:   tt.history,
+		})
+		want := ""
+		if tt.hit {
+			want = entity.RiskRuleRoundAmountStructuring
+		}
+		checkHit(t, tt.name, hit, want, entity.RiskOutcomeReview)
+	}
+}
+
+func TestStructured(t *testing.T) {
+	rule := roundAmountStructuring{testConfig()}
+	tests := []struct {
+		amount entity.Money
+		want   bool
+	}{
+		{amount: idr(100000), want: true},
+		{amount: idr(4900000), want: true},
+		{amount: idr(5000000)},
+		{amount: idr(150000)},
+		{amount: idr(0), want: true},
+		{amount: entity.NewMoney(idr(100000).Amount, "USD")},
+	}
+	for _, tt := range tests {
+		if got := rule.structured(tt.amount); got != tt.want {
+			t.Errorf("structured(%s %s) = %v, want %v", tt.amount, tt.amount.Currency, got, tt.want)
+		}
+	}
+
+	if (roundAmountStructuring{Config{LargeAmount: idr(10)}}).structured(idr(1)) {
+		t.Error("structured without a unit reported a round amount")
+	}
+}
+
+func TestSanctionsMatch(t *testing.T) {
+	checkHit(t, "no matches", sanctionsMatch{}.Evaluate(&entity.RiskInput{}), "", "")
+
+	hit := sanctionsMatch{}.Evaluate(&entity.RiskInput{
+		CounterpartyMatches: []entity.ScreeningMatch{
+			{EntryID: "36", ListedName: "AERO CARIBBEAN", Score: 0.97},
+			{EntryID: "12", ListedName: "OTHER", Score: 0.91},
+		},
+	})
+	checkHit(t, "matches", hit, entity.RiskRuleSanctionsMatch, entity.RiskOutcomeReview)
+	if hit != nil && hit.Reason != "recipient resembles watchlist entry 36 (AERO CARIBBEAN, score 0.970)" {
+		t.Errorf("reason = %q", hit.Reason)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		amount, threshold entity.Money
+		want              bool
+	}{
+		{amount: idr(10), threshold: idr(10), want: true},
+		{amount: idr(11), threshold: idr(10), want: true},
+		{amount: entity.NewMoney(idr(10).Amount-1, ""), threshold: idr(10)},
+		{amount: idr(10), threshold: idr(0)},
+		{amount: idr(10), threshold: idr(-1)},
+		{amount: entity.NewMoney(idr(20).Amount, "USD"), threshold: idr(10)},
+	}
+	for _, tt := range tests {
+		if got := atLeast(tt.amount, tt.threshold); got != tt.want {
+			t.Errorf("atLeast(%s %s, %s) = %v, want %v", tt.amount, tt.amount.Currency, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestEngineMostSevereHitDecides(t *testing.T) {
+	engine := NewEngine(DefaultRules(testConfig())...)
+
+	allowed := engine.Evaluate(&entity.RiskInput{Kind: entity.TransactionKindTopUp, Amount: idr(10), CreatedAt: now})
+	if allowed.Outcome != entity.RiskOutcomeAllow || len(allowed.Hits) != 0 {
+		t.Errorf("top up = %+v", allowed)
+	}
+
+	blocked := engine.Evaluate(&entity.RiskInput{
+		TransactionID:       "t1",
+		Kind:                entity.TransactionKindTransfer,
+		TargetUser:          "a",
+		Amount:              idr(10),
+		CreatedAt:           now,
+		PinResetAt:          now.Add(-time.Minute),
+		CounterpartyMatches: []entity.ScreeningMatch{{EntryID: "36", ListedName: "X", Score: 0.95}},
+	})
+	if blocked.Outcome != entity.RiskOutcomeBlock || len(blocked.Hits) != 2 || blocked.TransactionID != "t1" {
+		t.Errorf("transfer = %+v", blocked)
+	}
+}
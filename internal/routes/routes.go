@@ -1,37 +1,147 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
 	"github.com/leonardoong/e-wallet/internal/handler"
 	"github.com/leonardoong/e-wallet/internal/middleware"
+	"github.com/leonardoong/e-wallet/internal/rbac"
+	"github.com/leonardoong/e-wallet/internal/repository"
 	"github.com/leonardoong/e-wallet/internal/service"
 )
 
-func SetupRoutes(router *gin.Engine, authService service.IAuthService, transactionService service.ITransactionService) {
+func SetupRoutes(router *gin.Engine, cfg *config.Config, auditLogger audit.Logger, authService service.IAuthService, userService service.IUserService, accountService service.IAccountService, mfaService service.IMFAService, transactionService service.ITransactionService, confirmationService service.IConfirmationService, jobService service.IJobService, auditService service.IAuditService, kycService service.IKYCService, riskService service.IRiskService, complianceService service.IComplianceService, idempotencyRepo repository.IIdempotencyRepository) {
 	authHandler := handler.AuthHandler{
 		AuthService: authService,
 	}
 
+	userHandler := handler.UserHandler{
+		UserService: userService,
+	}
+
+	accountHandler := handler.AccountHandler{
+		AccountService: accountService,
+	}
+
+	mfaHandler := handler.MFAHandler{
+		MFAService:  mfaService,
+		AuthService: authService,
+	}
+
 	transactionHandler := handler.TransactionHandler{
+		TransactionService:  transactionService,
+		ConfirmationService: confirmationService,
+	}
+
+	jobHandler := handler.JobHandler{
+		JobService: jobService,
+	}
+
+	auditHandler := handler.AuditHandler{
+		AuditService: auditService,
+	}
+
+	kycHandler := handler.KYCHandler{
+		KYCService:    kycService,
+		MaxImageBytes: cfg.KYCMaxImageBytes,
+	}
+
+	riskHandler := handler.RiskHandler{
+		RiskService:        riskService,
 		TransactionService: transactionService,
 	}
 
+	complianceHandler := handler.ComplianceHandler{
+		ComplianceService: complianceService,
+		MaxUploadBytes:    cfg.CaseMaxUploadBytes,
+	}
+
 	jwtMiddleware := middleware.JWTMiddleware{
 		AuthService: authService,
 	}
 
+	idempotencyMiddleware := middleware.IdempotencyMiddleware{
+		Repository: idempotencyRepo,
+		TTL:        time.Duration(cfg.IdempotencyTTLHours) * time.Hour,
+	}
+
+	rbacMiddleware := middleware.RBACMiddleware{
+		AuditLogger: auditLogger,
+	}
+	can := rbacMiddleware.RequirePermission
+
 	publicRoutes := router.Group("")
+	publicRoutes.GET("/.well-known/jwks.json", authHandler.JWKS)
 	publicRoutes.POST("/register", authHandler.Register)
+	publicRoutes.POST("/register/verify", authHandler.VerifyRegistration)
+	publicRoutes.POST("/register/verify/resend", authHandler.ResendVerification)
 	publicRoutes.POST("/login", authHandler.Login)
+	publicRoutes.POST("/token/refresh", authHandler.RefreshToken)
+	publicRoutes.POST("/pin/reset/request", authHandler.RequestPinReset)
+	publicRoutes.POST("/pin/reset/confirm", authHandler.ConfirmPinReset)
 
 	protectedRoutes := router.Group("")
 	protectedRoutes.Use(jwtMiddleware.AuthRequired())
 	protectedRoutes.PUT("/profile", authHandler.UpdateProfile)
-	protectedRoutes.POST("/topup", transactionHandler.TopUp)
+	protectedRoutes.PUT("/pin", authHandler.ChangePin)
+	protectedRoutes.POST("/logout", authHandler.Logout)
+	protectedRoutes.POST("/logout/all", authHandler.LogoutAll)
+	protectedRoutes.GET("/sessions", authHandler.ListSessions)
+	protectedRoutes.DELETE("/sessions/:session_id", authHandler.RevokeSession)
+	protectedRoutes.POST("/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+	protectedRoutes.POST("/mfa/totp/activate", mfaHandler.ActivateTOTP)
+	protectedRoutes.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+	protectedRoutes.GET("/kyc", kycHandler.Status)
+	protectedRoutes.POST("/kyc/submissions", kycHandler.Submit)
 	protectedRoutes.GET("/topup/:top_up_id", transactionHandler.FindTopUp)
-	protectedRoutes.POST("/payment", transactionHandler.Payment)
 	protectedRoutes.GET("/payment/:payment_id", transactionHandler.FindPayment)
-	protectedRoutes.POST("/transfer", transactionHandler.Transfer)
 	protectedRoutes.GET("/transfer/:transfer_id", transactionHandler.FindTransfer)
 	protectedRoutes.GET("/transactions", transactionHandler.FindTransactions)
+	protectedRoutes.POST("/transactions/confirm", transactionHandler.Confirm)
+
+	mutatingTransactionRoutes := protectedRoutes.Group("")
+	mutatingTransactionRoutes.Use(idempotencyMiddleware.Handle())
+	mutatingTransactionRoutes.POST("/topup", transactionHandler.TopUp)
+	mutatingTransactionRoutes.POST("/payment", transactionHandler.Payment)
+	mutatingTransactionRoutes.POST("/transfer", transactionHandler.Transfer)
+
+	adminRoutes := router.Group("/admin")
+	adminRoutes.Use(jwtMiddleware.AuthRequired())
+	adminRoutes.GET("/users", can(rbac.PermissionUsersRead), userHandler.FindUserByPhoneNumber)
+	adminRoutes.GET("/users/:user_id", can(rbac.PermissionUsersRead), userHandler.FindUser)
+	adminRoutes.GET("/users/:user_id/transactions", can(rbac.PermissionWalletsRead), transactionHandler.FindUserTransactions)
+	adminRoutes.PUT("/users/:user_id/role", can(rbac.PermissionRolesManage), userHandler.SetRole)
+	adminRoutes.PUT("/users/:user_id/status", can(rbac.PermissionAccountsStatus), accountHandler.SetStatus)
+	adminRoutes.POST("/users/:user_id/close", can(rbac.PermissionAccountsClose), accountHandler.Close)
+	adminRoutes.POST("/users/:user_id/revoke-sessions", can(rbac.PermissionSessionsRevoke), authHandler.RevokeUserSessions)
+	adminRoutes.POST("/users/:user_id/unlock", can(rbac.PermissionLoginsUnlock), authHandler.UnlockLogin)
+	adminRoutes.GET("/jobs/stats", can(rbac.PermissionJobsRead), jobHandler.Stats)
+	adminRoutes.GET("/jobs/dead", can(rbac.PermissionJobsRead), jobHandler.ListDeadJobs)
+	adminRoutes.POST("/jobs/dead/retry", can(rbac.PermissionJobsManage), jobHandler.RetryDeadJobs)
+	adminRoutes.POST("/jobs/dead/discard", can(rbac.PermissionJobsManage), jobHandler.DiscardDeadJobs)
+	adminRoutes.POST("/jobs/dead/:died_at/:job_id/retry", can(rbac.PermissionJobsManage), jobHandler.RetryDeadJob)
+	adminRoutes.DELETE("/jobs/dead/:died_at/:job_id", can(rbac.PermissionJobsManage), jobHandler.DiscardDeadJob)
+	adminRoutes.GET("/audit-events", can(rbac.PermissionAuditRead), auditHandler.FindEvents)
+	adminRoutes.GET("/risk/decisions", can(rbac.PermissionRiskReview), riskHandler.FindDecisions)
+	adminRoutes.GET("/transactions/:transaction_id/risk", can(rbac.PermissionRiskReview), riskHandler.FindDecision)
+	adminRoutes.POST("/transactions/:transaction_id/release", can(rbac.PermissionRiskReview), riskHandler.Release)
+	adminRoutes.POST("/transactions/:transaction_id/reject", can(rbac.PermissionRiskReview), riskHandler.Reject)
+	adminRoutes.POST("/compliance/cases", can(rbac.PermissionCasesManage), complianceHandler.Create)
+	adminRoutes.GET("/compliance/cases", can(rbac.PermissionCasesManage), complianceHandler.FindCases)
+	adminRoutes.GET("/compliance/cases/:case_id", can(rbac.PermissionCasesManage), complianceHandler.FindCase)
+	adminRoutes.PUT("/compliance/cases/:case_id/assignee", can(rbac.PermissionCasesManage), complianceHandler.Assign)
+	adminRoutes.PUT("/compliance/cases/:case_id/status", can(rbac.PermissionCasesManage), complianceHandler.SetStatus)
+	adminRoutes.POST("/compliance/cases/:case_id/transactions", can(rbac.PermissionCasesManage), complianceHandler.LinkTransaction)
+	adminRoutes.POST("/compliance/cases/:case_id/notes", can(rbac.PermissionCasesManage), complianceHandler.AddNote)
+	adminRoutes.POST("/compliance/cases/:case_id/attachments", can(rbac.PermissionCasesManage), complianceHandler.AddAttachment)
+	adminRoutes.GET("/compliance/cases/:case_id/attachments/:attachment_id", can(rbac.PermissionCasesManage), complianceHandler.Attachment)
+	adminRoutes.POST("/compliance/cases/:case_id/close", can(rbac.PermissionCasesManage), complianceHandler.Close)
+	adminRoutes.GET("/kyc/submissions", can(rbac.PermissionKYCReview), kycHandler.FindSubmissions)
+	adminRoutes.GET("/kyc/submissions/:submission_id", can(rbac.PermissionKYCReview), kycHandler.FindSubmission)
+	adminRoutes.GET("/kyc/submissions/:submission_id/documents/:document", can(rbac.PermissionKYCReview), kycHandler.Document)
+	adminRoutes.POST("/kyc/submissions/:submission_id/approve", can(rbac.PermissionKYCReview), kycHandler.Approve)
+	adminRoutes.POST("/kyc/submissions/:submission_id/reject", can(rbac.PermissionKYCReview), kycHandler.Reject)
 }
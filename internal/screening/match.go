@@ -0,0 +1,126 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+// name is a name in the form it is compared in: lower case letters and
+// digits, words separated by single spaces, once as written and once with the
+// words sorted, so "Smith John" matches "John Smith".
+type name struct {
+	full   string
+	sorted string
+}
+
+func newName(value string) name {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	full := strings.Join(words, " ")
+	sort.Strings(words)
+	return name{full: full, sorted: strings.Join(words, " ")}
+}
+
+// score is the Jaro-Winkler similarity of two names, from 0 to 1.
+func (n name) score(other name) float64 {
+	if n.full == "" || other.full == "" {
+		return 0
+	}
+	return max(jaroWinkler(n.full, other.full), jaroWinkler(n.sorted, other.sorted))
+}
+
+// Match returns the entries whose name or alias scores at least minScore
+// against firstName and lastName, best first.
+func (l *List) Match(firstName, lastName string, minScore float64) []entity.ScreeningMatch {
+	if l == nil {
+		return nil
+	}
+
+	screened := newName(joinName(firstName, lastName))
+	var matches []entity.ScreeningMatch
+	for _, entry := range l.Entries {
+		best, bestIndex := 0.0, 0
+		for i, listed := range entry.names {
+			if score := screened.score(listed); score > best {
+				best, bestIndex = score, i
+			}
+		}
+		if best < minScore {
+			continue
+		}
+
+		matches = append(matches, entity.ScreeningMatch{
+			EntryID:      entry.ID,
+			EntryVersion: entry.Version,
+			ListedName:   entry.Names[bestIndex],
+			Programs:     entry.Programs,
+			Score:        float64(int(best*1000)) / 1000,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// jaroWinkler boosts the Jaro similarity of a and b for a common prefix of up
+// to four characters.
+func jaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	similarity := jaro(s1, s2)
+
+	prefix := 0
+	for prefix < min(len(s1), len(s2), 4) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return similarity + float64(prefix)*0.1*(1-similarity)
+}
+
+func jaro(s1, s2 []rune) float64 {
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		for j := max(0, i-window); j < min(len(s2), i+window+1); j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	return (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+}
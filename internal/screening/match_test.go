@@ -0,0 +1,94 @@
+package screening
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "martha", b: "marhta", want: 0.961},
+		{a: "dixon", b: "dicksonx", want: 0.813},
+		{a: "dwayne", b: "duane", want: 0.840},
+		{a: "jones", b: "johnson", want: 0.832},
+		{a: "abc", b: "abc", want: 1},
+		{a: "abc", b: "xyz", want: 0},
+		{a: "", b: "abc", want: 0},
+		{a: "a", b: "a", want: 1},
+	}
+	for _, tt := range tests {
+		if got := jaroWinkler(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("jaroWinkler(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+		if got, back := jaroWinkler(tt.a, tt.b), jaroWinkler(tt.b, tt.a); math.Abs(got-back) > 1e-9 {
+			t.Errorf("jaroWinkler(%q, %q) = %f but %f the other way round", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func TestNewName(t *testing.T) {
+	tests := []struct {
+		value, full, sorted string
+	}{
+		{value: "John Smith", full: "john smith", sorted: "john smith"},
+		{value: "  SMITH,  John-Paul ", full: "smith john paul", sorted: "john paul smith"},
+		{value: "Abu Bakr al-Baghdadi", full: "abu bakr al baghdadi", sorted: "abu al baghdadi bakr"},
+		{value: "José Núñez", full: "josé núñez", sorted: "josé núñez"},
+		{value: "Agent 007", full: "agent 007", sorted: "007 agent"},
+		{value: " -. ", full: "", sorted: ""},
+	}
+	for _, tt := range tests {
+		n := newName(tt.value)
+		if n.full != tt.full || n.sorted != tt.sorted {
+			t.Errorf("newName(%q) = %q / %q, want %q / %q", tt.value, n.full, n.sorted, tt.full, tt.sorted)
+		}
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	if score := newName("Smith John").score(newName("JOHN SMITH")); score != 1 {
+		t.Errorf("swapped words score %.3f, want 1", score)
+	}
+	if score := newName("").score(newName("John Smith")); score != 0 {
+		t.Errorf("empty name scores %.3f", score)
+	}
+	if score := newName("Jon Smyth").score(newName("John Smith")); score < 0.9 || score >= 1 {
+		t.Errorf("near spelling scores %.3f", score)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	list := &List{Entries: []*Entry{
+		{ID: "1", Names: []string{"John Smith", "Johnny Smyth"}, Programs: []string{"SDGT"}},
+		{ID: "2", Names: []string{"Maria Lopez"}},
+		{ID: "3", Names: []string{"Jon Smith"}},
+	}}
+	for _, entry := range list.Entries {
+		for _, listed := range entry.Names {
+			entry.names = append(entry.names, newName(listed))
+		}
+	}
+
+	matches := list.Match("Smith", "John", 0.9)
+	if len(matches) != 2 {
+		t.Fatalf("Match = %+v, want 2 matches", matches)
+	}
+	if matches[0].EntryID != "1" || matches[0].ListedName != "John Smith" || matches[0].Score != 1 || matches[0].Programs[0] != "SDGT" {
+		t.Errorf("best match = %+v", matches[0])
+	}
+	if matches[1].EntryID != "3" || matches[1].Score >= matches[0].Score {
+		t.Errorf("second match = %+v", matches[1])
+	}
+
+	if matches := list.Match("Budi", "Santoso", 0.9); len(matches) != 0 {
+		t.Errorf("unrelated name matched %+v", matches)
+	}
+
+	var none *List
+	if matches := none.Match("John", "Smith", 0.9); matches != nil {
+		t.Errorf("nil list matched %+v", matches)
+	}
+}
@@ -0,0 +1,283 @@
+// Package screening holds the sanctions and PEP watchlist. The list is read
+// from a local file in one of the OFAC SDN formats and reloaded whenever it
+// changes, so a new publication can be dropped in without a restart.
+package screening
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sdnTypeIndividual marks people on the list. Only they are screened, since
+// users are people.
+const sdnTypeIndividual = "individual"
+
+// sdnNull is how the OFAC CSV files write an empty field.
+const sdnNull = "-0-"
+
+// Entry is a listed individual.
+type Entry struct {
+	ID string
+	// Names holds the listed name first, then its aliases.
+	Names    []string
+	Programs []string
+	// Version changes whenever the names or programs of the entry do, so a
+	// match cleared by staff is screened again once the listing changes.
+	Version string
+
+	// names are Names ready for matching.
+	names []name
+}
+
+// List is a loaded watchlist.
+type List struct {
+	Entries []*Entry
+}
+
+// Load reads a list file: the OFAC SDN XML (sdn.xml) when path ends in .xml,
+// otherwise the OFAC SDN CSV (sdn.csv).
+func Load(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []*Entry
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		entries, err = parseXML(file)
+	} else {
+		entries, err = parseCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: no individuals listed", path)
+	}
+
+	for _, entry := range entries {
+		for _, listed := range entry.Names {
+			entry.names = append(entry.names, newName(listed))
+		}
+		entry.Version = entryVersion(entry)
+	}
+	return &List{Entries: entries}, nil
+}
+
+// entryVersion is a short hash of the names and programs of entry.
+func entryVersion(entry *Entry) string {
+	hash := sha256.New()
+	for _, value := range append(append([]string{entry.ID}, entry.Names...), entry.Programs...) {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+// parseCSV reads sdn.csv: ent_num, SDN_Name, SDN_Type, Program, and further
+// columns that are not used. Individuals are listed as "LAST, First".
+func parseCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 4 || !strings.EqualFold(csvField(record[2]), sdnTypeIndividual) {
+			continue
+		}
+
+		listed := csvField(record[1])
+		if last, first, ok := strings.Cut(listed, ","); ok {
+			listed = strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+
+		var programs []string
+		for _, program := range strings.Split(csvField(record[3]), "] [") {
+			if program = strings.Trim(program, "[] "); program != "" {
+				programs = append(programs, program)
+			}
+		}
+
+		entries = append(entries, &Entry{
+			ID:       csvField(record[0]),
+			Names:    []string{listed},
+			Programs: programs,
+		})
+	}
+	return entries, nil
+}
+
+func csvField(value string) string {
+	value = strings.TrimSpace(value)
+	if value == sdnNull {
+		return ""
+	}
+	return value
+}
+
+type sdnList struct {
+	Entries []sdnEntry `xml:"sdnEntry"`
+}
+
+type sdnEntry struct {
+	UID       string   `xml:"uid"`
+	FirstName string   `xml:"firstName"`
+	LastName  string   `xml:"lastName"`
+	Type      string   `xml:"sdnType"`
+	Programs  []string `xml:"programList>program"`
+	AKAs      []struct {
+		FirstName string `xml:"firstName"`
+		LastName  string `xml:"lastName"`
+	} `xml:"akaList>aka"`
+}
+
+// parseXML reads sdn.xml, which also lists the aliases of each entry.
+func parseXML(r io.Reader) ([]*Entry, error) {
+	var list sdnList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, sdn := range list.Entries {
+		if !strings.EqualFold(sdn.Type, sdnTypeIndividual) {
+			continue
+		}
+
+		entry := &Entry{
+			ID:       sdn.UID,
+			Names:    []string{joinName(sdn.FirstName, sdn.LastName)},
+			Programs: sdn.Programs,
+		}
+		for _, aka := range sdn.AKAs {
+			entry.Names = append(entry.Names, joinName(aka.FirstName, aka.LastName))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func joinName(firstName, lastName string) string {
+	return strings.TrimSpace(strings.TrimSpace(firstName) + " " + strings.TrimSpace(lastName))
+}
+
+// Store holds the list currently in force. Without a file nobody is
+// screened.
+type Store struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	list    *List
+	modTime time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStore loads the list file at path, if any, and reloads it every
+// interval once started.
+func NewStore(path string, interval time.Duration) (*Store, error) {
+	s := &Store{
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if path == "" {
+		return s, nil
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns the list in force, nil without a file.
+func (s *Store) List() *List {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list
+}
+
+// Reload loads the file again if its modification time changed. On error the
+// previous list stays in force.
+func (s *Store) Reload() (reloaded bool, err error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := s.list != nil && info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	list, err := Load(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.list = list
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Start polls the list file for changes. It does nothing without a file.
+func (s *Store) Start() {
+	if s.path == "" {
+		close(s.done)
+		return
+	}
+	go s.run()
+}
+
+func (s *Store) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.Reload()
+		if err != nil {
+			log.Printf("screening: keeping previous list: %v", err)
+		} else if reloaded {
+			log.Printf("screening: reloaded %s, %d individuals", s.path, len(s.List().Entries))
+		}
+	}
+}
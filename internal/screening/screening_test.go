@@ -0,0 +1,131 @@
+package screening
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const sdnCSV = `36,"AEROCARIBBEAN AIRLINES",-0-,"CUBA",-0-,-0-,-0-,-0-,-0-,-0-,-0-,-0-
+173,"ABU ABBAS, Mohammed","individual","SDGT] [NPWMD",-0-,-0-,-0-,-0-,-0-,-0-,-0-,-0-
+306,"BANCO NACIONAL DE CUBA",-0-,"CUBA",-0-,-0-,-0-,-0-,-0-,-0-,-0-,-0-
+2674,"NORIEGA MORENO, Manuel Antonio","individual","[NARCOTICS]",-0-,-0-,-0-,-0-,-0-,-0-,-0-,"DOB 11 Feb 1934."
+9001,"MADONNA","individual",-0-
+`
+
+const sdnXML = `<?xml version="1.0" standalone="yes"?>
+<sdnList>
+  <publshInformation><Publish_Date>03/15/2024</Publish_Date></publshInformation>
+  <sdnEntry>
+    <uid>36</uid>
+    <lastName>AEROCARIBBEAN AIRLINES</lastName>
+    <sdnType>Entity</sdnType>
+    <programList><program>CUBA</program></programList>
+  </sdnEntry>
+  <sdnEntry>
+    <uid>173</uid>
+    <firstName>Mohammed</firstName>
+    <lastName>ABU ABBAS</lastName>
+    <sdnType>Individual</sdnType>
+    <programList><program>SDGT</program><program>NPWMD</program></programList>
+    <akaList>
+      <aka><uid>1</uid><type>a.k.a.</type><category>strong</category><firstName>Muhammad</firstName><lastName>ABBAS</lastName></aka>
+      <aka><uid>2</uid><type>a.k.a.</type><category>weak</category><lastName>ABU KHALED</lastName></aka>
+    </akaList>
+  </sdnEntry>
+</sdnList>
+`
+
+func writeList(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCSV(t *testing.T) {
+	list, err := Load(writeList(t, "sdn.csv", sdnCSV))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := []Entry{
+		{ID: "173", Names: []string{"Mohammed ABU ABBAS"}, Programs: []string{"SDGT", "NPWMD"}},
+		{ID: "2674", Names: []string{"Manuel Antonio NORIEGA MORENO"}, Programs: []string{"NARCOTICS"}},
+		{ID: "9001", Names: []string{"MADONNA"}},
+	}
+	if len(list.Entries) != len(want) {
+		t.Fatalf("loaded %d entries, want %d", len(list.Entries), len(want))
+	}
+	for i, entry := range list.Entries {
+		if entry.ID != want[i].ID || !reflect.DeepEqual(entry.Names, want[i].Names) || !reflect.DeepEqual(entry.Programs, want[i].Programs) {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+		if entry.Version == "" {
+			t.Errorf("entry %s has no version", entry.ID)
+		}
+		if len(entry.names) != len(entry.Names) {
+			t.Errorf("entry %s has %d names ready for matching", entry.ID, len(entry.names))
+		}
+	}
+
+	if matches := list.Match("Mohammed", "Abu Abbas", 0.9); len(matches) != 1 || matches[0].EntryID != "173" {
+		t.Errorf("Match = %+v", matches)
+	}
+}
+
+func TestLoadXML(t *testing.T) {
+	list, err := Load(writeList(t, "sdn.XML", sdnXML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(list.Entries) != 1 {
+		t.Fatalf("loaded %d entries, want 1", len(list.Entries))
+	}
+
+	entry := list.Entries[0]
+	wantNames := []string{"Mohammed ABU ABBAS", "Muhammad ABBAS", "ABU KHALED"}
+	if entry.ID != "173" || !reflect.DeepEqual(entry.Names, wantNames) || !reflect.DeepEqual(entry.Programs, []string{"SDGT", "NPWMD"}) {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	matches := list.Match("Muhammad", "Abbas", 0.9)
+	if len(matches) != 1 || matches[0].ListedName != "Muhammad ABBAS" || matches[0].Score != 1 {
+		t.Errorf("alias Match = %+v", matches)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(writeList(t, "entities.csv", "36,\"AEROCARIBBEAN AIRLINES\",-0-,\"CUBA\"\n")); err == nil {
+		t.Error("Load accepted a list without individuals")
+	}
+	if _, err := Load(writeList(t, "broken.xml", "<sdnList><sdnEntry>")); err == nil {
+		t.Error("Load accepted broken XML")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Load accepted a missing file")
+	}
+}
+
+func TestEntryVersion(t *testing.T) {
+	entry := &Entry{ID: "173", Names: []string{"Mohammed ABU ABBAS"}, Programs: []string{"SDGT"}}
+	version := entryVersion(entry)
+	if version != entryVersion(&Entry{ID: "173", Names: []string{"Mohammed ABU ABBAS"}, Programs: []string{"SDGT"}}) {
+		t.Error("same listing has another version")
+	}
+
+	changed := []*Entry{
+		{ID: "174", Names: entry.Names, Programs: entry.Programs},
+		{ID: "173", Names: []string{"Mohammed ABU ABBAS", "ABU KHALED"}, Programs: entry.Programs},
+		{ID: "173", Names: entry.Names, Programs: []string{"SDGT", "NPWMD"}},
+		{ID: "173", Names: []string{"Mohammed ABU ABBASSDGT"}},
+	}
+	for _, other := range changed {
+		if entryVersion(other) == version {
+			t.Errorf("listing %+v has the version of %+v", other, entry)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+var (
+	ErrInvalidAccountStatus = errors.New("status must be ACTIVE, FROZEN or SUSPENDED")
+	ErrAccountClosed        = errors.New("account is closed")
+	ErrPayoutRequired       = errors.New("the remaining balance must be paid out first; payout_reference is required")
+	ErrOpenCases            = errors.New("user has open compliance cases; the account cannot be made active until they are closed")
+)
+
+// AccountRestrictedError is returned when the status of an account does not
+// allow the requested operation.
+type AccountRestrictedError struct {
+	Status string
+}
+
+func (e *AccountRestrictedError) Error() string {
+	return fmt.Sprintf("account is %s", strings.ToLower(e.Status))
+}
+
+// Code is the error code sent to clients, e.g. ACCOUNT_FROZEN.
+func (e *AccountRestrictedError) Code() string {
+	return "ACCOUNT_" + e.Status
+}
+
+type IAccountService interface {
+	// SetStatus moves an account between ACTIVE, FROZEN and SUSPENDED.
+	// Suspending revokes every session of the user. An account is not made
+	// ACTIVE while the user has open compliance cases.
+	SetStatus(req *entity.AccountStatusRequest) error
+
+	// Close closes an account for good. A remaining balance is debited as a
+	// payout, which needs the reference of the bank transfer that paid it
+	// out.
+	Close(req *entity.CloseAccountRequest) (*entity.CloseAccountResponse, error)
+}
+
+type accountService struct {
+	db                    *sql.DB
+	userRepository        repository.IUserRepository
+	walletRepository      repository.IWalletRepository
+	transactionRepository repository.ITransactionRepository
+	complianceRepository  repository.IComplianceRepository
+	ledgerService         ILedgerService
+	authService           IAuthService
+	auditLogger           audit.Logger
+}
+
+func NewAccountService(dbConn *sql.DB, userRepo repository.IUserRepository, walletRepo repository.IWalletRepository, transactionRepo repository.ITransactionRepository, complianceRepo repository.IComplianceRepository, ledgerService ILedgerService, authService IAuthService, auditLogger audit.Logger) IAccountService {
+	return &accountService{
+		db:                    dbConn,
+		userRepository:        userRepo,
+		walletRepository:      walletRepo,
+		transactionRepository: transactionRepo,
+		complianceRepository:  complianceRepo,
+		ledgerService:         ledgerService,
+		authService:           authService,
+		auditLogger:           auditLogger,
+	}
+}
+
+func (s *accountService) SetStatus(req *entity.AccountStatusRequest) error {
+	switch req.Status {
+	case entity.AccountStatusActive, entity.AccountStatusFrozen, entity.AccountStatusSuspended:
+	default:
+		return ErrInvalidAccountStatus
+	}
+
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	var previousStatus string
+	err = repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		wallet, err := s.lockWallet(tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		previousStatus = wallet.Status
+
+		if req.Status == entity.AccountStatusActive && previousStatus != entity.AccountStatusActive {
+			open, err := s.complianceRepository.CountOpen(tx, user.UserID, "")
+			if err != nil {
+				return err
+			}
+			if open > 0 {
+				return ErrOpenCases
+			}
+		}
+
+		now := time.Now()
+		if err := s.userRepository.UpdateStatus(tx, user.UserID, req.Status, req.Reason, now); err != nil {
+			return err
+		}
+		return s.walletRepository.UpdateStatus(tx, user.UserID, req.Status, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !entity.CanSignIn(req.Status) {
+		if err := s.authService.RevokeAllSessions(user.UserID); err != nil {
+			return err
+		}
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventAccountStatus,
+		UserID:      user.UserID,
+		ActorID:     req.ActorID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		Details: map[string]interface{}{
+			"from":   previousStatus,
+			"to":     req.Status,
+			"reason": req.Reason,
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return nil
+}
+
+func (s *accountService) Close(req *entity.CloseAccountRequest) (*entity.CloseAccountResponse, error) {
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	resp := &entity.CloseAccountResponse{}
+	err = repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		wallet, err := s.lockWallet(tx, user.UserID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		resp.PayoutAmount = wallet.Balance
+		if wallet.Balance.IsPositive() {
+			if req.PayoutReference == "" {
+				return ErrPayoutRequired
+			}
+			resp.PayoutID, err = s.payout(tx, wallet, req.PayoutReference, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.userRepository.UpdateStatus(tx, user.UserID, entity.AccountStatusClosed, req.Reason, now); err != nil {
+			return err
+		}
+		return s.walletRepository.UpdateStatus(tx, user.UserID, entity.AccountStatusClosed, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.RevokeAllSessions(user.UserID); err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventAccountClosed,
+		UserID:      user.UserID,
+		ActorID:     req.ActorID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		Details: map[string]interface{}{
+			"reason":           req.Reason,
+			"payout_id":        resp.PayoutID,
+			"payout_amount":    resp.PayoutAmount.String(),
+			"payout_reference": req.PayoutReference,
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return resp, nil
+}
+
+// lockWallet locks the wallet of a user that is not closed yet.
+func (s *accountService) lockWallet(tx *sql.Tx, userID string) (entity.Wallet, error) {
+	wallets, err := s.walletRepository.LockWallets(tx, userID)
+	if err != nil {
+		return entity.Wallet{}, err
+	}
+
+	wallet, ok := wallets[userID]
+	if !ok {
+		return entity.Wallet{}, repository.ErrWalletNotFound
+	}
+	if wallet.Status == entity.AccountStatusClosed {
+		return entity.Wallet{}, ErrAccountClosed
+	}
+	return wallet, nil
+}
+
+// payout debits the whole balance of a locked wallet and records it as a
+// transaction and a PAYOUT journal.
+func (s *accountService) payout(tx *sql.Tx, wallet entity.Wallet, reference string, now time.Time) (string, error) {
+	payoutID := uuid.New().String()
+	description := "Payout on account closure, reference " + reference
+
+	if err := s.walletRepository.Debit(tx, wallet.UserID, wallet.Balance, now); err != nil {
+		return "", err
+	}
+
+	err := s.transactionRepository.InsertTransaction(tx, entity.Transaction{
+		TransactionID: payoutID,
+		UserID:        wallet.UserID,
+		Type:          entity.TransactionTypeDebit,
+		Amount:        wallet.Balance,
+		BalanceBefore: wallet.Balance,
+		BalanceAfter:  entity.NewMoney(0, wallet.Balance.Currency),
+		Description:   description,
+		Status:        entity.TransactionStatusSuccess,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.ledgerService.PostPayout(tx, payoutID, wallet.UserID, wallet.Balance, description, now); err != nil {
+		return "", err
+	}
+
+	return payoutID, nil
+}
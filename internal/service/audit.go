@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+type IAuditService interface {
+	FindEvents(filter entity.AuditEventFilter) ([]*entity.AuditEvent, error)
+
+	// Verify recomputes the hash chain and reports the first event that was
+	// changed, removed or inserted out of order.
+	Verify() (*entity.AuditVerification, error)
+}
+
+type auditService struct {
+	auditRepository repository.IAuditRepository
+}
+
+func NewAuditService(auditRepo repository.IAuditRepository) IAuditService {
+	return &auditService{
+		auditRepository: auditRepo,
+	}
+}
+
+func (s *auditService) FindEvents(filter entity.AuditEventFilter) ([]*entity.AuditEvent, error) {
+	return s.auditRepository.Find(filter)
+}
+
+func (s *auditService) Verify() (*entity.AuditVerification, error) {
+	headSequence, headHash, err := s.auditRepository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.AuditVerification{Valid: true}
+	broken := func(sequence int64, reason string) {
+		if result.Valid {
+			result.Valid = false
+			result.BrokenSequence = sequence
+			result.Reason = reason
+		}
+	}
+
+	var previousHash string
+	err = s.auditRepository.Walk(headSequence, func(event *entity.AuditEvent) error {
+		expectedSequence := result.Events + 1
+		result.Events++
+
+		switch {
+		case event.Sequence != expectedSequence:
+			broken(expectedSequence, fmt.Sprintf("event %d is missing", expectedSequence))
+		case event.PrevHash != previousHash:
+			broken(event.Sequence, "prev_hash does not match the hash of the previous event")
+		}
+
+		hash, err := event.ComputeHash()
+		if err != nil {
+			return err
+		}
+		if hash != event.Hash {
+			broken(event.Sequence, "event was modified")
+		}
+
+		previousHash = event.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The chain head catches events removed from the end.
+	if result.Events != headSequence {
+		broken(result.Events+1, fmt.Sprintf("expected %d events, found %d", headSequence, result.Events))
+	} else if previousHash != headHash {
+		broken(headSequence, "last event does not match the chain head")
+	}
+
+	return result, nil
+}
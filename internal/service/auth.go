@@ -7,34 +7,118 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
 	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/jwtkeys"
 	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/sms"
 	"github.com/leonardoong/e-wallet/internal/utils"
 )
 
+const accessTokenTTL = 60 * time.Minute
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrPhoneNotVerified    = errors.New("phone number is not verified")
+)
+
 type IAuthService interface {
 	Register(req *entity.RegisterUserRequest) (user *entity.User, err error)
+	VerifyRegistration(req *entity.VerifyRegistrationRequest) error
+	ResendVerification(req *entity.ResendVerificationRequest) error
 	Login(req *entity.LoginRequest) (resp *entity.LoginResponse, err error)
+	RefreshToken(req *entity.RefreshTokenRequest) (resp *entity.LoginResponse, err error)
 	UpdateProfile(req *entity.UpdateProfileRequest) (resp *entity.UpdateProfileResponse, err error)
+
+	// JWKS returns the public keys that verify issued tokens.
+	JWKS() entity.JSONWebKeySet
+
+	// ValidateToken accepts access tokens only, and rejects tokens that were
+	// logged out or issued before the user's sessions were revoked.
 	ValidateToken(tokenString string) (jwt.MapClaims, error)
+
+	// CheckAccountStatus returns an AccountRestrictedError when the user may
+	// no longer use the API because the account is suspended or closed.
+	CheckAccountStatus(userID string) error
+
+	// Logout revokes the access token with the given claims and its session.
+	Logout(claims jwt.MapClaims) error
+
+	// ListSessions returns the active sessions of a user, flagging the one
+	// with currentSessionID.
+	ListSessions(userID, currentSessionID string) ([]*entity.Session, error)
+	RevokeSession(userID, sessionID string) error
+
+	// RevokeAllSessions invalidates every access and refresh token of a user.
+	RevokeAllSessions(userID string) error
+
+	// UnlockLogin lifts a login lock and clears the failed attempts of a user.
+	UnlockLogin(userID string) error
+
+	ChangePin(req *entity.ChangePinRequest) error
+
+	// VerifyPin re-checks the PIN of a logged in user, counting failures
+	// towards the login lockout.
+	VerifyPin(userID, pin, ip string) error
+	// DisableTOTP turns two-factor authentication off. It needs the PIN as
+	// well as a code, so a stolen access token alone cannot remove it.
+	DisableTOTP(req *entity.DisableTOTPRequest) error
+	RequestPinReset(req *entity.PinResetRequest) error
+	ConfirmPinReset(req *entity.PinResetConfirmRequest) error
 }
 
 type authService struct {
-	config         *config.Config
-	userRepository repository.IUserRepository
+	config                 *config.Config
+	userRepository         repository.IUserRepository
+	tokenRepository        repository.ITokenRepository
+	loginAttemptRepository repository.ILoginAttemptRepository
+	sessionRepository      repository.ISessionRepository
+	otpService             IOTPService
+	mfaService             IMFAService
+	screeningService       IScreeningService
+	smsSender              sms.Sender
+	auditLogger            audit.Logger
+	keySet                 *jwtkeys.KeySet
 }
 
-func NewAuthService(config *config.Config, userRepo repository.IUserRepository) IAuthService {
+func NewAuthService(config *config.Config, keySet *jwtkeys.KeySet, userRepo repository.IUserRepository, tokenRepo repository.ITokenRepository, loginAttemptRepo repository.ILoginAttemptRepository, sessionRepo repository.ISessionRepository, otpService IOTPService, mfaService IMFAService, screeningService IScreeningService, smsSender sms.Sender, auditLogger audit.Logger) IAuthService {
 	return &authService{
-		config:         config,
-		userRepository: userRepo,
+		config:                 config,
+		keySet:                 keySet,
+		userRepository:         userRepo,
+		tokenRepository:        tokenRepo,
+		loginAttemptRepository: loginAttemptRepo,
+		sessionRepository:      sessionRepo,
+		otpService:             otpService,
+		mfaService:             mfaService,
+		screeningService:       screeningService,
+		smsSender:              smsSender,
+		auditLogger:            auditLogger,
 	}
 }
 
+// Register creates an UNVERIFIED user and texts a verification code to the
+// phone number. A pending registration for the same number is replaced, so an
+// unconfirmed sign-up cannot block the real owner of the number. A name on
+// the watchlist opens a compliance case and freezes the new account.
 func (s *authService) Register(req *entity.RegisterUserRequest) (user *entity.User, err error) {
-	existingUser, _ := s.userRepository.FindByPhoneNumber(req.PhoneNumber)
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
 	if existingUser != nil {
-		return nil, errors.New("Phone number already registered")
+		if existingUser.VerificationStatus == entity.UserVerificationStatusVerified {
+			return nil, errors.New("Phone number already registered")
+		}
+		if err := s.userRepository.DeleteUnverified(existingUser.UserID); err != nil {
+			return nil, err
+		}
 	}
 
 	hashedPin, err := utils.HashPin(req.Pin)
@@ -45,70 +129,424 @@ func (s *authService) Register(req *entity.RegisterUserRequest) (user *entity.Us
 	now := time.Now()
 
 	user = &entity.User{
-		UserID:      uuid.New().String(),
-		FirstName:   req.FirstName,
-		LastName:    req.LastName,
-		PhoneNumber: req.PhoneNumber,
-		Pin:         hashedPin,
-		Address:     req.Address,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		UserID:             uuid.New().String(),
+		FirstName:          req.FirstName,
+		LastName:           req.LastName,
+		PhoneNumber:        phoneNumber,
+		Pin:                hashedPin,
+		Address:            req.Address,
+		VerificationStatus: entity.UserVerificationStatusUnverified,
+		Role:               entity.RoleCustomer,
+		Status:             entity.AccountStatusActive,
+		Tier:               entity.TierBasic,
+		CreatedAt:          now,
+		UpdatedAt:          now,
 	}
 
 	if err := s.userRepository.Register(user); err != nil {
 		return nil, err
 	}
 
+	matches := s.screeningService.Screen(user.UserID, user.FirstName, user.LastName)
+	if err := s.screeningService.Flag(user, entity.ScreeningTriggerRegistration, "", matches); err != nil {
+		return nil, err
+	}
+
+	// A rate limited send still leaves the previous code valid.
+	err = s.otpService.Send(entity.OTPPurposeRegistration, phoneNumber)
+	if err != nil && err != ErrOTPRateLimited {
+		return nil, err
+	}
+
 	return user, nil
 }
 
-func (s *authService) Login(req *entity.LoginRequest) (resp *entity.LoginResponse, err error) {
-	user, err := s.userRepository.FindByPhoneNumber(req.PhoneNumber)
+func (s *authService) VerifyRegistration(req *entity.VerifyRegistrationRequest) error {
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
 	if err != nil {
-		return nil, errors.New("Phone Number and PIN doesn't match.")
+		return ErrInvalidOTP
 	}
 
+	if err := s.otpService.Verify(entity.OTPPurposeRegistration, phoneNumber, req.Code); err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil {
+		return err
+	}
 	if user == nil {
-		return nil, errors.New("Phone Number and PIN doesn't match.")
+		return ErrInvalidOTP
+	}
+
+	return s.userRepository.UpdateVerificationStatus(user.UserID, entity.UserVerificationStatusVerified, time.Now())
+}
+
+// ResendVerification texts a new code for a pending registration. Unknown and
+// already verified numbers are accepted silently.
+func (s *authService) ResendVerification(req *entity.ResendVerificationRequest) error {
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.VerificationStatus != entity.UserVerificationStatusUnverified {
+		return nil
+	}
+
+	return s.otpService.Send(entity.OTPPurposeRegistration, phoneNumber)
+}
+
+func (s *authService) Login(req *entity.LoginRequest) (resp *entity.LoginResponse, err error) {
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var user *entity.User
+	defer func() { s.auditLogin(req, phoneNumber, user, err) }()
+
+	attempt, err := s.startLoginAttempt(phoneNumber, req.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil || user == nil {
+		return nil, s.failLoginAttempt(attempt, "")
 	}
 
 	if !utils.CheckPinHash(req.Pin, user.Pin) {
-		return nil, errors.New("Phone Number and PIN doesn't match.")
+		return nil, s.failLoginAttempt(attempt, user.UserID)
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"phone_number": req.PhoneNumber,
-		"user_id":      user.UserID,
-		"exp":          time.Now().Add(time.Minute * 60).Unix(),
+	// A wrong second factor fails the attempt like a wrong PIN. Otherwise
+	// the PIN was right and the attempt is settled before anything else can
+	// turn the login down.
+	if user.TOTPEnabled && req.OTPCode != "" {
+		err := s.mfaService.VerifyCode(user, req.OTPCode)
+		if err == ErrInvalidMFACode {
+			if err := s.failLoginAttempt(attempt, user.UserID); err != ErrInvalidCredentials {
+				return nil, err
+			}
+			return nil, ErrInvalidMFACode
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.passLoginAttempt(attempt); err != nil {
+		return nil, err
+	}
+
+	// Checked only after the PIN so the answer does not reveal whether a
+	// number has a pending registration.
+	if user.VerificationStatus != entity.UserVerificationStatusVerified {
+		return nil, ErrPhoneNotVerified
+	}
+	if !entity.CanSignIn(user.Status) {
+		return nil, &AccountRestrictedError{Status: user.Status}
+	}
+
+	if user.TOTPEnabled && req.OTPCode == "" {
+		return nil, ErrMFARequired
+	}
+
+	newDevice, err := s.checkDevice(user, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessionID := uuid.New().String()
+	jti := uuid.New().String()
+
+	resp, err = s.issueTokens(user, sessionID, jti, user.TOTPEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepository.CreateRefreshFamily(sessionID, jti, s.refreshTokenTTL()); err != nil {
+		return nil, err
+	}
+
+	err = s.sessionRepository.Create(entity.Session{
+		SessionID:  sessionID,
+		UserID:     user.UserID,
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		Platform:   req.Platform,
+		IPAddress:  req.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTokenTTL()),
 	})
-	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWTSecret))
 	if err != nil {
+		return nil, err
+	}
+
+	if newDevice {
+		s.notifyNewDevice(user, req)
+	}
+
+	return resp, nil
+}
+
+// auditLogin records the outcome of a login attempt. Asking for a two-factor
+// or new device code is part of a login, not a failure, so it is not recorded.
+func (s *authService) auditLogin(req *entity.LoginRequest, phoneNumber string, user *entity.User, err error) {
+	if errors.Is(err, ErrMFARequired) || errors.Is(err, ErrDeviceVerificationRequired) {
 		return
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"phone_number": req.PhoneNumber,
+	event := entity.AuditEvent{
+		Type:        entity.AuditEventLoginSucceeded,
+		PhoneNumber: phoneNumber,
+		IPAddress:   req.IPAddress,
+		UserAgent:   req.UserAgent,
+		Details: map[string]interface{}{
+			"device_id": req.DeviceID,
+			"platform":  req.Platform,
+		},
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		event.UserID = user.UserID
+	}
+
+	var blocked *LoginBlockedError
+	var restricted *AccountRestrictedError
+	switch {
+	case err == nil:
+		event.ActorID = event.UserID
+	case errors.As(err, &blocked):
+		event.Type = entity.AuditEventLoginFailed
+		event.Details["reason"] = blocked.Code
+	case errors.As(err, &restricted):
+		event.Type = entity.AuditEventLoginFailed
+		event.Details["reason"] = restricted.Code()
+	default:
+		event.Type = entity.AuditEventLoginFailed
+		event.Details["reason"] = err.Error()
+	}
+
+	s.auditLogger.Log(event)
+}
+
+// RefreshToken exchanges a refresh token for a new access/refresh pair. Each
+// refresh token can be used once; presenting an already rotated token revokes
+// every token of its family, so a stolen token stops working for both the
+// thief and the owner.
+func (s *authService) RefreshToken(req *entity.RefreshTokenRequest) (resp *entity.LoginResponse, err error) {
+	claims, err := s.parseToken(req.RefreshToken, entity.TokenTypeRefresh)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := s.checkRevocation(claims); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+	sessionID, _ := claims["sid"].(string)
+	oldJTI, _ := claims["jti"].(string)
+	if userID == "" || sessionID == "" || oldJTI == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil || user == nil || !entity.CanSignIn(user.Status) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// The second factor is not asked again on refresh; the new tokens keep the
+	// mfa claim of the login that started the family.
+	mfa, _ := claims["mfa"].(bool)
+
+	newJTI := uuid.New().String()
+	resp, err = s.issueTokens(user, sessionID, newJTI, mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tokenRepository.RotateRefreshFamily(sessionID, oldJTI, newJTI, s.refreshTokenTTL())
+	if err == repository.ErrRefreshTokenReused {
+		// The family is already gone; end the session as well so the stolen
+		// access tokens stop working too.
+		if err := s.revokeSession(sessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err == repository.ErrRefreshTokenRevoked {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.sessionRepository.Touch(sessionID, req.IPAddress, now, now.Add(s.refreshTokenTTL())); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (s *authService) JWKS() entity.JSONWebKeySet {
+	return s.keySet.JWKS()
+}
+
+func (s *authService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := s.parseToken(tokenString, entity.TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRevocation(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (s *authService) CheckAccountStatus(userID string) error {
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !entity.CanSignIn(user.Status) {
+		return &AccountRestrictedError{Status: user.Status}
+	}
+	return nil
+}
+
+func (s *authService) Logout(claims jwt.MapClaims) error {
+	if jti, _ := claims["jti"].(string); jti != "" {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return errors.New("invalid token claims")
+		}
+		if err := s.tokenRepository.RevokeToken(jti, time.Until(exp.Time)); err != nil {
+			return err
+		}
+	}
+
+	if sessionID, _ := claims["sid"].(string); sessionID != "" {
+		if err := s.revokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *authService) RevokeAllSessions(userID string) error {
+	if _, err := s.tokenRepository.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+
+	return s.sessionRepository.RevokeAllByUserID(userID, time.Now())
+}
+
+// checkRevocation rejects denylisted tokens and tokens issued with an older
+// token version than the user's current one.
+func (s *authService) checkRevocation(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	version, ok := claims["ver"].(float64)
+	if jti == "" || userID == "" || !ok {
+		return errors.New("invalid token claims")
+	}
+
+	revoked, err := s.tokenRepository.IsTokenRevoked(jti)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	if sessionID, _ := claims["sid"].(string); sessionID != "" {
+		revoked, err := s.tokenRepository.IsSessionRevoked(sessionID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	currentVersion, err := s.tokenRepository.GetTokenVersion(userID)
+	if err != nil {
+		return err
+	}
+	if int64(version) != currentVersion {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// issueTokens signs an access/refresh pair for a session. The mfa claim
+// records whether the login that started the session passed a second factor.
+// The role claim is read fresh from the user on every refresh.
+func (s *authService) issueTokens(user *entity.User, sessionID, jti string, mfa bool) (*entity.LoginResponse, error) {
+	now := time.Now()
+
+	version, err := s.tokenRepository.GetTokenVersion(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessTokenString, err := s.keySet.Sign(jwt.MapClaims{
+		"phone_number": user.PhoneNumber,
 		"user_id":      user.UserID,
-		"exp":          time.Now().Add(time.Hour * time.Duration(s.config.JWTExpiryHours)).Unix(),
+		"role":         user.Role,
+		"type":         entity.TokenTypeAccess,
+		"jti":          uuid.New().String(),
+		"sid":          sessionID,
+		"ver":          version,
+		"mfa":          mfa,
+		"iat":          now.Unix(),
+		"exp":          now.Add(accessTokenTTL).Unix(),
 	})
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.config.JWTSecret))
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	refreshTokenString, err := s.keySet.Sign(jwt.MapClaims{
+		"user_id": user.UserID,
+		"type":    entity.TokenTypeRefresh,
+		"jti":     jti,
+		"sid":     sessionID,
+		"ver":     version,
+		"mfa":     mfa,
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.refreshTokenTTL()).Unix(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &entity.LoginResponse{
 		AccessToken:  accessTokenString,
 		RefreshToken: refreshTokenString,
-	}, err
+	}, nil
 }
 
-func (s *authService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+func (s *authService) refreshTokenTTL() time.Duration {
+	return time.Duration(s.config.JWTExpiryHours) * time.Hour
+}
+
+// parseToken verifies the signature and expiry of a token and checks that it
+// is of the expected type, so a refresh token cannot be used as an access
+// token or the other way around.
+func (s *authService) parseToken(tokenString, tokenType string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keySet.Keyfunc)
 
 	if err != nil {
 		return nil, err
@@ -123,12 +561,24 @@ func (s *authService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 		return nil, errors.New("invalid token claims")
 	}
 
+	if claims["type"] != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
 	return claims, nil
 }
 
 func (s *authService) UpdateProfile(req *entity.UpdateProfileRequest) (resp *entity.UpdateProfileResponse, err error) {
 	now := time.Now()
 
+	previous, err := s.userRepository.FindByID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ErrUserNotFound
+	}
+
 	user := entity.User{
 		UserID:    req.UserID,
 		Address:   req.Address,
@@ -141,6 +591,25 @@ func (s *authService) UpdateProfile(req *entity.UpdateProfileRequest) (resp *ent
 		return nil, err
 	}
 
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventProfileUpdated,
+		ActorID:     req.UserID,
+		UserID:      req.UserID,
+		PhoneNumber: previous.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		UserAgent:   req.UserAgent,
+		Before:      profileSnapshot(previous),
+		After:       profileSnapshot(&user),
+		CreatedAt:   now,
+	})
+
+	if user.FirstName != previous.FirstName || user.LastName != previous.LastName {
+		matches := s.screeningService.Screen(user.UserID, user.FirstName, user.LastName)
+		if err := s.screeningService.Flag(previous, entity.ScreeningTriggerProfileUpdate, "", matches); err != nil {
+			return nil, err
+		}
+	}
+
 	return &entity.UpdateProfileResponse{
 		UserID:    req.UserID,
 		Address:   req.Address,
@@ -149,3 +618,12 @@ func (s *authService) UpdateProfile(req *entity.UpdateProfileRequest) (resp *ent
 		UpdatedAt: now,
 	}, err
 }
+
+// profileSnapshot is the part of a user that UpdateProfile can change.
+func profileSnapshot(user *entity.User) map[string]interface{} {
+	return map[string]interface{}{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"address":    user.Address,
+	}
+}
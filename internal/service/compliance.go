@@ -0,0 +1,620 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/blob"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/rbac"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const (
+	maxCaseSummaryLength  = 255
+	maxCaseFileNameLength = 255
+	maxClosingNoteLength  = 255
+
+	// caseFreezeReason starts the status reason of an account frozen by a
+	// compliance case, so closing a case lifts only those freezes.
+	caseFreezeReason = "Compliance freeze: "
+)
+
+var (
+	ErrCaseNotFound           = errors.New("compliance case not found")
+	ErrCaseAttachmentNotFound = errors.New("attachment not found")
+	ErrCaseClosed             = errors.New("compliance case is already closed")
+	ErrCaseSelfReview         = errors.New("staff cannot work a case about themselves")
+	ErrCaseNoteRequired       = errors.New("note is required")
+	ErrClosingNoteTooLong     = errors.New("closing note is at most 255 characters")
+	ErrNotScreeningCase       = errors.New("only screening cases can be closed as false positives")
+	ErrCaseSummaryRequired    = errors.New("summary is required and at most 255 characters")
+	ErrInvalidCaseStatus      = errors.New("status must be INVESTIGATING or ESCALATED; cases are closed through the close route")
+	ErrCaseTransition         = errors.New("case cannot move to this status")
+	ErrInvalidAssignee        = errors.New("assignee must be staff allowed to manage cases")
+	ErrInvalidCaseAttachment  = errors.New("attachments must be PDF, JPEG, PNG or plain text")
+	ErrCaseAttachmentTooLarge = errors.New("attachment is too large")
+	ErrOtherCasesOpen         = errors.New("user has other open compliance cases; the account stays frozen")
+)
+
+// caseTransitions lists the statuses a case may move to before it is
+// closed, which it can be from any of them.
+var caseTransitions = map[string][]string{
+	entity.CaseStatusOpen:          {entity.CaseStatusInvestigating, entity.CaseStatusEscalated},
+	entity.CaseStatusInvestigating: {entity.CaseStatusEscalated},
+	entity.CaseStatusEscalated:     {entity.CaseStatusInvestigating},
+}
+
+// caseAttachmentExtensions are the accepted attachment types, detected from
+// the content rather than trusted from the upload.
+var caseAttachmentExtensions = map[string]string{
+	"application/pdf":           ".pdf",
+	"image/jpeg":                ".jpg",
+	"image/png":                 ".png",
+	"text/plain; charset=utf-8": ".txt",
+}
+
+type IComplianceService interface {
+	// CreateCase opens a case by hand, optionally freezing the account.
+	CreateCase(req *entity.CreateCaseRequest) (*entity.ComplianceCase, error)
+	FindCases(filter entity.ComplianceCaseFilter) ([]*entity.ComplianceCase, error)
+	// FindCase returns a case with its transactions, notes and attachments.
+	FindCase(caseID string) (*entity.ComplianceCase, error)
+
+	Assign(req *entity.AssignCaseRequest) error
+	SetStatus(req *entity.CaseStatusRequest) error
+	LinkTransaction(req *entity.LinkCaseTransactionRequest) error
+	AddNote(req *entity.AddCaseNoteRequest) (*entity.CaseNote, error)
+	AddAttachment(req *entity.AddCaseAttachmentRequest) (*entity.CaseAttachment, error)
+	OpenAttachment(caseID, attachmentID string) (io.ReadCloser, *entity.CaseAttachment, error)
+
+	// CloseCase closes a case. With Unfreeze an account frozen by a case is
+	// made ACTIVE again, with FalsePositive the matches of a screening case are cleared
+	// and with ReleaseTransactions the linked transactions still held for
+	// review are queued.
+	CloseCase(req *entity.CloseCaseRequest) (*entity.CloseCaseResponse, error)
+}
+
+type complianceService struct {
+	config               *config.Config
+	db                   *sql.DB
+	complianceRepository repository.IComplianceRepository
+	userRepository       repository.IUserRepository
+	walletRepository     repository.IWalletRepository
+	transactionService   ITransactionService
+	blobStore            blob.Store
+	auditLogger          audit.Logger
+}
+
+func NewComplianceService(cfg *config.Config, db *sql.DB, complianceRepo repository.IComplianceRepository, userRepo repository.IUserRepository, walletRepo repository.IWalletRepository, transactionService ITransactionService, blobStore blob.Store, auditLogger audit.Logger) IComplianceService {
+	return &complianceService{
+		config:               cfg,
+		db:                   db,
+		complianceRepository: complianceRepo,
+		userRepository:       userRepo,
+		walletRepository:     walletRepo,
+		transactionService:   transactionService,
+		blobStore:            blobStore,
+		auditLogger:          auditLogger,
+	}
+}
+
+func (s *complianceService) CreateCase(req *entity.CreateCaseRequest) (*entity.ComplianceCase, error) {
+	summary := strings.TrimSpace(req.Summary)
+	if summary == "" || len(summary) > maxCaseSummaryLength {
+		return nil, ErrCaseSummaryRequired
+	}
+	if req.UserID == req.ActorID {
+		return nil, ErrCaseSelfReview
+	}
+
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, transactionID := range req.TransactionIDs {
+		if _, err := s.transactionService.FindTransactionByID(transactionID); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, transactionID)
+		}
+	}
+
+	now := time.Now()
+	complianceCase := &entity.ComplianceCase{
+		CaseID:         uuid.New().String(),
+		UserID:         user.UserID,
+		Source:         entity.CaseSourceManual,
+		Summary:        summary,
+		Status:         entity.CaseStatusOpen,
+		Matches:        []entity.ScreeningMatch{},
+		TransactionIDs: req.TransactionIDs,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	var frozen bool
+	err = repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		frozen = false
+		if err := s.complianceRepository.Create(tx, *complianceCase); err != nil {
+			return err
+		}
+		for _, transactionID := range req.TransactionIDs {
+			if err := s.complianceRepository.LinkTransaction(tx, complianceCase.CaseID, transactionID, now); err != nil {
+				return err
+			}
+		}
+
+		if !req.Freeze {
+			return nil
+		}
+		var err error
+		frozen, err = switchAccountStatus(tx, s.userRepository, s.walletRepository, user.UserID,
+			entity.AccountStatusActive, entity.AccountStatusFrozen, caseFreezeReason+"case "+complianceCase.CaseID, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventCaseOpened,
+		ActorID:     req.ActorID,
+		UserID:      user.UserID,
+		TargetID:    complianceCase.CaseID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		Details: map[string]interface{}{
+			"source":          complianceCase.Source,
+			"summary":         summary,
+			"transaction_ids": req.TransactionIDs,
+			"freeze":          req.Freeze,
+		},
+		CreatedAt: now,
+	})
+	if frozen {
+		auditCaseAccountStatus(s.auditLogger, req.ActorID, user.UserID, complianceCase.CaseID, entity.AccountStatusActive, entity.AccountStatusFrozen, now)
+	}
+
+	return complianceCase, nil
+}
+
+func (s *complianceService) FindCases(filter entity.ComplianceCaseFilter) ([]*entity.ComplianceCase, error) {
+	return s.complianceRepository.Find(filter)
+}
+
+func (s *complianceService) FindCase(caseID string) (*entity.ComplianceCase, error) {
+	complianceCase, err := s.complianceRepository.FindByID(caseID)
+	if err == sql.ErrNoRows {
+		return nil, ErrCaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if complianceCase.Notes, err = s.complianceRepository.FindNotes(caseID); err != nil {
+		return nil, err
+	}
+	if complianceCase.Attachments, err = s.complianceRepository.FindAttachments(caseID); err != nil {
+		return nil, err
+	}
+	return complianceCase, nil
+}
+
+func (s *complianceService) Assign(req *entity.AssignCaseRequest) error {
+	if req.AssigneeID != "" {
+		assignee, err := s.userRepository.FindByID(req.AssigneeID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if assignee == nil || !rbac.Allowed(assignee.Role, rbac.PermissionCasesManage) {
+			return ErrInvalidAssignee
+		}
+	}
+
+	var previous string
+	now := time.Now()
+	complianceCase, err := s.updateCase(req.CaseID, req.ActorID, now, func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error {
+		if req.AssigneeID == complianceCase.UserID {
+			return ErrCaseSelfReview
+		}
+		previous = complianceCase.AssigneeID
+		return s.complianceRepository.UpdateAssignee(tx, complianceCase.CaseID, req.AssigneeID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditCase(entity.AuditEventCaseAssigned, req.ActorID, req.IPAddress, complianceCase, now, map[string]interface{}{
+		"from": previous,
+		"to":   req.AssigneeID,
+	})
+	return nil
+}
+
+func (s *complianceService) SetStatus(req *entity.CaseStatusRequest) error {
+	if req.Status != entity.CaseStatusInvestigating && req.Status != entity.CaseStatusEscalated {
+		return ErrInvalidCaseStatus
+	}
+	note := strings.TrimSpace(req.Note)
+
+	var previous string
+	now := time.Now()
+	complianceCase, err := s.updateCase(req.CaseID, req.ActorID, now, func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error {
+		previous = complianceCase.Status
+		allowed := false
+		for _, status := range caseTransitions[complianceCase.Status] {
+			allowed = allowed || status == req.Status
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s to %s", ErrCaseTransition, complianceCase.Status, req.Status)
+		}
+
+		if err := s.complianceRepository.UpdateStatus(tx, complianceCase.CaseID, req.Status, now); err != nil {
+			return err
+		}
+		if note == "" {
+			return nil
+		}
+		return s.complianceRepository.AddNote(tx, entity.CaseNote{
+			NoteID:    uuid.New().String(),
+			CaseID:    complianceCase.CaseID,
+			AuthorID:  req.ActorID,
+			Body:      note,
+			CreatedAt: now,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditCase(entity.AuditEventCaseStatusChanged, req.ActorID, req.IPAddress, complianceCase, now, map[string]interface{}{
+		"from": previous,
+		"to":   req.Status,
+		"note": note,
+	})
+	return nil
+}
+
+func (s *complianceService) LinkTransaction(req *entity.LinkCaseTransactionRequest) error {
+	if _, err := s.transactionService.FindTransactionByID(req.TransactionID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	complianceCase, err := s.updateCase(req.CaseID, req.ActorID, now, func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error {
+		return s.complianceRepository.LinkTransaction(tx, complianceCase.CaseID, req.TransactionID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditCase(entity.AuditEventCaseTransactionLinked, req.ActorID, req.IPAddress, complianceCase, now, map[string]interface{}{
+		"transaction_id": req.TransactionID,
+	})
+	return nil
+}
+
+func (s *complianceService) AddNote(req *entity.AddCaseNoteRequest) (*entity.CaseNote, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, ErrCaseNoteRequired
+	}
+
+	now := time.Now()
+	note := &entity.CaseNote{
+		NoteID:    uuid.New().String(),
+		CaseID:    req.CaseID,
+		AuthorID:  req.ActorID,
+		Body:      body,
+		CreatedAt: now,
+	}
+	complianceCase, err := s.updateCase(req.CaseID, req.ActorID, now, func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error {
+		return s.complianceRepository.AddNote(tx, *note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditCase(entity.AuditEventCaseNoteAdded, req.ActorID, req.IPAddress, complianceCase, now, map[string]interface{}{
+		"note_id": note.NoteID,
+	})
+	return note, nil
+}
+
+func (s *complianceService) AddAttachment(req *entity.AddCaseAttachmentRequest) (*entity.CaseAttachment, error) {
+	if len(req.Content) == 0 {
+		return nil, ErrInvalidCaseAttachment
+	}
+	if len(req.Content) > s.config.CaseMaxUploadBytes {
+		return nil, fmt.Errorf("%w, the limit is %d bytes", ErrCaseAttachmentTooLarge, s.config.CaseMaxUploadBytes)
+	}
+	contentType := http.DetectContentType(req.Content)
+	extension, ok := caseAttachmentExtensions[contentType]
+	if !ok {
+		return nil, ErrInvalidCaseAttachment
+	}
+
+	fileName := path.Base(strings.ReplaceAll(req.FileName, "\\", "/"))
+	if fileName == "." || fileName == "/" {
+		fileName = "attachment" + extension
+	}
+	if len(fileName) > maxCaseFileNameLength {
+		fileName = fileName[len(fileName)-maxCaseFileNameLength:]
+	}
+
+	now := time.Now()
+	attachment := &entity.CaseAttachment{
+		AttachmentID: uuid.New().String(),
+		CaseID:       req.CaseID,
+		UploadedBy:   req.ActorID,
+		FileName:     fileName,
+		ContentType:  contentType,
+		Size:         int64(len(req.Content)),
+		CreatedAt:    now,
+	}
+	attachment.BlobKey = path.Join("cases", req.CaseID, attachment.AttachmentID+extension)
+
+	// The blob is stored first and removed again when the case refuses it.
+	if err := s.blobStore.Put(attachment.BlobKey, bytes.NewReader(req.Content)); err != nil {
+		return nil, err
+	}
+	complianceCase, err := s.updateCase(req.CaseID, req.ActorID, now, func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error {
+		return s.complianceRepository.AddAttachment(tx, *attachment)
+	})
+	if err != nil {
+		s.blobStore.Delete(attachment.BlobKey)
+		return nil, err
+	}
+
+	s.auditCase(entity.AuditEventCaseAttachmentAdded, req.ActorID, req.IPAddress, complianceCase, now, map[string]interface{}{
+		"attachment_id": attachment.AttachmentID,
+		"file_name":     attachment.FileName,
+		"content_type":  attachment.ContentType,
+		"size":          attachment.Size,
+	})
+	return attachment, nil
+}
+
+func (s *complianceService) OpenAttachment(caseID, attachmentID string) (io.ReadCloser, *entity.CaseAttachment, error) {
+	attachment, err := s.complianceRepository.FindAttachment(caseID, attachmentID)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrCaseAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.blobStore.Open(attachment.BlobKey)
+	if err == blob.ErrNotFound {
+		return nil, nil, ErrCaseAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, attachment, nil
+}
+
+func (s *complianceService) CloseCase(req *entity.CloseCaseRequest) (*entity.CloseCaseResponse, error) {
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		return nil, ErrCaseNoteRequired
+	}
+	if len(req.Note) > maxClosingNoteLength {
+		return nil, ErrClosingNoteTooLong
+	}
+
+	var closed *entity.ComplianceCase
+	var unfrozen bool
+	now := time.Now()
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		closed, unfrozen = nil, false
+		complianceCase, err := s.lockOpenCase(tx, req.CaseID, req.ActorID)
+		if err != nil {
+			return err
+		}
+
+		if req.FalsePositive && complianceCase.Source != entity.CaseSourceScreening {
+			return ErrNotScreeningCase
+		}
+
+		if err := s.complianceRepository.Close(tx, complianceCase.CaseID, req.ActorID, req.Note, now); err != nil {
+			return err
+		}
+		closed = complianceCase
+
+		if req.FalsePositive {
+			for _, match := range complianceCase.Matches {
+				err := s.complianceRepository.AddClearance(tx, entity.ScreeningClearance{
+					UserID:       complianceCase.UserID,
+					EntryID:      match.EntryID,
+					EntryVersion: match.EntryVersion,
+					CaseID:       complianceCase.CaseID,
+					ClearedBy:    req.ActorID,
+					CreatedAt:    now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if !req.Unfreeze {
+			return nil
+		}
+		open, err := s.complianceRepository.CountOpen(tx, complianceCase.UserID, complianceCase.CaseID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrOtherCasesOpen
+		}
+		unfrozen, err = s.liftCaseFreeze(tx, complianceCase.UserID, "Compliance case "+complianceCase.CaseID+" closed", now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditCase(entity.AuditEventCaseClosed, req.ActorID, req.IPAddress, closed, now, map[string]interface{}{
+		"note":                 req.Note,
+		"unfreeze":             req.Unfreeze,
+		"false_positive":       req.FalsePositive,
+		"release_transactions": req.ReleaseTransactions,
+	})
+	if unfrozen {
+		auditCaseAccountStatus(s.auditLogger, req.ActorID, closed.UserID, closed.CaseID, entity.AccountStatusFrozen, entity.AccountStatusActive, now)
+	}
+
+	resp := &entity.CloseCaseResponse{Unfrozen: unfrozen, ReleasedTransactionIDs: []string{}}
+	if !req.ReleaseTransactions {
+		return resp, nil
+	}
+
+	// Each release runs on its own, so a failure leaves the case closed and
+	// the transactions released so far queued. The review note points at
+	// the case, which holds the closing note.
+	linked, err := s.complianceRepository.FindByID(closed.CaseID)
+	if err != nil {
+		return nil, err
+	}
+	for _, transactionID := range linked.TransactionIDs {
+		err := s.transactionService.ReleaseTransaction(&entity.ReviewTransactionRequest{
+			Note:          "Compliance case " + closed.CaseID + " closed",
+			TransactionID: transactionID,
+			ActorID:       req.ActorID,
+			IPAddress:     req.IPAddress,
+		})
+		// Transactions another open case still links stay held until that
+		// case is closed.
+		if errors.Is(err, ErrTransactionNotHeld) || errors.Is(err, ErrTransactionInCase) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("case closed, but transaction %s was not released: %w", transactionID, err)
+		}
+		resp.ReleasedTransactionIDs = append(resp.ReleasedTransactionIDs, transactionID)
+	}
+
+	return resp, nil
+}
+
+// liftCaseFreeze makes the account of userID ACTIVE again when a compliance
+// case froze it. A freeze by staff, told apart by its status reason, stays.
+func (s *complianceService) liftCaseFreeze(tx *sql.Tx, userID, reason string, now time.Time) (bool, error) {
+	// Status changes lock the wallet first, so the reason read under the
+	// lock is the one of the current freeze.
+	if _, err := s.walletRepository.LockWallets(tx, userID); err != nil {
+		return false, err
+	}
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(user.StatusReason, caseFreezeReason) {
+		return false, nil
+	}
+	return switchAccountStatus(tx, s.userRepository, s.walletRepository, userID,
+		entity.AccountStatusFrozen, entity.AccountStatusActive, reason, now)
+}
+
+// updateCase runs update with a case locked that actorID may still work, and
+// marks the case updated.
+func (s *complianceService) updateCase(caseID, actorID string, now time.Time, update func(tx *sql.Tx, complianceCase *entity.ComplianceCase) error) (*entity.ComplianceCase, error) {
+	var updated *entity.ComplianceCase
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		updated = nil
+		complianceCase, err := s.lockOpenCase(tx, caseID, actorID)
+		if err != nil {
+			return err
+		}
+		if err := update(tx, complianceCase); err != nil {
+			return err
+		}
+		updated = complianceCase
+		return s.complianceRepository.Touch(tx, caseID, now)
+	})
+	return updated, err
+}
+
+// lockOpenCase locks a case that is not closed and not about actorID.
+func (s *complianceService) lockOpenCase(tx *sql.Tx, caseID, actorID string) (*entity.ComplianceCase, error) {
+	complianceCase, err := s.complianceRepository.Lock(tx, caseID)
+	if err == sql.ErrNoRows {
+		return nil, ErrCaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if complianceCase.Status == entity.CaseStatusClosed {
+		return nil, ErrCaseClosed
+	}
+	if complianceCase.UserID == actorID {
+		return nil, ErrCaseSelfReview
+	}
+	return complianceCase, nil
+}
+
+// auditCase records staff working a case.
+func (s *complianceService) auditCase(eventType, actorID, ip string, complianceCase *entity.ComplianceCase, now time.Time, details map[string]interface{}) {
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:      eventType,
+		ActorID:   actorID,
+		UserID:    complianceCase.UserID,
+		TargetID:  complianceCase.CaseID,
+		IPAddress: ip,
+		Details:   details,
+		CreatedAt: now,
+	})
+}
+
+// switchAccountStatus moves the account of userID from one status to
+// another, locking the wallet first. It reports false and changes nothing
+// when the account is in any other status, so a compliance case never
+// overrides a suspension or closure by staff.
+func switchAccountStatus(tx *sql.Tx, userRepo repository.IUserRepository, walletRepo repository.IWalletRepository, userID, from, to, reason string, now time.Time) (bool, error) {
+	wallets, err := walletRepo.LockWallets(tx, userID)
+	if err != nil {
+		return false, err
+	}
+	wallet, ok := wallets[userID]
+	if !ok {
+		return false, repository.ErrWalletNotFound
+	}
+	if wallet.Status != from {
+		return false, nil
+	}
+
+	if err := userRepo.UpdateStatus(tx, userID, to, reason, now); err != nil {
+		return false, err
+	}
+	return true, walletRepo.UpdateStatus(tx, userID, to, now)
+}
+
+// auditCaseAccountStatus records an account frozen or unfrozen for a
+// compliance case. actorID is empty when screening froze it.
+func auditCaseAccountStatus(auditLogger audit.Logger, actorID, userID, caseID, from, to string, now time.Time) {
+	auditLogger.Log(entity.AuditEvent{
+		Type:     entity.AuditEventAccountStatus,
+		ActorID:  actorID,
+		UserID:   userID,
+		TargetID: caseID,
+		Details: map[string]interface{}{
+			"from":   from,
+			"to":     to,
+			"reason": "compliance case",
+		},
+		CreatedAt: now,
+	})
+}
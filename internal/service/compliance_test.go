@@ -0,0 +1,308 @@
+package service
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+// txDriver is a database that only begins, commits and rolls back, counting
+// the commits. The fake repositories below ignore tx.
+type txDriver struct {
+	commits *int
+}
+
+func (d txDriver) Open(name string) (driver.Conn, error) { return txConn(d), nil }
+
+type txConn txDriver
+
+func (c txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("txDriver runs no queries")
+}
+func (c txConn) Close() error              { return nil }
+func (c txConn) Begin() (driver.Tx, error) { return txTx(c), nil }
+
+type txTx txConn
+
+func (t txTx) Commit() error   { *t.commits++; return nil }
+func (t txTx) Rollback() error { return nil }
+
+var txDrivers int
+
+// newTxDB returns a database for WithTransaction and the number of
+// transactions committed on it.
+func newTxDB(t *testing.T) (*sql.DB, *int) {
+	t.Helper()
+	commits := new(int)
+	txDrivers++
+	name := fmt.Sprintf("tx%d", txDrivers)
+	sql.Register(name, txDriver{commits: commits})
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, commits
+}
+
+// caseRepository holds one case; other compliance calls panic.
+type caseRepository struct {
+	repository.IComplianceRepository
+	complianceCase entity.ComplianceCase
+	otherOpen      int
+	closed         bool
+	clearances     []entity.ScreeningClearance
+}
+
+func (r *caseRepository) Lock(tx *sql.Tx, caseID string) (*entity.ComplianceCase, error) {
+	if caseID != r.complianceCase.CaseID {
+		return nil, sql.ErrNoRows
+	}
+	complianceCase := r.complianceCase
+	return &complianceCase, nil
+}
+
+func (r *caseRepository) FindByID(caseID string) (*entity.ComplianceCase, error) {
+	return r.Lock(nil, caseID)
+}
+
+func (r *caseRepository) UpdateStatus(tx *sql.Tx, caseID, status string, updatedAt time.Time) error {
+	r.complianceCase.Status = status
+	return nil
+}
+
+func (r *caseRepository) Touch(tx *sql.Tx, caseID string, updatedAt time.Time) error { return nil }
+
+func (r *caseRepository) Close(tx *sql.Tx, caseID, closedBy, note string, closedAt time.Time) error {
+	r.closed = true
+	return nil
+}
+
+func (r *caseRepository) AddClearance(tx *sql.Tx, clearance entity.ScreeningClearance) error {
+	r.clearances = append(r.clearances, clearance)
+	return nil
+}
+
+func (r *caseRepository) CountOpen(tx *sql.Tx, userID, exceptCaseID string) (int, error) {
+	return r.otherOpen, nil
+}
+
+// accountRepository holds the status of one account for both the user and
+// the wallet repository.
+type accountRepository struct {
+	repository.IUserRepository
+	repository.IWalletRepository
+	user entity.User
+}
+
+func (r *accountRepository) FindByID(id string) (*entity.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *accountRepository) LockWallets(tx *sql.Tx, userIDs ...string) (map[string]entity.Wallet, error) {
+	return map[string]entity.Wallet{r.user.UserID: {UserID: r.user.UserID, Status: r.user.Status}}, nil
+}
+
+func (r *accountRepository) UpdateStatus(tx *sql.Tx, userID, status string, updatedAt time.Time) error {
+	r.user.Status = status
+	return nil
+}
+
+// accountUsers exposes the user side of accountRepository, whose
+// UpdateStatus takes a reason.
+type accountUsers struct {
+	*accountRepository
+}
+
+func (r accountUsers) UpdateStatus(tx *sql.Tx, userID, status, reason string, updatedAt time.Time) error {
+	r.user.Status = status
+	r.user.StatusReason = reason
+	return nil
+}
+
+// releasingTransactions answers ReleaseTransaction from a map of errors.
+type releasingTransactions struct {
+	ITransactionService
+	errs map[string]error
+}
+
+func (s releasingTransactions) ReleaseTransaction(req *entity.ReviewTransactionRequest) error {
+	return s.errs[req.TransactionID]
+}
+
+func newTestComplianceService(t *testing.T, cases *caseRepository, account *accountRepository, transactions ITransactionService) (*complianceService, *int) {
+	db, commits := newTxDB(t)
+	return &complianceService{
+		db:                   db,
+		complianceRepository: cases,
+		userRepository:       accountUsers{account},
+		walletRepository:     account,
+		transactionService:   transactions,
+		auditLogger:          audit.NewLogLogger(),
+	}, commits
+}
+
+func TestSetStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  error
+	}{
+		{from: entity.CaseStatusOpen, to: entity.CaseStatusInvestigating},
+		{from: entity.CaseStatusOpen, to: entity.CaseStatusEscalated},
+		{from: entity.CaseStatusInvestigating, to: entity.CaseStatusEscalated},
+		{from: entity.CaseStatusEscalated, to: entity.CaseStatusInvestigating},
+		{from: entity.CaseStatusInvestigating, to: entity.CaseStatusInvestigating, wantErr: ErrCaseTransition},
+		{from: entity.CaseStatusEscalated, to: entity.CaseStatusEscalated, wantErr: ErrCaseTransition},
+		{from: entity.CaseStatusInvestigating, to: entity.CaseStatusOpen, wantErr: ErrInvalidCaseStatus},
+		{from: entity.CaseStatusOpen, to: entity.CaseStatusClosed, wantErr: ErrInvalidCaseStatus},
+		{from: entity.CaseStatusClosed, to: entity.CaseStatusInvestigating, wantErr: ErrCaseClosed},
+	}
+
+	for _, tt := range tests {
+		name := tt.from + " to " + tt.to
+		cases := &caseRepository{complianceCase: entity.ComplianceCase{CaseID: "c1", UserID: "u1", Status: tt.from}}
+		svc, commits := newTestComplianceService(t, cases, &accountRepository{}, nil)
+
+		err := svc.SetStatus(&entity.CaseStatusRequest{CaseID: "c1", Status: tt.to, ActorID: "staff"})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error %v, want %v", name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			if *commits != 0 {
+				t.Errorf("%s: committed a refused transition", name)
+			}
+			continue
+		}
+		if cases.complianceCase.Status != tt.to {
+			t.Errorf("%s: status %s", name, cases.complianceCase.Status)
+		}
+	}
+}
+
+func TestCloseCaseUnfreeze(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		reason       string
+		otherOpen    int
+		wantErr      error
+		wantUnfrozen bool
+	}{
+		{name: "case freeze", status: entity.AccountStatusFrozen, reason: caseFreezeReason + "watchlist screening hit", wantUnfrozen: true},
+		{name: "freeze by staff", status: entity.AccountStatusFrozen, reason: "Chargeback under investigation"},
+		{name: "suspended", status: entity.AccountStatusSuspended, reason: caseFreezeReason + "case c0"},
+		{name: "other cases open", status: entity.AccountStatusFrozen, reason: caseFreezeReason + "case c0", otherOpen: 1, wantErr: ErrOtherCasesOpen},
+	}
+
+	for _, tt := range tests {
+		cases := &caseRepository{
+			complianceCase: entity.ComplianceCase{CaseID: "c1", UserID: "u1", Source: entity.CaseSourceManual, Status: entity.CaseStatusInvestigating},
+			otherOpen:      tt.otherOpen,
+		}
+		account := &accountRepository{user: entity.User{UserID: "u1", Status: tt.status, StatusReason: tt.reason}}
+		svc, commits := newTestComplianceService(t, cases, account, nil)
+
+		resp, err := svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c1", Note: "done", Unfreeze: true, ActorID: "staff"})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			// The close ran in the transaction that was rolled back.
+			if *commits != 0 {
+				t.Errorf("%s: committed the close", tt.name)
+			}
+			if account.user.Status != tt.status {
+				t.Errorf("%s: account is %s", tt.name, account.user.Status)
+			}
+			continue
+		}
+
+		if !cases.closed || *commits != 1 {
+			t.Errorf("%s: closed %v with %d commits", tt.name, cases.closed, *commits)
+		}
+		if resp.Unfrozen != tt.wantUnfrozen {
+			t.Errorf("%s: unfrozen %v, want %v", tt.name, resp.Unfrozen, tt.wantUnfrozen)
+		}
+		wantStatus := tt.status
+		if tt.wantUnfrozen {
+			wantStatus = entity.AccountStatusActive
+		}
+		if account.user.Status != wantStatus {
+			t.Errorf("%s: account is %s, want %s", tt.name, account.user.Status, wantStatus)
+		}
+	}
+}
+
+func TestCloseCaseFalsePositive(t *testing.T) {
+	matches := []entity.ScreeningMatch{{EntryID: "173", EntryVersion: "v1"}, {EntryID: "174", EntryVersion: "v2"}}
+
+	cases := &caseRepository{complianceCase: entity.ComplianceCase{CaseID: "c1", UserID: "u1", Source: entity.CaseSourceScreening, Status: entity.CaseStatusOpen, Matches: matches}}
+	svc, _ := newTestComplianceService(t, cases, &accountRepository{}, nil)
+	if _, err := svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c1", Note: "namesake", FalsePositive: true, ActorID: "staff"}); err != nil {
+		t.Fatalf("CloseCase: %v", err)
+	}
+	if len(cases.clearances) != len(matches) {
+		t.Fatalf("%d clearances, want %d", len(cases.clearances), len(matches))
+	}
+	for i, clearance := range cases.clearances {
+		if clearance.UserID != "u1" || clearance.EntryID != matches[i].EntryID || clearance.EntryVersion != matches[i].EntryVersion || clearance.CaseID != "c1" {
+			t.Errorf("clearance %d is %+v", i, clearance)
+		}
+	}
+
+	manual := &caseRepository{complianceCase: entity.ComplianceCase{CaseID: "c2", UserID: "u1", Source: entity.CaseSourceManual, Status: entity.CaseStatusOpen}}
+	svc, commits := newTestComplianceService(t, manual, &accountRepository{}, nil)
+	_, err := svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c2", Note: "namesake", FalsePositive: true, ActorID: "staff"})
+	if !errors.Is(err, ErrNotScreeningCase) || *commits != 0 {
+		t.Errorf("manual case: error %v with %d commits, want %v", err, *commits, ErrNotScreeningCase)
+	}
+}
+
+func TestCloseCaseReleasesTransactions(t *testing.T) {
+	cases := &caseRepository{complianceCase: entity.ComplianceCase{
+		CaseID:         "c1",
+		UserID:         "u1",
+		Status:         entity.CaseStatusEscalated,
+		TransactionIDs: []string{"t1", "t2", "t3", "t4"},
+	}}
+	transactions := releasingTransactions{errs: map[string]error{
+		"t2": ErrTransactionNotHeld,
+		"t3": ErrTransactionInCase,
+	}}
+	svc, _ := newTestComplianceService(t, cases, &accountRepository{}, transactions)
+
+	resp, err := svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c1", Note: "cleared", ReleaseTransactions: true, ActorID: "staff"})
+	if err != nil {
+		t.Fatalf("CloseCase: %v", err)
+	}
+	if fmt.Sprint(resp.ReleasedTransactionIDs) != "[t1 t4]" {
+		t.Errorf("released %v, want [t1 t4]", resp.ReleasedTransactionIDs)
+	}
+
+	// A failed release leaves the case closed and reports the transaction.
+	cases.closed = false
+	transactions.errs["t4"] = errors.New("connection refused")
+	_, err = svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c1", Note: "cleared", ReleaseTransactions: true, ActorID: "staff"})
+	if err == nil || !cases.closed {
+		t.Errorf("failed release: error %v, closed %v", err, cases.closed)
+	}
+
+	cases.closed = false
+	resp, err = svc.CloseCase(&entity.CloseCaseRequest{CaseID: "c1", Note: "cleared", ActorID: "staff"})
+	if err != nil {
+		t.Fatalf("without release: %v", err)
+	}
+	if len(resp.ReleasedTransactionIDs) != 0 {
+		t.Errorf("without release: released %v", resp.ReleasedTransactionIDs)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const (
+	ErrorCodeConfirmationRequired = "CONFIRMATION_REQUIRED"
+
+	confirmationTokenTTL = 5 * time.Minute
+)
+
+var (
+	ErrConfirmationRequired     = errors.New("PIN or confirmation token required")
+	ErrInvalidConfirmationToken = errors.New("invalid or expired confirmation token")
+	ErrInvalidConfirmationType  = errors.New("type must be PAYMENT or TRANSFER")
+)
+
+type IConfirmationService interface {
+	// Confirm checks the PIN and returns a short-lived token for exactly the
+	// described payment or transfer.
+	Confirm(req *entity.TransactionConfirmRequest) (*entity.TransactionConfirmResponse, error)
+
+	// Authorize lets a payment or transfer through when it comes with the
+	// user's PIN or a matching confirmation token. Tokens are single use.
+	Authorize(confirmation entity.TransactionConfirmation, pin, token, ip string) error
+}
+
+type confirmationService struct {
+	authService            IAuthService
+	confirmationRepository repository.IConfirmationRepository
+}
+
+func NewConfirmationService(authService IAuthService, confirmationRepo repository.IConfirmationRepository) IConfirmationService {
+	return &confirmationService{
+		authService:            authService,
+		confirmationRepository: confirmationRepo,
+	}
+}
+
+func (s *confirmationService) Confirm(req *entity.TransactionConfirmRequest) (*entity.TransactionConfirmResponse, error) {
+	if req.Type != entity.ConfirmationTypePayment && req.Type != entity.ConfirmationTypeTransfer {
+		return nil, ErrInvalidConfirmationType
+	}
+	if req.Type == entity.ConfirmationTypeTransfer && req.TargetUser == "" {
+		return nil, errors.New("target_user is required for transfers")
+	}
+	if !req.Amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+
+	if err := s.authService.VerifyPin(req.UserID, req.Pin, req.IPAddress); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(raw)
+
+	confirmation := entity.TransactionConfirmation{
+		UserID:     req.UserID,
+		Type:       req.Type,
+		Amount:     req.Amount,
+		TargetUser: req.TargetUser,
+	}
+	if err := s.confirmationRepository.Save(token, confirmation, confirmationTokenTTL); err != nil {
+		return nil, err
+	}
+
+	return &entity.TransactionConfirmResponse{
+		ConfirmationToken: token,
+		ExpiresAt:         time.Now().Add(confirmationTokenTTL),
+	}, nil
+}
+
+func (s *confirmationService) Authorize(confirmation entity.TransactionConfirmation, pin, token, ip string) error {
+	if token != "" {
+		stored, err := s.confirmationRepository.Consume(token)
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID != confirmation.UserID || stored.Type != confirmation.Type ||
+			stored.TargetUser != confirmation.TargetUser || stored.Amount != confirmation.Amount {
+			return ErrInvalidConfirmationToken
+		}
+		return nil
+	}
+
+	if pin != "" {
+		return s.authService.VerifyPin(confirmation.UserID, pin, ip)
+	}
+
+	return ErrConfirmationRequired
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+var ErrDeadJobNotFound = errors.New("Dead job not found")
+
+type IJobService interface {
+	ListDeadJobs(filter entity.DeadJobFilter) ([]*entity.FailedJob, error)
+	RetryDeadJob(diedAt int64, jobID string) error
+	DiscardDeadJob(diedAt int64, jobID string) error
+	RetryDeadJobs(filter entity.DeadJobFilter) (*entity.DeadJobBatchResult, error)
+	DiscardDeadJobs(filter entity.DeadJobFilter) (*entity.DeadJobBatchResult, error)
+	Stats() ([]*entity.JobTypeStats, error)
+}
+
+type jobService struct {
+	jobRepository      repository.IJobRepository
+	transactionService ITransactionService
+}
+
+func NewJobService(jobRepo repository.IJobRepository, transactionService ITransactionService) IJobService {
+	return &jobService{
+		jobRepository:      jobRepo,
+		transactionService: transactionService,
+	}
+}
+
+func (s *jobService) ListDeadJobs(filter entity.DeadJobFilter) ([]*entity.FailedJob, error) {
+	jobs, err := s.allJobs(s.jobRepository.DeadJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*entity.FailedJob{}
+	for _, job := range jobs {
+		if filter.Match(job) {
+			matched = append(matched, job)
+		}
+	}
+
+	return matched, nil
+}
+
+// RetryDeadJob puts a dead job back on its queue. The transaction it applies
+// was marked FAILED when the job died, so it is reopened first; otherwise the
+// worker would skip it.
+func (s *jobService) RetryDeadJob(diedAt int64, jobID string) error {
+	job, err := s.findDeadJob(diedAt, jobID)
+	if err != nil {
+		return err
+	}
+
+	return s.retry(job)
+}
+
+func (s *jobService) DiscardDeadJob(diedAt int64, jobID string) error {
+	if _, err := s.findDeadJob(diedAt, jobID); err != nil {
+		return err
+	}
+
+	return s.jobRepository.DeleteDeadJob(diedAt, jobID)
+}
+
+func (s *jobService) RetryDeadJobs(filter entity.DeadJobFilter) (*entity.DeadJobBatchResult, error) {
+	return s.batch(filter, s.retry)
+}
+
+func (s *jobService) DiscardDeadJobs(filter entity.DeadJobFilter) (*entity.DeadJobBatchResult, error) {
+	return s.batch(filter, func(job *entity.FailedJob) error {
+		return s.jobRepository.DeleteDeadJob(job.DiedAt, job.ID)
+	})
+}
+
+func (s *jobService) Stats() ([]*entity.JobTypeStats, error) {
+	queued, err := s.jobRepository.QueuedCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]*entity.JobTypeStats{}
+	statsFor := func(name string) *entity.JobTypeStats {
+		if _, ok := stats[name]; !ok {
+			stats[name] = &entity.JobTypeStats{Name: name}
+		}
+		return stats[name]
+	}
+
+	for name, count := range queued {
+		statsFor(name).Queued = count
+	}
+
+	retrying, err := s.allJobs(s.jobRepository.RetryingJobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range retrying {
+		jobStats := statsFor(job.Name)
+		jobStats.Retrying++
+		jobStats.TotalFails += job.Fails
+	}
+
+	dead, err := s.allJobs(s.jobRepository.DeadJobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range dead {
+		jobStats := statsFor(job.Name)
+		jobStats.Dead++
+		jobStats.TotalFails += job.Fails
+	}
+
+	result := make([]*entity.JobTypeStats, 0, len(stats))
+	for _, jobStats := range stats {
+		result = append(result, jobStats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// retry reopens the transaction of job before queueing the job again, since
+// the worker skips transactions that are not PENDING. When the job cannot be
+// queued the reopened transaction is failed again, so it is not left PENDING
+// without a job while counting against the limits.
+func (s *jobService) retry(job *entity.FailedJob) error {
+	var transactionID string
+	var reopened bool
+	if argName, ok := entity.JobTransactionIDArg[job.Name]; ok {
+		transactionID, _ = job.Args[argName].(string)
+		var err error
+		if reopened, err = s.transactionService.ReopenTransaction(transactionID); err != nil {
+			return err
+		}
+	}
+
+	err := s.jobRepository.RetryDeadJob(job.DiedAt, job.ID)
+	// A job that left the dead set meanwhile may be queued by another retry,
+	// which needs the transaction PENDING.
+	if err == nil || !reopened || errors.Is(err, repository.ErrJobNotRetried) {
+		return err
+	}
+	if failErr := s.transactionService.FailTransaction(transactionID, entity.FailureReasonProcessingError); failErr != nil {
+		return fmt.Errorf("%w; transaction %s stays PENDING: %v", err, transactionID, failErr)
+	}
+	return err
+}
+
+func (s *jobService) batch(filter entity.DeadJobFilter, apply func(job *entity.FailedJob) error) (*entity.DeadJobBatchResult, error) {
+	jobs, err := s.ListDeadJobs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.DeadJobBatchResult{Matched: len(jobs)}
+	for _, job := range jobs {
+		if err := apply(job); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%d/%s: %v", job.DiedAt, job.ID, err))
+			continue
+		}
+		result.Succeeded++
+	}
+
+	return result, nil
+}
+
+func (s *jobService) findDeadJob(diedAt int64, jobID string) (*entity.FailedJob, error) {
+	jobs, err := s.allJobs(s.jobRepository.DeadJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.DiedAt == diedAt && job.ID == jobID {
+			return job, nil
+		}
+	}
+
+	return nil, ErrDeadJobNotFound
+}
+
+// allJobs walks every page of a gocraft/work sorted set.
+func (s *jobService) allJobs(page func(page uint) ([]*entity.FailedJob, int64, error)) ([]*entity.FailedJob, error) {
+	var all []*entity.FailedJob
+
+	for p := uint(1); ; p++ {
+		jobs, count, err := page(p)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, jobs...)
+
+		if len(jobs) == 0 || int64(len(all)) >= count {
+			return all, nil
+		}
+	}
+}
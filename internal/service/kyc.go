@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/blob"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const maxKYCIDNumberLength = 50
+
+var (
+	ErrKYCSubmissionNotFound = errors.New("kyc submission not found")
+	ErrKYCDocumentNotFound   = errors.New("kyc document not found")
+	ErrKYCPending            = errors.New("a kyc submission is already waiting for review")
+	ErrKYCAlreadyFull        = errors.New("user already has the full tier")
+	ErrKYCAlreadyReviewed    = errors.New("kyc submission was already reviewed")
+	ErrKYCSelfReview         = errors.New("staff cannot review their own kyc submission")
+	ErrKYCNoteRequired       = errors.New("note is required when rejecting")
+	ErrInvalidIDType         = errors.New("id_type must be KTP, PASSPORT or SIM")
+	ErrInvalidIDNumber       = errors.New("id_number is required")
+	ErrInvalidKYCImage       = errors.New("images must be JPEG or PNG")
+	ErrKYCImageTooLarge      = errors.New("image is too large")
+)
+
+// kycImageExtensions are the accepted image types, detected from the content
+// rather than trusted from the upload.
+var kycImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+type IKYCService interface {
+	// Status returns the tier of userID and its latest submission, if any.
+	Status(userID string) (*entity.KYCStatusResponse, error)
+	Submit(req *entity.SubmitKYCRequest) (*entity.KYCSubmission, error)
+
+	FindSubmissions(filter entity.KYCSubmissionFilter) ([]*entity.KYCSubmission, error)
+	FindSubmission(submissionID string) (*entity.KYCSubmission, error)
+	// OpenDocument returns entity.KYCDocumentIDImage or
+	// entity.KYCDocumentSelfie of a submission and its content type.
+	OpenDocument(submissionID, document string) (io.ReadCloser, string, error)
+
+	// Approve moves the user to entity.TierFull. Reject needs a note for the
+	// user, who may then submit again.
+	Approve(req *entity.ReviewKYCRequest) error
+	Reject(req *entity.ReviewKYCRequest) error
+}
+
+type kycService struct {
+	config         *config.Config
+	db             *sql.DB
+	kycRepository  repository.IKYCRepository
+	userRepository repository.IUserRepository
+	blobStore      blob.Store
+	auditLogger    audit.Logger
+}
+
+func NewKYCService(cfg *config.Config, db *sql.DB, kycRepo repository.IKYCRepository, userRepo repository.IUserRepository, blobStore blob.Store, auditLogger audit.Logger) IKYCService {
+	return &kycService{
+		config:         cfg,
+		db:             db,
+		kycRepository:  kycRepo,
+		userRepository: userRepo,
+		blobStore:      blobStore,
+		auditLogger:    auditLogger,
+	}
+}
+
+func (s *kycService) Status(userID string) (*entity.KYCStatusResponse, error) {
+	user, err := s.userRepository.FindByID(userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := s.kycRepository.FindLatestByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.KYCStatusResponse{Tier: user.Tier, Submission: submission}, nil
+}
+
+func (s *kycService) Submit(req *entity.SubmitKYCRequest) (*entity.KYCSubmission, error) {
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Tier == entity.TierFull {
+		return nil, ErrKYCAlreadyFull
+	}
+
+	latest, err := s.kycRepository.FindLatestByUserID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == entity.KYCStatusPending {
+		return nil, ErrKYCPending
+	}
+
+	switch req.IDType {
+	case entity.KYCIDTypeKTP, entity.KYCIDTypePassport, entity.KYCIDTypeSIM:
+	default:
+		return nil, ErrInvalidIDType
+	}
+	idNumber := strings.TrimSpace(req.IDNumber)
+	if idNumber == "" || len(idNumber) > maxKYCIDNumberLength {
+		return nil, ErrInvalidIDNumber
+	}
+
+	idImageExtension, err := s.imageExtension(req.IDImage)
+	if err != nil {
+		return nil, err
+	}
+	selfieExtension, err := s.imageExtension(req.Selfie)
+	if err != nil {
+		return nil, err
+	}
+
+	selfieMetadata := req.SelfieMetadata
+	if selfieMetadata == nil {
+		selfieMetadata = map[string]interface{}{}
+	}
+
+	submission := entity.KYCSubmission{
+		SubmissionID:   uuid.New().String(),
+		UserID:         req.UserID,
+		Status:         entity.KYCStatusPending,
+		IDType:         req.IDType,
+		IDNumber:       idNumber,
+		SelfieMetadata: selfieMetadata,
+		CreatedAt:      time.Now(),
+	}
+	prefix := path.Join("kyc", req.UserID, submission.SubmissionID)
+	submission.IDImageKey = path.Join(prefix, entity.KYCDocumentIDImage+idImageExtension)
+	submission.SelfieKey = path.Join(prefix, entity.KYCDocumentSelfie+selfieExtension)
+
+	if err := s.blobStore.Put(submission.IDImageKey, bytes.NewReader(req.IDImage)); err != nil {
+		return nil, err
+	}
+	if err := s.blobStore.Put(submission.SelfieKey, bytes.NewReader(req.Selfie)); err != nil {
+		s.blobStore.Delete(submission.IDImageKey)
+		return nil, err
+	}
+	if err := s.kycRepository.Create(submission); err != nil {
+		s.blobStore.Delete(submission.IDImageKey)
+		s.blobStore.Delete(submission.SelfieKey)
+		return nil, err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventKYCSubmitted,
+		ActorID:     req.UserID,
+		UserID:      req.UserID,
+		TargetID:    submission.SubmissionID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		UserAgent:   req.UserAgent,
+		Details: map[string]interface{}{
+			"id_type": req.IDType,
+		},
+		CreatedAt: submission.CreatedAt,
+	})
+
+	return &submission, nil
+}
+
+func (s *kycService) imageExtension(image []byte) (string, error) {
+	if len(image) == 0 {
+		return "", ErrInvalidKYCImage
+	}
+	if len(image) > s.config.KYCMaxImageBytes {
+		return "", fmt.Errorf("%w, the limit is %d bytes", ErrKYCImageTooLarge, s.config.KYCMaxImageBytes)
+	}
+
+	extension, ok := kycImageExtensions[http.DetectContentType(image)]
+	if !ok {
+		return "", ErrInvalidKYCImage
+	}
+	return extension, nil
+}
+
+func (s *kycService) FindSubmissions(filter entity.KYCSubmissionFilter) ([]*entity.KYCSubmission, error) {
+	return s.kycRepository.Find(filter)
+}
+
+func (s *kycService) FindSubmission(submissionID string) (*entity.KYCSubmission, error) {
+	submission, err := s.kycRepository.FindByID(submissionID)
+	if err == sql.ErrNoRows {
+		return nil, ErrKYCSubmissionNotFound
+	}
+	return submission, err
+}
+
+func (s *kycService) OpenDocument(submissionID, document string) (io.ReadCloser, string, error) {
+	submission, err := s.FindSubmission(submissionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var key string
+	switch document {
+	case entity.KYCDocumentIDImage:
+		key = submission.IDImageKey
+	case entity.KYCDocumentSelfie:
+		key = submission.SelfieKey
+	default:
+		return nil, "", ErrKYCDocumentNotFound
+	}
+
+	reader, err := s.blobStore.Open(key)
+	if err == blob.ErrNotFound {
+		return nil, "", ErrKYCDocumentNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return reader, mime.TypeByExtension(path.Ext(key)), nil
+}
+
+func (s *kycService) Approve(req *entity.ReviewKYCRequest) error {
+	return s.review(req, entity.KYCStatusApproved)
+}
+
+func (s *kycService) Reject(req *entity.ReviewKYCRequest) error {
+	if strings.TrimSpace(req.Note) == "" {
+		return ErrKYCNoteRequired
+	}
+	return s.review(req, entity.KYCStatusRejected)
+}
+
+func (s *kycService) review(req *entity.ReviewKYCRequest, status string) error {
+	var submission *entity.KYCSubmission
+	var user *entity.User
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		var err error
+		submission, err = s.kycRepository.Lock(tx, req.SubmissionID)
+		if err == sql.ErrNoRows {
+			return ErrKYCSubmissionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if submission.Status != entity.KYCStatusPending {
+			return ErrKYCAlreadyReviewed
+		}
+		if submission.UserID == req.ActorID {
+			return ErrKYCSelfReview
+		}
+
+		user, err = s.userRepository.FindByID(submission.UserID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		submission.Status = status
+		submission.ReviewerID = req.ActorID
+		submission.ReviewNote = strings.TrimSpace(req.Note)
+		submission.ReviewedAt = &now
+		if err := s.kycRepository.UpdateReview(tx, *submission); err != nil {
+			return err
+		}
+
+		if status == entity.KYCStatusApproved {
+			return s.userRepository.UpdateTier(tx, submission.UserID, entity.TierFull, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	event := entity.AuditEvent{
+		Type:        entity.AuditEventKYCRejected,
+		ActorID:     req.ActorID,
+		UserID:      submission.UserID,
+		TargetID:    submission.SubmissionID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		Details: map[string]interface{}{
+			"note": submission.ReviewNote,
+		},
+		CreatedAt: *submission.ReviewedAt,
+	}
+	if status == entity.KYCStatusApproved {
+		event.Type = entity.AuditEventKYCApproved
+		event.Before = map[string]interface{}{"tier": user.Tier}
+		event.After = map[string]interface{}{"tier": entity.TierFull}
+	}
+	s.auditLogger.Log(event)
+
+	return nil
+}
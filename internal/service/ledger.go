@@ -0,0 +1,285 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+var ErrUnbalancedJournal = errors.New("journal entry is not balanced")
+
+type ILedgerService interface {
+	Post(tx *sql.Tx, journal entity.JournalEntry) (*entity.JournalEntry, error)
+	PostTopUp(tx *sql.Tx, referenceID, userID string, amount entity.Money, now time.Time) error
+	PostPayment(tx *sql.Tx, referenceID, userID string, amount entity.Money, description string, now time.Time) error
+	PostTransfer(tx *sql.Tx, referenceID, fromUserID, toUserID string, amount entity.Money, description string, now time.Time) error
+	PostPayout(tx *sql.Tx, referenceID, userID string, amount entity.Money, description string, now time.Time) error
+
+	GetWalletBalance(userID string) (entity.Money, error)
+	FindJournalsByReferenceID(referenceID string) ([]*entity.JournalEntry, error)
+	CheckConservation() error
+
+	// Check compares the ledger with the wallets.
+	Check() (*entity.LedgerReport, error)
+	// OpenBalances posts an opening balance against SYSTEM:OPENING_EQUITY
+	// for every wallet whose balance its postings do not explain and that
+	// has no opening balance yet, i.e. wallets older than the ledger. It
+	// returns the journals posted.
+	OpenBalances() ([]*entity.JournalEntry, error)
+}
+
+type ledgerService struct {
+	db               *sql.DB
+	ledgerRepository repository.ILedgerRepository
+	walletRepository repository.IWalletRepository
+}
+
+func NewLedgerService(db *sql.DB, ledgerRepo repository.ILedgerRepository, walletRepo repository.IWalletRepository) ILedgerService {
+	return &ledgerService{
+		db:               db,
+		ledgerRepository: ledgerRepo,
+		walletRepository: walletRepo,
+	}
+}
+
+// Post validates that the journal is balanced and writes it with its postings
+// inside tx. Wallet accounts are created on first use.
+func (s *ledgerService) Post(tx *sql.Tx, journal entity.JournalEntry) (*entity.JournalEntry, error) {
+	if err := validateJournal(journal); err != nil {
+		return nil, err
+	}
+
+	if journal.JournalID == "" {
+		journal.JournalID = uuid.New().String()
+	}
+	if journal.CreatedAt.IsZero() {
+		journal.CreatedAt = time.Now()
+	}
+
+	for _, posting := range journal.Postings {
+		account := entity.LedgerAccount{
+			AccountCode: posting.AccountCode,
+			Type:        entity.LedgerAccountTypeSystem,
+			Currency:    posting.Amount.Currency,
+			CreatedAt:   journal.CreatedAt,
+		}
+		if userID, ok := walletOwner(posting.AccountCode); ok {
+			account.Type = entity.LedgerAccountTypeWallet
+			account.UserID = userID
+		}
+		if err := s.ledgerRepository.EnsureAccount(tx, account); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ledgerRepository.InsertJournal(tx, journal); err != nil {
+		return nil, err
+	}
+
+	return &journal, nil
+}
+
+func (s *ledgerService) PostTopUp(tx *sql.Tx, referenceID, userID string, amount entity.Money, now time.Time) error {
+	_, err := s.Post(tx, entity.JournalEntry{
+		ReferenceID: referenceID,
+		Type:        entity.JournalTypeTopUp,
+		Postings: []entity.Posting{
+			{AccountCode: entity.SystemAccountTopUpSource, Direction: entity.PostingDirectionDebit, Amount: amount},
+			{AccountCode: entity.WalletAccountCode(userID), Direction: entity.PostingDirectionCredit, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	return err
+}
+
+func (s *ledgerService) PostPayment(tx *sql.Tx, referenceID, userID string, amount entity.Money, description string, now time.Time) error {
+	_, err := s.Post(tx, entity.JournalEntry{
+		ReferenceID: referenceID,
+		Type:        entity.JournalTypePayment,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountCode: entity.WalletAccountCode(userID), Direction: entity.PostingDirectionDebit, Amount: amount},
+			{AccountCode: entity.SystemAccountPaymentSink, Direction: entity.PostingDirectionCredit, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	return err
+}
+
+func (s *ledgerService) PostTransfer(tx *sql.Tx, referenceID, fromUserID, toUserID string, amount entity.Money, description string, now time.Time) error {
+	_, err := s.Post(tx, entity.JournalEntry{
+		ReferenceID: referenceID,
+		Type:        entity.JournalTypeTransfer,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountCode: entity.WalletAccountCode(fromUserID), Direction: entity.PostingDirectionDebit, Amount: amount},
+			{AccountCode: entity.WalletAccountCode(toUserID), Direction: entity.PostingDirectionCredit, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	return err
+}
+
+func (s *ledgerService) PostPayout(tx *sql.Tx, referenceID, userID string, amount entity.Money, description string, now time.Time) error {
+	_, err := s.Post(tx, entity.JournalEntry{
+		ReferenceID: referenceID,
+		Type:        entity.JournalTypePayout,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountCode: entity.WalletAccountCode(userID), Direction: entity.PostingDirectionDebit, Amount: amount},
+			{AccountCode: entity.SystemAccountPayoutSink, Direction: entity.PostingDirectionCredit, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	return err
+}
+
+func (s *ledgerService) GetWalletBalance(userID string) (entity.Money, error) {
+	return s.ledgerRepository.GetAccountBalance(entity.WalletAccountCode(userID))
+}
+
+func (s *ledgerService) FindJournalsByReferenceID(referenceID string) ([]*entity.JournalEntry, error) {
+	return s.ledgerRepository.FindJournalsByReferenceID(referenceID)
+}
+
+// CheckConservation returns an error if credits and debits across the whole
+// ledger do not cancel out.
+func (s *ledgerService) CheckConservation() error {
+	total, err := s.ledgerRepository.SumAllPostings()
+	if err != nil {
+		return err
+	}
+	if !total.IsZero() {
+		return fmt.Errorf("%w: ledger is off by %s", ErrUnbalancedJournal, total)
+	}
+	return nil
+}
+
+func (s *ledgerService) Check() (*entity.LedgerReport, error) {
+	total, err := s.ledgerRepository.SumAllPostings()
+	if err != nil {
+		return nil, err
+	}
+	mismatches, err := s.ledgerRepository.FindWalletMismatches()
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.LedgerReport{
+		Total:      total,
+		Balanced:   total.IsZero() && len(mismatches) == 0,
+		Mismatches: mismatches,
+	}, nil
+}
+
+func (s *ledgerService) OpenBalances() ([]*entity.JournalEntry, error) {
+	mismatches, err := s.ledgerRepository.FindWalletMismatches()
+	if err != nil {
+		return nil, err
+	}
+
+	posted := []*entity.JournalEntry{}
+	for _, mismatch := range mismatches {
+		if mismatch.Opened {
+			continue
+		}
+
+		var journal *entity.JournalEntry
+		err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+			journal = nil
+			// Every posting to a wallet is made under its lock, so the
+			// difference cannot change before the opening is posted.
+			wallets, err := s.walletRepository.LockWallets(tx, mismatch.UserID)
+			if err != nil {
+				return err
+			}
+			wallet, ok := wallets[mismatch.UserID]
+			if !ok {
+				return nil
+			}
+			opened, err := s.ledgerRepository.HasJournal(tx, mismatch.UserID, entity.JournalTypeOpening)
+			if err != nil || opened {
+				return err
+			}
+
+			ledgerBalance, err := s.ledgerRepository.GetAccountBalance(entity.WalletAccountCode(mismatch.UserID))
+			if err != nil {
+				return err
+			}
+			difference, err := wallet.Balance.Sub(ledgerBalance)
+			if err != nil || difference.IsZero() {
+				return err
+			}
+
+			debit, credit := entity.SystemAccountOpeningEquity, entity.WalletAccountCode(mismatch.UserID)
+			if difference.IsNegative() {
+				debit, credit = credit, debit
+				difference.Amount = -difference.Amount
+			}
+			journal, err = s.Post(tx, entity.JournalEntry{
+				ReferenceID: mismatch.UserID,
+				Type:        entity.JournalTypeOpening,
+				Description: "Opening balance",
+				Postings: []entity.Posting{
+					{AccountCode: debit, Direction: entity.PostingDirectionDebit, Amount: difference},
+					{AccountCode: credit, Direction: entity.PostingDirectionCredit, Amount: difference},
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return posted, fmt.Errorf("opening balance of %s: %w", mismatch.UserID, err)
+		}
+		if journal != nil {
+			posted = append(posted, journal)
+		}
+	}
+
+	return posted, nil
+}
+
+func validateJournal(journal entity.JournalEntry) error {
+	if len(journal.Postings) < 2 {
+		return fmt.Errorf("%w: at least two postings are required", ErrUnbalancedJournal)
+	}
+
+	currency := journal.Postings[0].Amount.Currency
+	debits := entity.NewMoney(0, currency)
+	credits := entity.NewMoney(0, currency)
+
+	for _, posting := range journal.Postings {
+		if !posting.Amount.IsPositive() {
+			return fmt.Errorf("%w: posting amount must be positive", ErrUnbalancedJournal)
+		}
+
+		var err error
+		switch posting.Direction {
+		case entity.PostingDirectionDebit:
+			debits, err = debits.Add(posting.Amount)
+		case entity.PostingDirectionCredit:
+			credits, err = credits.Add(posting.Amount)
+		default:
+			return fmt.Errorf("%w: unknown posting direction %q", ErrUnbalancedJournal, posting.Direction)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmp, _ := debits.Cmp(credits); cmp != 0 {
+		return fmt.Errorf("%w: debits %s, credits %s", ErrUnbalancedJournal, debits, credits)
+	}
+
+	return nil
+}
+
+func walletOwner(accountCode string) (string, bool) {
+	userID, ok := strings.CutPrefix(accountCode, entity.WalletAccountCode(""))
+	return userID, ok && userID != ""
+}
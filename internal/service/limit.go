@@ -0,0 +1,355 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/limits"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const (
+	ErrorCodeLimitMinAmount     = "LIMIT_MIN_AMOUNT"
+	ErrorCodeLimitMaxAmount     = "LIMIT_MAX_AMOUNT"
+	ErrorCodeLimitDailyAmount   = "LIMIT_DAILY_AMOUNT"
+	ErrorCodeLimitMonthlyAmount = "LIMIT_MONTHLY_AMOUNT"
+	ErrorCodeLimitHourlyCount   = "LIMIT_HOURLY_COUNT"
+	ErrorCodeLimitBalanceCap    = "LIMIT_BALANCE_CAP"
+)
+
+// limitCounterGrace keeps counters a while past their period, so a failed
+// transaction from just before the end of the period can still be taken off.
+const limitCounterGrace = time.Hour
+
+var ErrInvalidAmount = errors.New("amount must be greater than zero")
+
+// LimitExceededError is returned when a transaction would break a limit.
+// Limit and Remaining are amounts, or numbers of transactions for
+// ErrorCodeLimitHourlyCount; ResetAt is when the period ends and is zero for
+// per-transaction limits and the balance cap.
+type LimitExceededError struct {
+	Code      string
+	Kind      string
+	Limit     string
+	Remaining string
+	ResetAt   time.Time
+}
+
+func (e *LimitExceededError) Error() string {
+	switch e.Code {
+	case ErrorCodeLimitMinAmount:
+		return fmt.Sprintf("%s amount must be at least %s", e.Kind, e.Limit)
+	case ErrorCodeLimitMaxAmount:
+		return fmt.Sprintf("%s amount must not be more than %s", e.Kind, e.Limit)
+	case ErrorCodeLimitHourlyCount:
+		return fmt.Sprintf("limit of %s %s transactions per hour reached", e.Limit, e.Kind)
+	case ErrorCodeLimitBalanceCap:
+		return fmt.Sprintf("balance cap of %s exceeded, %s remaining", e.Limit, e.Remaining)
+	case ErrorCodeLimitDailyAmount:
+		return fmt.Sprintf("daily %s limit of %s exceeded, %s remaining", e.Kind, e.Limit, e.Remaining)
+	default:
+		return fmt.Sprintf("monthly %s limit of %s exceeded, %s remaining", e.Kind, e.Limit, e.Remaining)
+	}
+}
+
+type ILimitService interface {
+	// CheckBalanceCap returns a LimitExceededError when crediting amount to a
+	// wallet of tier holding balance would pass the tier's balance cap.
+	CheckBalanceCap(tier string, balance, amount entity.Money) error
+
+	// Check returns a LimitExceededError when usage would break a limit of
+	// tier, without counting it.
+	Check(tier string, usage entity.LimitUsage) error
+
+	// Reserve counts usage against the limits of tier in Redis, or returns a
+	// LimitExceededError. Record must then store it in the database
+	// transaction that stores the transaction; when that fails,
+	// ReleaseCounters gives the reservation back.
+	Reserve(tier string, usage entity.LimitUsage) error
+	Record(tx *sql.Tx, usage entity.LimitUsage) error
+
+	// Release stops the usage of a failed transaction from counting and
+	// Restore counts it again when the transaction is retried. Both return
+	// the usage, or nil when there was nothing to change; pass it to
+	// ReleaseCounters or RestoreCounters once tx is committed.
+	Release(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error)
+	Restore(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error)
+	ReleaseCounters(usage entity.LimitUsage)
+	RestoreCounters(usage entity.LimitUsage)
+}
+
+type limitService struct {
+	rules             *limits.Store
+	usageRepository   repository.ILimitUsageRepository
+	counterRepository repository.ILimitCounterRepository
+}
+
+func NewLimitService(rules *limits.Store, usageRepo repository.ILimitUsageRepository, counterRepo repository.ILimitCounterRepository) ILimitService {
+	return &limitService{
+		rules:             rules,
+		usageRepository:   usageRepo,
+		counterRepository: counterRepo,
+	}
+}
+
+// limitWindow is the counter of one period of a user's transactions of one
+// kind. Hours, days and months are those of the server's time zone.
+type limitWindow struct {
+	code     string
+	count    bool
+	from, to time.Time
+	key      string
+	max      int64
+}
+
+func limitWindows(usage entity.LimitUsage, rule entity.LimitRule) []limitWindow {
+	t := usage.CreatedAt.Local()
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	prefix := "limit:" + usage.UserID + ":" + usage.Kind
+
+	return []limitWindow{
+		{
+			code: ErrorCodeLimitDailyAmount,
+			from: day, to: day.AddDate(0, 0, 1),
+			key: prefix + ":day:" + day.Format("20060102"),
+			max: rule.DailyAmount.Amount,
+		},
+		{
+			code: ErrorCodeLimitMonthlyAmount,
+			from: month, to: month.AddDate(0, 1, 0),
+			key: prefix + ":month:" + month.Format("200601"),
+			max: rule.MonthlyAmount.Amount,
+		},
+		{
+			code:  ErrorCodeLimitHourlyCount,
+			count: true,
+			from:  hour, to: hour.Add(time.Hour),
+			key: prefix + ":hour:" + hour.Format("2006010215"),
+			max: rule.HourlyCount,
+		},
+	}
+}
+
+func (w limitWindow) add(usage entity.LimitUsage) int64 {
+	if w.count {
+		return 1
+	}
+	return usage.Amount.Amount
+}
+
+func (w limitWindow) counter(usage entity.LimitUsage, sign int64) repository.LimitCounter {
+	return repository.LimitCounter{
+		Key: w.key,
+		Add: sign * w.add(usage),
+		Max: w.max,
+		TTL: time.Until(w.to) + limitCounterGrace,
+	}
+}
+
+func (w limitWindow) exceeded(usage entity.LimitUsage, current int64) *LimitExceededError {
+	remaining := w.max - current
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	err := &LimitExceededError{Code: w.code, Kind: usage.Kind, ResetAt: w.to}
+	if w.count {
+		err.Limit = strconv.FormatInt(w.max, 10)
+		err.Remaining = strconv.FormatInt(remaining, 10)
+	} else {
+		err.Limit = entity.NewMoney(w.max, usage.Amount.Currency).String()
+		err.Remaining = entity.NewMoney(remaining, usage.Amount.Currency).String()
+	}
+	return err
+}
+
+func (s *limitService) rule(tier string, usage entity.LimitUsage) (entity.LimitRule, error) {
+	rule, _ := s.rules.Rules().Rule(tier, usage.Kind)
+
+	if !rule.MinAmount.IsZero() {
+		below, err := usage.Amount.LessThan(rule.MinAmount)
+		if err != nil {
+			return rule, err
+		}
+		if below {
+			return rule, &LimitExceededError{Code: ErrorCodeLimitMinAmount, Kind: usage.Kind, Limit: rule.MinAmount.String()}
+		}
+	}
+	if !rule.MaxAmount.IsZero() {
+		above, err := rule.MaxAmount.LessThan(usage.Amount)
+		if err != nil {
+			return rule, err
+		}
+		if above {
+			return rule, &LimitExceededError{
+				Code:      ErrorCodeLimitMaxAmount,
+				Kind:      usage.Kind,
+				Limit:     rule.MaxAmount.String(),
+				Remaining: rule.MaxAmount.String(),
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+func (s *limitService) CheckBalanceCap(tier string, balance, amount entity.Money) error {
+	balanceCap, ok := s.rules.Rules().BalanceCap(tier)
+	if !ok {
+		return nil
+	}
+
+	balanceAfter, err := balance.Add(amount)
+	if err != nil {
+		return err
+	}
+	above, err := balanceCap.LessThan(balanceAfter)
+	if err != nil || !above {
+		return err
+	}
+
+	remaining, err := balanceCap.Sub(balance)
+	if err != nil {
+		return err
+	}
+	if remaining.IsNegative() {
+		remaining = entity.NewMoney(0, remaining.Currency)
+	}
+	return &LimitExceededError{
+		Code:      ErrorCodeLimitBalanceCap,
+		Limit:     balanceCap.String(),
+		Remaining: remaining.String(),
+	}
+}
+
+func (s *limitService) Check(tier string, usage entity.LimitUsage) error {
+	rule, err := s.rule(tier, usage)
+	if err != nil {
+		return err
+	}
+
+	windows := limitWindows(usage, rule)
+	current, err := s.load(usage, windows)
+	if err != nil {
+		return err
+	}
+
+	for i, window := range windows {
+		if window.max > 0 && current[i]+window.add(usage) > window.max {
+			return window.exceeded(usage, current[i])
+		}
+	}
+	return nil
+}
+
+func (s *limitService) Reserve(tier string, usage entity.LimitUsage) error {
+	rule, err := s.rule(tier, usage)
+	if err != nil {
+		return err
+	}
+
+	windows := limitWindows(usage, rule)
+	counters := make([]repository.LimitCounter, len(windows))
+	for i, window := range windows {
+		counters[i] = window.counter(usage, 1)
+	}
+
+	exceeded, current, err := s.counterRepository.Reserve(counters)
+	if err == repository.ErrLimitCounterMissing {
+		if _, err := s.load(usage, windows); err != nil {
+			return err
+		}
+		exceeded, current, err = s.counterRepository.Reserve(counters)
+	}
+	if err != nil {
+		return err
+	}
+
+	if exceeded >= 0 {
+		return windows[exceeded].exceeded(usage, current)
+	}
+	return nil
+}
+
+// load returns the counters of windows, seeding missing ones from MySQL.
+func (s *limitService) load(usage entity.LimitUsage, windows []limitWindow) ([]int64, error) {
+	keys := make([]string, len(windows))
+	for i, window := range windows {
+		keys[i] = window.key
+	}
+
+	values, exists, err := s.counterRepository.Get(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	seeded := false
+	for i, window := range windows {
+		if exists[i] {
+			continue
+		}
+
+		amount, count, err := s.usageRepository.Sum(usage.UserID, usage.Kind, window.from, window.to)
+		if err != nil {
+			return nil, err
+		}
+		value := amount.Amount
+		if window.count {
+			value = count
+		}
+		if err := s.counterRepository.Seed(window.key, value, time.Until(window.to)+limitCounterGrace); err != nil {
+			return nil, err
+		}
+		seeded = true
+	}
+
+	// Another request may have seeded a counter first.
+	if seeded {
+		values, _, err = s.counterRepository.Get(keys...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (s *limitService) Record(tx *sql.Tx, usage entity.LimitUsage) error {
+	return s.usageRepository.Record(tx, usage)
+}
+
+func (s *limitService) Release(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error) {
+	return s.usageRepository.Release(tx, transactionID, time.Now())
+}
+
+func (s *limitService) Restore(tx *sql.Tx, transactionID string) (*entity.LimitUsage, error) {
+	return s.usageRepository.Restore(tx, transactionID)
+}
+
+// ReleaseCounters and RestoreCounters only log errors: the usage in MySQL is
+// already right, and a counter that is off corrects itself with its period.
+func (s *limitService) ReleaseCounters(usage entity.LimitUsage) {
+	s.adjustCounters(usage, -1)
+}
+
+func (s *limitService) RestoreCounters(usage entity.LimitUsage) {
+	s.adjustCounters(usage, 1)
+}
+
+func (s *limitService) adjustCounters(usage entity.LimitUsage, sign int64) {
+	windows := limitWindows(usage, entity.LimitRule{})
+	counters := make([]repository.LimitCounter, len(windows))
+	for i, window := range windows {
+		counters[i] = window.counter(usage, sign)
+	}
+
+	if err := s.counterRepository.Adjust(counters); err != nil {
+		log.Printf("limits: failed to adjust counters of transaction %s: %v", usage.TransactionID, err)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+func TestLimitWindows(t *testing.T) {
+	// Windows follow the server's time zone. Jakarta has no daylight saving
+	// time, so every day has 24 hours.
+	local := time.Local
+	time.Local = time.FixedZone("WIB", 7*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		hour      [2]time.Time
+		day       [2]time.Time
+		month     [2]time.Time
+		hourKey   string
+		dayKey    string
+		monthKey  string
+	}{
+		{
+			name:      "middle of the month",
+			createdAt: at(2024, time.March, 15, 10, 30),
+			hour:      [2]time.Time{at(2024, time.March, 15, 10, 0), at(2024, time.March, 15, 11, 0)},
+			day:       [2]time.Time{at(2024, time.March, 15, 0, 0), at(2024, time.March, 16, 0, 0)},
+			month:     [2]time.Time{at(2024, time.March, 1, 0, 0), at(2024, time.April, 1, 0, 0)},
+			hourKey:   "limit:u1:TRANSFER:hour:2024031510",
+			dayKey:    "limit:u1:TRANSFER:day:20240315",
+			monthKey:  "limit:u1:TRANSFER:month:202403",
+		},
+		{
+			name:      "last minute of the year",
+			createdAt: at(2024, time.December, 31, 23, 59),
+			hour:      [2]time.Time{at(2024, time.December, 31, 23, 0), at(2025, time.January, 1, 0, 0)},
+			day:       [2]time.Time{at(2024, time.December, 31, 0, 0), at(2025, time.January, 1, 0, 0)},
+			month:     [2]time.Time{at(2024, time.December, 1, 0, 0), at(2025, time.January, 1, 0, 0)},
+			hourKey:   "limit:u1:TRANSFER:hour:2024123123",
+			dayKey:    "limit:u1:TRANSFER:day:20241231",
+			monthKey:  "limit:u1:TRANSFER:month:202412",
+		},
+		{
+			name:      "leap day",
+			createdAt: at(2024, time.February, 29, 0, 0),
+			hour:      [2]time.Time{at(2024, time.February, 29, 0, 0), at(2024, time.February, 29, 1, 0)},
+			day:       [2]time.Time{at(2024, time.February, 29, 0, 0), at(2024, time.March, 1, 0, 0)},
+			month:     [2]time.Time{at(2024, time.February, 1, 0, 0), at(2024, time.March, 1, 0, 0)},
+			hourKey:   "limit:u1:TRANSFER:hour:2024022900",
+			dayKey:    "limit:u1:TRANSFER:day:20240229",
+			monthKey:  "limit:u1:TRANSFER:month:202402",
+		},
+		{
+			name:      "UTC time late in the local day",
+			createdAt: time.Date(2024, time.January, 31, 17, 5, 0, 0, time.UTC),
+			hour:      [2]time.Time{at(2024, time.February, 1, 0, 0), at(2024, time.February, 1, 1, 0)},
+			day:       [2]time.Time{at(2024, time.February, 1, 0, 0), at(2024, time.February, 2, 0, 0)},
+			month:     [2]time.Time{at(2024, time.February, 1, 0, 0), at(2024, time.March, 1, 0, 0)},
+			hourKey:   "limit:u1:TRANSFER:hour:2024020100",
+			dayKey:    "limit:u1:TRANSFER:day:20240201",
+			monthKey:  "limit:u1:TRANSFER:month:202402",
+		},
+	}
+
+	rule := entity.LimitRule{
+		DailyAmount:   entity.NewMoney(100, ""),
+		MonthlyAmount: entity.NewMoney(1000, ""),
+		HourlyCount:   3,
+	}
+	for _, tt := range tests {
+		usage := entity.LimitUsage{UserID: "u1", Kind: entity.TransactionKindTransfer, Amount: entity.NewMoney(40, ""), CreatedAt: tt.createdAt}
+		windows := limitWindows(usage, rule)
+		if len(windows) != 3 {
+			t.Fatalf("%s: %d windows, want 3", tt.name, len(windows))
+		}
+
+		want := map[string]struct {
+			period [2]time.Time
+			key    string
+			max    int64
+			add    int64
+		}{
+			ErrorCodeLimitDailyAmount:   {tt.day, tt.dayKey, 100, 40},
+			ErrorCodeLimitMonthlyAmount: {tt.month, tt.monthKey, 1000, 40},
+			ErrorCodeLimitHourlyCount:   {tt.hour, tt.hourKey, 3, 1},
+		}
+		for _, window := range windows {
+			w, ok := want[window.code]
+			if !ok {
+				t.Errorf("%s: unexpected window %s", tt.name, window.code)
+				continue
+			}
+			if !window.from.Equal(w.period[0]) || !window.to.Equal(w.period[1]) {
+				t.Errorf("%s, %s: window %s to %s, want %s to %s", tt.name, window.code, window.from, window.to, w.period[0], w.period[1])
+			}
+			if window.key != w.key {
+				t.Errorf("%s, %s: key %s, want %s", tt.name, window.code, window.key, w.key)
+			}
+			if window.max != w.max || window.add(usage) != w.add {
+				t.Errorf("%s, %s: max %d add %d, want %d and %d", tt.name, window.code, window.max, window.add(usage), w.max, w.add)
+			}
+		}
+	}
+}
+
+func TestLimitWindowExceeded(t *testing.T) {
+	resetAt := time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)
+	usage := entity.LimitUsage{Kind: entity.TransactionKindPayment, Amount: entity.NewMoney(500, "")}
+
+	amount := limitWindow{code: ErrorCodeLimitDailyAmount, to: resetAt, max: 10000}
+	err := amount.exceeded(usage, 9750)
+	if err.Code != ErrorCodeLimitDailyAmount || err.Limit != "100.00" || err.Remaining != "2.50" || !err.ResetAt.Equal(resetAt) {
+		t.Errorf("daily = %+v", err)
+	}
+
+	// Counters can pass their limit when transactions race, remaining
+	// never goes below zero.
+	if err := amount.exceeded(usage, 10500); err.Remaining != "0.00" {
+		t.Errorf("remaining = %s, want 0.00", err.Remaining)
+	}
+
+	count := limitWindow{code: ErrorCodeLimitHourlyCount, count: true, to: resetAt, max: 5}
+	if err := count.exceeded(usage, 5); err.Limit != "5" || err.Remaining != "0" {
+		t.Errorf("hourly = %+v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+)
+
+const (
+	ErrorCodeAccountLocked   = "ACCOUNT_LOCKED"
+	ErrorCodeTooManyAttempts = "TOO_MANY_ATTEMPTS"
+
+	loginFailureWindow = time.Hour
+	loginLockWindow    = 24 * time.Hour
+	maxLoginDelay      = 30 * time.Second
+	maxLoginLock       = 24 * time.Hour
+)
+
+var (
+	ErrInvalidCredentials = errors.New("Phone Number and PIN doesn't match.")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+// LoginBlockedError is returned while a phone number or client IP has to wait
+// before the next login attempt.
+type LoginBlockedError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Code == ErrorCodeAccountLocked {
+		return fmt.Sprintf("account is locked, try again in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+func phoneSubject(phoneNumber string) string {
+	return "phone:" + phoneNumber
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// loginAttempt is a login or PIN check, counted as a failure against the
+// phone number and the client IP before the PIN is compared.
+type loginAttempt struct {
+	phoneNumber string
+	ip          string
+	// failures holds the failure count of each subject including this
+	// attempt.
+	failures map[string]int64
+}
+
+// startLoginAttempt rejects the attempt while the phone number or the client
+// IP is delayed or locked, and otherwise counts it as a failure. Counting
+// first means parallel attempts each see their own count, so no more than the
+// configured number are checked before the lock.
+func (s *authService) startLoginAttempt(phoneNumber, ip string) (*loginAttempt, error) {
+	subjects := []string{phoneSubject(phoneNumber)}
+	if ip != "" {
+		subjects = append(subjects, ipSubject(ip))
+	}
+
+	for _, subject := range subjects {
+		reason, remaining, err := s.loginAttemptRepository.Blocked(subject)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, &LoginBlockedError{Code: reason, RetryAfter: remaining}
+		}
+	}
+
+	attempt := &loginAttempt{phoneNumber: phoneNumber, ip: ip, failures: make(map[string]int64)}
+	for _, subject := range subjects {
+		failures, err := s.loginAttemptRepository.RecordFailure(subject, loginFailureWindow)
+		if err != nil {
+			return nil, err
+		}
+		attempt.failures[subject] = failures
+
+		// Attempts racing the one that locks the subject.
+		if failures > int64(s.maxLoginAttempts(attempt, subject)) {
+			return nil, &LoginBlockedError{
+				Code:       s.lockCode(attempt, subject),
+				RetryAfter: time.Duration(s.config.LoginLockMinutes) * time.Minute,
+			}
+		}
+	}
+
+	return attempt, nil
+}
+
+func (s *authService) maxLoginAttempts(attempt *loginAttempt, subject string) int {
+	if subject == phoneSubject(attempt.phoneNumber) {
+		return s.config.LoginMaxAttempts
+	}
+	return s.config.LoginMaxAttemptsPerIP
+}
+
+func (s *authService) lockCode(attempt *loginAttempt, subject string) string {
+	if subject == phoneSubject(attempt.phoneNumber) {
+		return ErrorCodeAccountLocked
+	}
+	return ErrorCodeTooManyAttempts
+}
+
+// failLoginAttempt settles a failed attempt, which startLoginAttempt already
+// counted. From the second failure on, each attempt has to wait twice as long
+// as the previous one; after the configured number of failures the subject
+// is locked, and every further lock within a day lasts twice as long.
+func (s *authService) failLoginAttempt(attempt *loginAttempt, userID string) error {
+	var blocked error
+
+	phoneNumber, ip := attempt.phoneNumber, attempt.ip
+	for subject, failures := range attempt.failures {
+		if failures >= int64(s.maxLoginAttempts(attempt, subject)) {
+			locks, err := s.loginAttemptRepository.IncrementLocks(subject, loginLockWindow)
+			if err != nil {
+				return err
+			}
+
+			duration := backoff(time.Duration(s.config.LoginLockMinutes)*time.Minute, locks-1, maxLoginLock)
+			code := s.lockCode(attempt, subject)
+			if err := s.loginAttemptRepository.Block(subject, code, duration); err != nil {
+				return err
+			}
+			if err := s.loginAttemptRepository.ResetFailures(subject); err != nil {
+				return err
+			}
+
+			s.auditLogger.Log(entity.AuditEvent{
+				Type:        entity.AuditEventLoginLocked,
+				UserID:      userID,
+				PhoneNumber: phoneNumber,
+				IPAddress:   ip,
+				Details: map[string]interface{}{
+					"subject":         subject,
+					"failures":        failures,
+					"locked_for_secs": int64(duration.Seconds()),
+					"lock_count":      locks,
+				},
+				CreatedAt: time.Now(),
+			})
+
+			blocked = &LoginBlockedError{Code: code, RetryAfter: duration}
+			continue
+		}
+
+		if failures >= 2 {
+			delay := backoff(time.Second, failures-2, maxLoginDelay)
+			if err := s.loginAttemptRepository.Block(subject, ErrorCodeTooManyAttempts, delay); err != nil {
+				return err
+			}
+		}
+	}
+
+	if blocked != nil {
+		return blocked
+	}
+	return ErrInvalidCredentials
+}
+
+// passLoginAttempt settles an attempt with the right PIN: the failures of the
+// phone number are cleared and the attempt no longer counts against the IP.
+func (s *authService) passLoginAttempt(attempt *loginAttempt) error {
+	if err := s.loginAttemptRepository.ResetFailures(phoneSubject(attempt.phoneNumber)); err != nil {
+		return err
+	}
+	if attempt.ip == "" {
+		return nil
+	}
+	return s.loginAttemptRepository.ForgiveFailure(ipSubject(attempt.ip))
+}
+
+func (s *authService) UnlockLogin(userID string) error {
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	subject := phoneSubject(user.PhoneNumber)
+	if err := s.loginAttemptRepository.Unblock(subject); err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepository.ResetFailures(subject); err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepository.ResetLocks(subject); err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepository.Unblock(mfaSubject(user.UserID)); err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepository.ResetFailures(mfaSubject(user.UserID)); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventLoginUnlocked,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		CreatedAt:   time.Now(),
+	})
+
+	return nil
+}
+
+// backoff returns base doubled exponent times, capped at max.
+func backoff(base time.Duration, exponent int64, max time.Duration) time.Duration {
+	if exponent < 0 {
+		exponent = 0
+	}
+	factor := math.Pow(2, float64(exponent))
+	if factor >= float64(max/base) {
+		return max
+	}
+	return base * time.Duration(factor)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+)
+
+func newAttemptTestService(attempts *memoryAttempts) *authService {
+	return &authService{
+		config:                 &config.Config{LoginMaxAttempts: 3, LoginMaxAttemptsPerIP: 10, LoginLockMinutes: 15},
+		loginAttemptRepository: attempts,
+		auditLogger:            audit.NewLogLogger(),
+	}
+}
+
+func TestParallelLoginAttemptsStopAtTheLimit(t *testing.T) {
+	attempts := newMemoryAttempts()
+	svc := newAttemptTestService(attempts)
+
+	// Every attempt starts before any of them fails, as when they are sent
+	// at once.
+	var started []*loginAttempt
+	for i := 0; i < 5; i++ {
+		attempt, err := svc.startLoginAttempt("+628111", "10.0.0.1")
+		if i < 3 {
+			if err != nil {
+				t.Fatalf("attempt %d: %v", i+1, err)
+			}
+			started = append(started, attempt)
+			continue
+		}
+		var blocked *LoginBlockedError
+		if !errors.As(err, &blocked) || blocked.Code != ErrorCodeAccountLocked {
+			t.Errorf("attempt %d = %v, want ACCOUNT_LOCKED", i+1, err)
+		}
+	}
+
+	for i, attempt := range started[:2] {
+		if err := svc.failLoginAttempt(attempt, "u1"); err != ErrInvalidCredentials {
+			t.Errorf("failure %d = %v", i+1, err)
+		}
+	}
+	var blocked *LoginBlockedError
+	if err := svc.failLoginAttempt(started[2], "u1"); !errors.As(err, &blocked) || blocked.Code != ErrorCodeAccountLocked {
+		t.Errorf("third failure = %v, want ACCOUNT_LOCKED", err)
+	}
+	if attempts.blocks[phoneSubject("+628111")] != ErrorCodeAccountLocked {
+		t.Errorf("phone number is not locked: %v", attempts.blocks)
+	}
+	if attempts.locks[phoneSubject("+628111")] != 1 {
+		t.Errorf("locked %d times, want once", attempts.locks[phoneSubject("+628111")])
+	}
+	if _, err := svc.startLoginAttempt("+628111", "10.0.0.1"); !errors.As(err, &blocked) {
+		t.Errorf("attempt while locked = %v", err)
+	}
+}
+
+func TestPassedLoginAttemptIsNotCounted(t *testing.T) {
+	attempts := newMemoryAttempts()
+	svc := newAttemptTestService(attempts)
+
+	failed, err := svc.startLoginAttempt("+628111", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.failLoginAttempt(failed, "u1"); err != ErrInvalidCredentials {
+		t.Fatalf("failure = %v", err)
+	}
+
+	passed, err := svc.startLoginAttempt("+628222", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.passLoginAttempt(passed); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := attempts.failures[ipSubject("10.0.0.1")]; got != 1 {
+		t.Errorf("IP failures = %d, want 1", got)
+	}
+	if got := attempts.failures[phoneSubject("+628222")]; got != 0 {
+		t.Errorf("phone failures after a login = %d", got)
+	}
+}
+
+func TestSecondFailureDelaysTheNextAttempt(t *testing.T) {
+	attempts := newMemoryAttempts()
+	svc := newAttemptTestService(attempts)
+
+	for i := 0; i < 2; i++ {
+		attempt, err := svc.startLoginAttempt("+628111", "")
+		if err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+		if err := svc.failLoginAttempt(attempt, "u1"); err != ErrInvalidCredentials {
+			t.Fatalf("failure %d = %v", i+1, err)
+		}
+	}
+
+	var blocked *LoginBlockedError
+	if _, err := svc.startLoginAttempt("+628111", ""); !errors.As(err, &blocked) || blocked.Code != ErrorCodeTooManyAttempts {
+		t.Errorf("attempt after two failures = %v, want TOO_MANY_ATTEMPTS", err)
+	}
+}
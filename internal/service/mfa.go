@@ -0,0 +1,306 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/totp"
+)
+
+const (
+	ErrorCodeMFARequired = "MFA_REQUIRED"
+
+	recoveryCodeCount = 10
+	totpSkew          = 1
+)
+
+var (
+	ErrMFARequired        = errors.New("two-factor code required")
+	ErrInvalidMFACode     = errors.New("invalid two-factor code")
+	ErrTOTPAlreadyEnabled = errors.New("TOTP is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("TOTP enrolment was not started")
+	ErrTOTPNotEnabled     = errors.New("TOTP is not enabled")
+)
+
+type IMFAService interface {
+	// EnrollTOTP creates a new secret. It only takes effect once ActivateTOTP
+	// confirmed a code generated from it.
+	EnrollTOTP(userID string) (*entity.TOTPEnrollResponse, error)
+
+	// ActivateTOTP enables TOTP and returns one-time recovery codes. The codes
+	// are shown only here; just their hashes are stored.
+	ActivateTOTP(req *entity.TOTPCodeRequest) (*entity.TOTPActivateResponse, error)
+	// DisableTOTP turns TOTP off after checking a code. Callers check the PIN
+	// first, see IAuthService.DisableTOTP.
+	DisableTOTP(req *entity.TOTPCodeRequest) error
+
+	// VerifyCode accepts a TOTP code or an unused recovery code of a user with
+	// TOTP enabled. Each code is accepted once. Attempts are counted per user
+	// and too many wrong codes lock the second factor for
+	// LOGIN_LOCK_MINUTES.
+	VerifyCode(user *entity.User, code string) error
+
+	// RequireStepUp verifies code when the user has TOTP enabled and amount
+	// reaches MFA_THRESHOLD_AMOUNT.
+	RequireStepUp(userID string, amount entity.Money, code string) error
+}
+
+type mfaService struct {
+	config                 *config.Config
+	userRepository         repository.IUserRepository
+	mfaRepository          repository.IMFARepository
+	loginAttemptRepository repository.ILoginAttemptRepository
+	auditLogger            audit.Logger
+}
+
+func NewMFAService(config *config.Config, userRepo repository.IUserRepository, mfaRepo repository.IMFARepository, loginAttemptRepo repository.ILoginAttemptRepository, auditLogger audit.Logger) IMFAService {
+	return &mfaService{
+		config:                 config,
+		userRepository:         userRepo,
+		mfaRepository:          mfaRepo,
+		loginAttemptRepository: loginAttemptRepo,
+		auditLogger:            auditLogger,
+	}
+}
+
+func mfaSubject(userID string) string {
+	return "mfa:" + userID
+}
+
+func (s *mfaService) EnrollTOTP(userID string) (*entity.TOTPEnrollResponse, error) {
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepository.SaveTOTPSecret(user.UserID, secret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &entity.TOTPEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.TOTPIssuer, user.PhoneNumber, secret),
+	}, nil
+}
+
+func (s *mfaService) ActivateTOTP(req *entity.TOTPCodeRequest) (*entity.TOTPActivateResponse, error) {
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if err := s.verifyTOTP(user, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(user.UserID, codes[i])
+	}
+
+	now := time.Now()
+	if err := s.mfaRepository.EnableTOTP(user.UserID, hashes, now); err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventMFAEnabled,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		CreatedAt:   now,
+	})
+
+	return &entity.TOTPActivateResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *mfaService) DisableTOTP(req *entity.TOTPCodeRequest) error {
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := s.VerifyCode(user, req.Code); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.mfaRepository.DisableTOTP(user.UserID, now); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventMFADisabled,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		CreatedAt:   now,
+	})
+
+	return nil
+}
+
+func (s *mfaService) VerifyCode(user *entity.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	if code == "" {
+		return ErrMFARequired
+	}
+
+	if err := s.countAttempt(user); err != nil {
+		return err
+	}
+	if err := s.checkCode(user, code); err != nil {
+		return err
+	}
+	return s.loginAttemptRepository.ResetFailures(mfaSubject(user.UserID))
+}
+
+func (s *mfaService) checkCode(user *entity.User, code string) error {
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(user, code)
+	}
+
+	used, err := s.mfaRepository.UseRecoveryCode(user.UserID, hashRecoveryCode(user.UserID, code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// countAttempt counts a code attempt before it is checked, so parallel
+// guesses cannot all pass a check made before any failure is recorded. A
+// correct code clears the count; the attempt after LOGIN_MAX_ATTEMPTS wrong
+// ones locks the second factor of the user.
+func (s *mfaService) countAttempt(user *entity.User) error {
+	subject := mfaSubject(user.UserID)
+	reason, remaining, err := s.loginAttemptRepository.Blocked(subject)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return &LoginBlockedError{Code: reason, RetryAfter: remaining}
+	}
+
+	attempts, err := s.loginAttemptRepository.RecordFailure(subject, loginFailureWindow)
+	if err != nil {
+		return err
+	}
+	if attempts <= int64(s.config.LoginMaxAttempts) {
+		return nil
+	}
+
+	duration := time.Duration(s.config.LoginLockMinutes) * time.Minute
+	if err := s.loginAttemptRepository.Block(subject, ErrorCodeTooManyAttempts, duration); err != nil {
+		return err
+	}
+	if err := s.loginAttemptRepository.ResetFailures(subject); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventLoginLocked,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		Details: map[string]interface{}{
+			"subject":         subject,
+			"failures":        attempts - 1,
+			"locked_for_secs": int64(duration.Seconds()),
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return &LoginBlockedError{Code: ErrorCodeTooManyAttempts, RetryAfter: duration}
+}
+
+func (s *mfaService) RequireStepUp(userID string, amount entity.Money, code string) error {
+	if s.config.MFAThresholdAmount == "" {
+		return nil
+	}
+
+	threshold, err := entity.ParseMoney(s.config.MFAThresholdAmount, amount.Currency)
+	if err != nil {
+		return err
+	}
+	if below, err := amount.LessThan(threshold); err == nil && below {
+		return nil
+	}
+
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return nil
+	}
+
+	return s.VerifyCode(user, code)
+}
+
+func (s *mfaService) verifyTOTP(user *entity.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.mfaRepository.UseTOTPStep(user.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// generateRecoveryCode returns a code such as "3f9a1-c07be".
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	code := hex.EncodeToString(raw)
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode ignores case and dashes so codes can be typed loosely. The
+// user id salts the hash so one precomputed table cannot cover every user.
+func hashRecoveryCode(userID, code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(userID + ":" + normalized))
+	return hex.EncodeToString(sum[:])
+}
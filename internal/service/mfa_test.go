@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/totp"
+)
+
+// memoryAttempts keeps login attempt counters in memory. Counters and blocks
+// do not expire.
+type memoryAttempts struct {
+	failures map[string]int64
+	locks    map[string]int64
+	blocks   map[string]string
+}
+
+func newMemoryAttempts() *memoryAttempts {
+	return &memoryAttempts{failures: map[string]int64{}, locks: map[string]int64{}, blocks: map[string]string{}}
+}
+
+func (m *memoryAttempts) RecordFailure(subject string, window time.Duration) (int64, error) {
+	m.failures[subject]++
+	return m.failures[subject], nil
+}
+
+func (m *memoryAttempts) ForgiveFailure(subject string) error {
+	if m.failures[subject] > 0 {
+		m.failures[subject]--
+	}
+	return nil
+}
+
+func (m *memoryAttempts) ResetFailures(subject string) error {
+	delete(m.failures, subject)
+	return nil
+}
+
+func (m *memoryAttempts) Block(subject, reason string, ttl time.Duration) error {
+	m.blocks[subject] = reason
+	return nil
+}
+
+func (m *memoryAttempts) Blocked(subject string) (string, time.Duration, error) {
+	if reason, ok := m.blocks[subject]; ok {
+		return reason, time.Minute, nil
+	}
+	return "", 0, nil
+}
+
+func (m *memoryAttempts) Unblock(subject string) error {
+	delete(m.blocks, subject)
+	return nil
+}
+
+func (m *memoryAttempts) IncrementLocks(subject string, window time.Duration) (int64, error) {
+	m.locks[subject]++
+	return m.locks[subject], nil
+}
+
+func (m *memoryAttempts) ResetLocks(subject string) error {
+	delete(m.locks, subject)
+	return nil
+}
+
+// memoryMFA accepts every TOTP step once and the recovery codes it holds.
+type memoryMFA struct {
+	repository.IMFARepository
+	lastStep      int64
+	recoveryCodes map[string]bool
+}
+
+func (m *memoryMFA) UseTOTPStep(userID string, step int64) (bool, error) {
+	if step <= m.lastStep {
+		return false, nil
+	}
+	m.lastStep = step
+	return true, nil
+}
+
+func (m *memoryMFA) UseRecoveryCode(userID, codeHash string, usedAt time.Time) (bool, error) {
+	if !m.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(m.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func TestVerifyCodeLocksAfterWrongCodes(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &entity.User{UserID: "u1", TOTPEnabled: true, TOTPSecret: secret}
+	attempts := newMemoryAttempts()
+	svc := NewMFAService(&config.Config{LoginMaxAttempts: 3, LoginLockMinutes: 15}, nil,
+		&memoryMFA{recoveryCodes: map[string]bool{hashRecoveryCode("u1", "3f9a1-c07be"): true}},
+		attempts, audit.NewLogLogger())
+
+	if err := svc.VerifyCode(user, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("wrong code = %v", err)
+	}
+	if err := svc.VerifyCode(user, "3F9A1C07BE"); err != nil {
+		t.Fatalf("recovery code = %v", err)
+	}
+	if attempts.failures[mfaSubject("u1")] != 0 {
+		t.Errorf("a correct code left %d attempts counted", attempts.failures[mfaSubject("u1")])
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := svc.VerifyCode(user, "bad-code"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d = %v", i+1, err)
+		}
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocked *LoginBlockedError
+	if err := svc.VerifyCode(user, code); !errors.As(err, &blocked) || blocked.Code != ErrorCodeTooManyAttempts {
+		t.Fatalf("code after 3 wrong ones = %v, want TOO_MANY_ATTEMPTS", err)
+	}
+	if err := svc.VerifyCode(user, code); !errors.As(err, &blocked) {
+		t.Errorf("code while locked = %v", err)
+	}
+
+	attempts.Unblock(mfaSubject("u1"))
+	if err := svc.VerifyCode(user, code); err != nil {
+		t.Errorf("code after the lock = %v", err)
+	}
+}
+
+func TestVerifyCodeWithoutCode(t *testing.T) {
+	attempts := newMemoryAttempts()
+	svc := NewMFAService(&config.Config{LoginMaxAttempts: 3}, nil, &memoryMFA{}, attempts, audit.NewLogLogger())
+
+	if err := svc.VerifyCode(&entity.User{UserID: "u1", TOTPEnabled: true}, ""); !errors.Is(err, ErrMFARequired) {
+		t.Errorf("missing code = %v", err)
+	}
+	if err := svc.VerifyCode(&entity.User{UserID: "u1"}, "123456"); !errors.Is(err, ErrTOTPNotEnabled) {
+		t.Errorf("TOTP off = %v", err)
+	}
+	if len(attempts.failures) != 0 {
+		t.Errorf("attempts counted without a code: %v", attempts.failures)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/sms"
+)
+
+const (
+	otpDigits         = 6
+	maxOTPAttempts    = 5
+	otpRequestsWindow = 15 * time.Minute
+)
+
+var (
+	ErrInvalidOTP     = errors.New("invalid or expired code")
+	ErrOTPRateLimited = errors.New("too many codes requested, try again later")
+)
+
+type IOTPService interface {
+	// Send generates a code for purpose and texts it to phoneNumber. Only a
+	// hash of the code is stored.
+	Send(purpose, phoneNumber string) error
+
+	// Verify checks and consumes the pending code for purpose. A code is
+	// discarded after too many wrong guesses.
+	Verify(purpose, phoneNumber, code string) error
+}
+
+type otpService struct {
+	config        *config.Config
+	otpRepository repository.IOTPRepository
+	smsSender     sms.Sender
+}
+
+func NewOTPService(config *config.Config, otpRepo repository.IOTPRepository, smsSender sms.Sender) IOTPService {
+	return &otpService{
+		config:        config,
+		otpRepository: otpRepo,
+		smsSender:     smsSender,
+	}
+}
+
+func (s *otpService) Send(purpose, phoneNumber string) error {
+	requests, err := s.otpRepository.IncrementRequests(purpose, phoneNumber, otpRequestsWindow)
+	if err != nil {
+		return err
+	}
+	if requests > int64(s.config.OTPMaxRequests) {
+		return ErrOTPRateLimited
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(s.config.OTPTTLMinutes) * time.Minute
+	record := entity.OTPRecord{Hash: s.hash(purpose, phoneNumber, code)}
+	if err := s.otpRepository.Save(purpose, phoneNumber, record, ttl); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your e-wallet code is %s. It expires in %d minutes. Never share it with anyone.", code, s.config.OTPTTLMinutes)
+	return s.smsSender.Send(phoneNumber, message)
+}
+
+func (s *otpService) Verify(purpose, phoneNumber, code string) error {
+	ok, err := s.otpRepository.Consume(purpose, phoneNumber, s.hash(purpose, phoneNumber, code), maxOTPAttempts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+// hash binds the code to its purpose and phone number so a code issued for
+// one flow cannot be replayed in another.
+func (s *otpService) hash(purpose, phoneNumber, code string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.JWTSecret))
+	mac.Write([]byte(purpose + ":" + phoneNumber + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/utils"
+)
+
+const pinLength = 6
+
+var (
+	ErrInvalidPinFormat = errors.New("PIN must be exactly 6 digits")
+	ErrWrongPin         = errors.New("PIN doesn't match.")
+	ErrSamePin          = errors.New("new PIN must be different from the old PIN")
+)
+
+func validatePin(pin string) error {
+	if len(pin) != pinLength {
+		return ErrInvalidPinFormat
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return ErrInvalidPinFormat
+		}
+	}
+	return nil
+}
+
+func (s *authService) ChangePin(req *entity.ChangePinRequest) error {
+	if err := validatePin(req.NewPin); err != nil {
+		return err
+	}
+	if req.NewPin == req.OldPin {
+		return ErrSamePin
+	}
+
+	user, err := s.verifyPin(req.UserID, req.OldPin, req.IPAddress)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPin(user, req.NewPin); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventPinChanged,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		CreatedAt:   time.Now(),
+	})
+
+	return nil
+}
+
+func (s *authService) VerifyPin(userID, pin, ip string) error {
+	_, err := s.verifyPin(userID, pin, ip)
+	return err
+}
+
+func (s *authService) DisableTOTP(req *entity.DisableTOTPRequest) error {
+	if _, err := s.verifyPin(req.UserID, req.Pin, req.IPAddress); err != nil {
+		return err
+	}
+	return s.mfaService.DisableTOTP(&entity.TOTPCodeRequest{Code: req.Code, UserID: req.UserID})
+}
+
+// verifyPin checks the PIN of a logged in user. Guessing it here is as good
+// as guessing it at login, so failures count towards the same lockout.
+func (s *authService) verifyPin(userID, pin, ip string) (*entity.User, error) {
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	attempt, err := s.startLoginAttempt(user.PhoneNumber, ip)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPinHash(pin, user.Pin) {
+		if err := s.failLoginAttempt(attempt, user.UserID); err != ErrInvalidCredentials {
+			return nil, err
+		}
+		return nil, ErrWrongPin
+	}
+	if err := s.passLoginAttempt(attempt); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// RequestPinReset texts a reset code to the phone number. Unknown numbers are
+// accepted silently so the endpoint cannot be used to find registered users.
+func (s *authService) RequestPinReset(req *entity.PinResetRequest) error {
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil || user == nil {
+		return nil
+	}
+
+	return s.otpService.Send(entity.OTPPurposePinReset, user.PhoneNumber)
+}
+
+// ConfirmPinReset sets a new PIN after checking the reset code, then revokes
+// every session of the user and lifts any login lock.
+func (s *authService) ConfirmPinReset(req *entity.PinResetConfirmRequest) error {
+	if err := validatePin(req.NewPin); err != nil {
+		return err
+	}
+
+	phoneNumber, err := utils.NormalizePhoneNumber(req.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return ErrInvalidOTP
+	}
+
+	if err := s.otpService.Verify(entity.OTPPurposePinReset, phoneNumber, req.Code); err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil || user == nil {
+		return ErrInvalidOTP
+	}
+
+	hashedPin, err := utils.HashPin(req.NewPin)
+	if err != nil {
+		return err
+	}
+	// The reset time is stored with the PIN, the risk rules block transfers
+	// shortly after it.
+	now := time.Now()
+	if err := s.userRepository.ResetPin(user.UserID, hashedPin, now); err != nil {
+		return err
+	}
+	if err := s.RevokeAllSessions(user.UserID); err != nil {
+		return err
+	}
+	if err := s.UnlockLogin(user.UserID); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventPinReset,
+		UserID:      user.UserID,
+		PhoneNumber: user.PhoneNumber,
+		CreatedAt:   now,
+	})
+
+	return nil
+}
+
+func (s *authService) setPin(user *entity.User, pin string) error {
+	hashedPin, err := utils.HashPin(pin)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepository.UpdatePin(user.UserID, hashedPin, time.Now())
+}
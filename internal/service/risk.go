@@ -0,0 +1,120 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/risk"
+)
+
+const ErrorCodeRiskBlocked = "RISK_BLOCKED"
+
+var (
+	ErrRiskDecisionNotFound = errors.New("risk decision not found")
+	ErrTransactionNotHeld   = errors.New("transaction is not waiting for review")
+)
+
+// RiskBlockedError is returned when the risk engine blocked a transaction.
+// The transaction is stored as FAILED, but the rules that hit are not shown
+// to the user.
+type RiskBlockedError struct {
+	TransactionID string
+}
+
+func (e *RiskBlockedError) Error() string {
+	return "transaction was declined"
+}
+
+type IRiskService interface {
+	// Assess gathers the signals for input and returns the decision of the
+	// risk engine. Record must then store it with the transaction.
+	Assess(input *entity.RiskInput) (*entity.RiskDecision, error)
+	Record(tx *sql.Tx, decision *entity.RiskDecision) error
+	MarkReviewed(tx *sql.Tx, req *entity.ReviewTransactionRequest, reviewedAt time.Time) error
+
+	FindDecision(transactionID string) (*entity.RiskDecision, error)
+	FindDecisions(filter entity.RiskDecisionFilter) ([]*entity.RiskDecision, error)
+}
+
+type riskService struct {
+	engine            risk.Engine
+	historyWindow     time.Duration
+	riskRepository    repository.IRiskRepository
+	sessionRepository repository.ISessionRepository
+	ledgerRepository  repository.ILedgerRepository
+	userRepository    repository.IUserRepository
+}
+
+// NewRiskService passes the engine the decisions of the last historyWindow.
+func NewRiskService(engine risk.Engine, historyWindow time.Duration, riskRepo repository.IRiskRepository, sessionRepo repository.ISessionRepository, ledgerRepo repository.ILedgerRepository, userRepo repository.IUserRepository) IRiskService {
+	return &riskService{
+		engine:            engine,
+		historyWindow:     historyWindow,
+		riskRepository:    riskRepo,
+		sessionRepository: sessionRepo,
+		ledgerRepository:  ledgerRepo,
+		userRepository:    userRepo,
+	}
+}
+
+func (s *riskService) Assess(input *entity.RiskInput) (*entity.RiskDecision, error) {
+	if input.SessionID != "" {
+		session, err := s.sessionRepository.FindByID(input.SessionID)
+		if err != nil && err != repository.ErrSessionNotFound {
+			return nil, err
+		}
+		if session != nil {
+			if input.DeviceFirstSeenAt, err = s.sessionRepository.DeviceFirstSeen(input.UserID, session.DeviceID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	user, err := s.userRepository.FindByID(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PinResetAt != nil {
+		input.PinResetAt = *user.PinResetAt
+	}
+
+	if input.Kind == entity.TransactionKindTransfer {
+		known, err := s.ledgerRepository.HasTransferred(input.UserID, input.TargetUser)
+		if err != nil {
+			return nil, err
+		}
+		input.NewRecipient = !known
+	}
+
+	if s.historyWindow > 0 {
+		input.History, err = s.riskRepository.FindRecent(input.UserID, input.CreatedAt.Add(-s.historyWindow))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.engine.Evaluate(input), nil
+}
+
+func (s *riskService) Record(tx *sql.Tx, decision *entity.RiskDecision) error {
+	return s.riskRepository.Save(tx, *decision)
+}
+
+func (s *riskService) MarkReviewed(tx *sql.Tx, req *entity.ReviewTransactionRequest, reviewedAt time.Time) error {
+	return s.riskRepository.UpdateReview(tx, req.TransactionID, req.ActorID, req.Note, reviewedAt)
+}
+
+func (s *riskService) FindDecision(transactionID string) (*entity.RiskDecision, error) {
+	decision, err := s.riskRepository.FindByTransactionID(transactionID)
+	if err == sql.ErrNoRows {
+		return nil, ErrRiskDecisionNotFound
+	}
+	return decision, err
+}
+
+func (s *riskService) FindDecisions(filter entity.RiskDecisionFilter) ([]*entity.RiskDecision, error) {
+	return s.riskRepository.Find(filter)
+}
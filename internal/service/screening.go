@@ -0,0 +1,147 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/screening"
+)
+
+type IScreeningService interface {
+	// Screen returns the watchlist entries that resemble the name of a user,
+	// best first. Entries staff cleared for the user are left out until
+	// their listing changes.
+	Screen(userID, firstName, lastName string) []entity.ScreeningMatch
+
+	// Flag opens a screening case for user over matches and freezes an
+	// ACTIVE account. A user with an open screening case gets no second one.
+	// transactionID, when set, is linked to the case.
+	Flag(user *entity.User, trigger, transactionID string, matches []entity.ScreeningMatch) error
+}
+
+type screeningService struct {
+	store                *screening.Store
+	minScore             float64
+	db                   *sql.DB
+	complianceRepository repository.IComplianceRepository
+	userRepository       repository.IUserRepository
+	walletRepository     repository.IWalletRepository
+	auditLogger          audit.Logger
+}
+
+// NewScreeningService reports names that score at least minScore, from 0 to
+// 1, against the list in store.
+func NewScreeningService(store *screening.Store, minScore float64, db *sql.DB, complianceRepo repository.IComplianceRepository, userRepo repository.IUserRepository, walletRepo repository.IWalletRepository, auditLogger audit.Logger) IScreeningService {
+	return &screeningService{
+		store:                store,
+		minScore:             minScore,
+		db:                   db,
+		complianceRepository: complianceRepo,
+		userRepository:       userRepo,
+		walletRepository:     walletRepo,
+		auditLogger:          auditLogger,
+	}
+}
+
+func (s *screeningService) Screen(userID, firstName, lastName string) []entity.ScreeningMatch {
+	matches := s.store.List().Match(firstName, lastName, s.minScore)
+	if len(matches) == 0 {
+		return matches
+	}
+
+	// Without the clearances every match is reported, so a lookup failure
+	// never lets a listed name through.
+	clearances, err := s.complianceRepository.FindClearances(userID)
+	if err != nil {
+		log.Printf("screening: failed to read clearances of user %s: %v", userID, err)
+		return matches
+	}
+
+	var uncleared []entity.ScreeningMatch
+	for _, match := range matches {
+		if version, ok := clearances[match.EntryID]; ok && version == match.EntryVersion {
+			continue
+		}
+		uncleared = append(uncleared, match)
+	}
+	return uncleared
+}
+
+func (s *screeningService) Flag(user *entity.User, trigger, transactionID string, matches []entity.ScreeningMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var opened, complianceCase *entity.ComplianceCase
+	var frozen bool
+	now := time.Now()
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		opened, complianceCase, frozen = nil, nil, false
+
+		// Freezing locks the wallet first, so concurrent flags of one user
+		// wait here and find the case opened by the first.
+		var err error
+		frozen, err = switchAccountStatus(tx, s.userRepository, s.walletRepository, user.UserID,
+			entity.AccountStatusActive, entity.AccountStatusFrozen, caseFreezeReason+"watchlist screening hit", now)
+		if err != nil {
+			return err
+		}
+
+		complianceCase, err = s.complianceRepository.FindOpen(tx, user.UserID, entity.CaseSourceScreening)
+		if err != nil {
+			return err
+		}
+		if complianceCase == nil {
+			complianceCase = &entity.ComplianceCase{
+				CaseID:    uuid.New().String(),
+				UserID:    user.UserID,
+				Source:    entity.CaseSourceScreening,
+				Trigger:   trigger,
+				Summary:   fmt.Sprintf("Name resembles %d watchlist entries", len(matches)),
+				Status:    entity.CaseStatusOpen,
+				Matches:   matches,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := s.complianceRepository.Create(tx, *complianceCase); err != nil {
+				return err
+			}
+			opened = complianceCase
+		}
+
+		if transactionID != "" {
+			return s.complianceRepository.LinkTransaction(tx, complianceCase.CaseID, transactionID, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if opened != nil {
+		s.auditLogger.Log(entity.AuditEvent{
+			Type:        entity.AuditEventCaseOpened,
+			UserID:      user.UserID,
+			TargetID:    opened.CaseID,
+			PhoneNumber: user.PhoneNumber,
+			Details: map[string]interface{}{
+				"source":         opened.Source,
+				"trigger":        trigger,
+				"transaction_id": transactionID,
+				"matches":        len(matches),
+			},
+			CreatedAt: now,
+		})
+	}
+	if frozen {
+		auditCaseAccountStatus(s.auditLogger, "", user.UserID, complianceCase.CaseID, entity.AccountStatusActive, entity.AccountStatusFrozen, now)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/screening"
+)
+
+// clearanceRepository serves clearances; other compliance calls panic.
+type clearanceRepository struct {
+	repository.IComplianceRepository
+	clearances map[string]string
+	err        error
+}
+
+func (r *clearanceRepository) FindClearances(userID string) (map[string]string, error) {
+	return r.clearances, r.err
+}
+
+func newTestWatchlist(t *testing.T) *screening.Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sdn.csv")
+	list := `173,"ABU ABBAS, Mohammed","individual","SDGT"
+174,"ABU ABBAS, Muhammad","individual","SDGT"
+`
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := screening.NewStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestScreenSkipsClearedEntries(t *testing.T) {
+	store := newTestWatchlist(t)
+	versions := map[string]string{}
+	for _, entry := range store.List().Entries {
+		versions[entry.ID] = entry.Version
+	}
+
+	tests := []struct {
+		name       string
+		clearances map[string]string
+		err        error
+		want       []string
+	}{
+		{name: "nothing cleared", clearances: map[string]string{}, want: []string{"173", "174"}},
+		{name: "one entry cleared", clearances: map[string]string{"173": versions["173"]}, want: []string{"174"}},
+		{name: "both cleared", clearances: versions},
+		{name: "entry relisted since", clearances: map[string]string{"173": "0123456789abcdef", "174": versions["174"]}, want: []string{"173"}},
+		{name: "clearances unavailable", err: errors.New("connection refused"), want: []string{"173", "174"}},
+	}
+
+	for _, tt := range tests {
+		svc := &screeningService{
+			store:                store,
+			minScore:             0.9,
+			complianceRepository: &clearanceRepository{clearances: tt.clearances, err: tt.err},
+		}
+		matches := svc.Screen("u1", "Mohammed", "Abu Abbas")
+		var got []string
+		for _, match := range matches {
+			got = append(got, match.EntryID)
+			if match.EntryVersion != versions[match.EntryID] {
+				t.Errorf("%s: match %s has version %q", tt.name, match.EntryID, match.EntryVersion)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: matches %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		seen := map[string]bool{}
+		for _, id := range got {
+			seen[id] = true
+		}
+		for _, id := range tt.want {
+			if !seen[id] {
+				t.Errorf("%s: matches %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
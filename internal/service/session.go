@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+const ErrorCodeDeviceVerificationRequired = "DEVICE_VERIFICATION_REQUIRED"
+
+var (
+	ErrSessionNotFound            = errors.New("session not found")
+	ErrDeviceVerificationRequired = errors.New("new device, enter the code sent to your phone number")
+)
+
+// checkDevice reports whether the login comes from a device the user never
+// logged in from. With NEW_DEVICE_OTP_REQUIRED such a login first gets an OTP
+// texted and has to be repeated with device_otp.
+func (s *authService) checkDevice(user *entity.User, req *entity.LoginRequest) (newDevice bool, err error) {
+	if req.DeviceID != "" {
+		known, err := s.sessionRepository.HasDevice(user.UserID, req.DeviceID)
+		if err != nil {
+			return false, err
+		}
+		if known {
+			return false, nil
+		}
+	}
+
+	if !s.config.NewDeviceOTPRequired {
+		return true, nil
+	}
+
+	if req.DeviceOTP == "" {
+		err := s.otpService.Send(entity.OTPPurposeNewDevice, user.PhoneNumber)
+		if err != nil && err != ErrOTPRateLimited {
+			return false, err
+		}
+		return false, ErrDeviceVerificationRequired
+	}
+
+	if err := s.otpService.Verify(entity.OTPPurposeNewDevice, user.PhoneNumber, req.DeviceOTP); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// notifyNewDevice tells the user about a login from a new device. A failed
+// text does not fail the login.
+func (s *authService) notifyNewDevice(user *entity.User, req *entity.LoginRequest) {
+	device := req.DeviceName
+	if device == "" {
+		device = "an unknown device"
+	}
+	if req.Platform != "" {
+		device += " (" + req.Platform + ")"
+	}
+
+	message := fmt.Sprintf("New login to your e-wallet from %s at %s. If this was not you, reset your PIN now.", device, time.Now().Format("2006-01-02 15:04 MST"))
+	if err := s.smsSender.Send(user.PhoneNumber, message); err != nil {
+		log.Printf("failed to send new device notification to user %s: %v", user.UserID, err)
+	}
+}
+
+func (s *authService) ListSessions(userID, currentSessionID string) ([]*entity.Session, error) {
+	sessions, err := s.sessionRepository.FindActiveByUserID(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.SessionID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (s *authService) RevokeSession(userID, sessionID string) error {
+	session, err := s.sessionRepository.FindByID(sessionID)
+	if err == repository.ErrSessionNotFound || (err == nil && session.UserID != userID) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.revokeSession(sessionID)
+}
+
+// revokeSession ends a session: its refresh token stops working at once and
+// its access tokens are denylisted for as long as they could still be valid.
+func (s *authService) revokeSession(sessionID string) error {
+	if err := s.sessionRepository.Revoke(sessionID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.tokenRepository.RevokeRefreshFamily(sessionID); err != nil {
+		return err
+	}
+
+	return s.tokenRepository.RevokeSession(sessionID, accessTokenTTL)
+}
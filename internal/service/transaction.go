@@ -1,26 +1,71 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
 	"github.com/leonardoong/e-wallet/internal/domain/entity"
 	"github.com/leonardoong/e-wallet/internal/repository"
 )
 
+var (
+	ErrSelfTransfer        = errors.New("Cannot transfer to your own wallet")
+	ErrTransactionNotFound = errors.New("Transaction not found")
+	ErrTargetBalanceCap    = errors.New("Target user cannot receive this amount")
+	ErrReviewNoteRequired  = errors.New("note is required when rejecting")
+	ErrSelfReview          = errors.New("staff cannot review their own transaction")
+	ErrTransactionInCase   = errors.New("transaction is linked to an open compliance case; it is released by closing the case")
+)
+
+// TransactionFailedError means a transaction broke a business rule while being
+// applied, e.g. the wallet no longer covers a payment. It is final: workers
+// record the reason and must not retry the job.
+type TransactionFailedError struct {
+	TransactionID string
+	Reason        string
+}
+
+func newTransactionFailedError(transactionID, reason string) *TransactionFailedError {
+	return &TransactionFailedError{TransactionID: transactionID, Reason: reason}
+}
+
+func (e *TransactionFailedError) Error() string {
+	return fmt.Sprintf("transaction %s failed: %s", e.TransactionID, e.Reason)
+}
+
+const (
+	maxTransactionWait      = 60 * time.Second
+	transactionPollInterval = 500 * time.Millisecond
+)
+
 type ITransactionService interface {
 	StartTopUp(req *entity.PublishTopUpRequest) (string, error)
 	StartPayment(req *entity.PaymentRequest) (string, error)
-	StartTransfer(req *entity.TransferRequest)  (*entity.StartTransferResponse, error)
+	StartTransfer(req *entity.TransferRequest) (*entity.StartTransferResponse, error)
 
 	ProcessTopUp(req entity.PublishTopUpRequest) error
 	ProcessPayment(req entity.PaymentRequest) error
 	ProcessTransfer(req entity.TransferRequest) error
+	FailTransaction(transactionID string, reason string) error
+	ReopenTransaction(transactionID string) (reopened bool, err error)
+
+	// ReleaseTransaction queues a transaction held IN_REVIEW by the risk
+	// engine, unless an open compliance case links it. RejectTransaction
+	// fails it with RISK_REJECTED and needs a note.
+	ReleaseTransaction(req *entity.ReviewTransactionRequest) error
+	RejectTransaction(req *entity.ReviewTransactionRequest) error
 
 	FindTransactionByID(transactionID string) (*entity.Transaction, error)
+	FindUserTransactionByID(userID, transactionID string) (*entity.Transaction, error)
+	WaitForTransaction(ctx context.Context, userID, transactionID string, timeout time.Duration) (*entity.Transaction, error)
 	FindTransactionsByUserID(userID string) ([]*entity.Transaction, error)
 }
 
@@ -29,24 +74,65 @@ type transactionService struct {
 	db                    *sql.DB
 	transactionRepository repository.ITransactionRepository
 	walletRepository      repository.IWalletRepository
-	userRepository repository.IUserRepository
+	userRepository        repository.IUserRepository
+	ledgerService         ILedgerService
+	mfaService            IMFAService
+	confirmationService   IConfirmationService
+	limitService          ILimitService
+	screeningService      IScreeningService
+	riskService           IRiskService
+	complianceRepository  repository.IComplianceRepository
+	auditLogger           audit.Logger
 }
 
-func NewTransactionService(config *config.Config, 
-	dbConn *sql.DB, 
-	transactionRepo repository.ITransactionRepository, 
+func NewTransactionService(config *config.Config,
+	dbConn *sql.DB,
+	transactionRepo repository.ITransactionRepository,
 	walletRepo repository.IWalletRepository,
-	userRepository repository.IUserRepository) ITransactionService {
+	userRepository repository.IUserRepository,
+	ledgerService ILedgerService,
+	mfaService IMFAService,
+	confirmationService IConfirmationService,
+	limitService ILimitService,
+	screeningService IScreeningService,
+	riskService IRiskService,
+	complianceRepo repository.IComplianceRepository,
+	auditLogger audit.Logger) ITransactionService {
 	return &transactionService{
 		config:                config,
 		db:                    dbConn,
 		transactionRepository: transactionRepo,
 		walletRepository:      walletRepo,
-		userRepository: userRepository,
+		userRepository:        userRepository,
+		ledgerService:         ledgerService,
+		mfaService:            mfaService,
+		confirmationService:   confirmationService,
+		limitService:          limitService,
+		screeningService:      screeningService,
+		riskService:           riskService,
+		complianceRepository:  complianceRepo,
+		auditLogger:           auditLogger,
 	}
 }
 
 func (s *transactionService) StartTopUp(req *entity.PublishTopUpRequest) (string, error) {
+	if !req.Amount.IsPositive() {
+		return "", ErrInvalidAmount
+	}
+
+	user, err := s.checkAccountStatus(req.UserID, false)
+	if err != nil {
+		return "", err
+	}
+
+	currentBalance, err := s.walletRepository.GetCurrentBalance(req.UserID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.limitService.CheckBalanceCap(user.Tier, currentBalance, req.Amount); err != nil {
+		return "", err
+	}
+
 	topUpUuid := uuid.New().String()
 
 	payload := entity.PublishTopUpRequest{
@@ -55,134 +141,264 @@ func (s *transactionService) StartTopUp(req *entity.PublishTopUpRequest) (string
 		UserID:  req.UserID,
 	}
 
-	s.transactionRepository.PublishTopUp(payload)
-	return topUpUuid, nil
-}
-
-func (s *transactionService) ProcessTopUp(req entity.PublishTopUpRequest) error {
-	balanceBefore, err := s.walletRepository.GetCurrentBalance(req.UserID)
+	transaction := newPendingTransaction(topUpUuid, req.UserID, entity.TransactionTypeCredit, req.Amount, "")
+	decision, err := s.assess(transaction, entity.TransactionKindTopUp, "", req.SessionID, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
-	
-	tx, err := s.db.Begin()
+
+	usage := s.limitUsage(topUpUuid, req.UserID, entity.TransactionKindTopUp, req.Amount)
+	err = s.storeStarted(user.Tier, usage, transaction, decision, func(tx *sql.Tx) error {
+		return s.transactionRepository.PublishTopUp(tx, payload)
+	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	defer tx.Rollback()
+	s.auditStart(entity.TransactionKindTopUp, topUpUuid, req.UserID, req.Amount, req.IPAddress, req.UserAgent, map[string]interface{}{
+		"risk_outcome": decision.Outcome,
+	})
 
-	balanceAfter := balanceBefore + req.Amount
+	return topUpUuid, nil
+}
 
-	now := time.Now()
+func (s *transactionService) ProcessTopUp(req entity.PublishTopUpRequest) error {
+	return s.applyTransaction(req.TopUpID, func(tx *sql.Tx, transaction *entity.Transaction) error {
+		if err := validateAmount(req.TopUpID, req.Amount); err != nil {
+			return err
+		}
 
-	topUpTransaction := entity.Transaction{
-		TransactionID: req.TopUpID,
-		UserID:        req.UserID,
-		Type:          "CREDIT",
-		Amount:        req.Amount,
-		Status:        "SUCCESS",
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceAfter,
-		Description:   "",
-		CreatedAt:     now,
-		UpdatedAt:     now,
-	}
+		wallets, err := s.walletRepository.LockWallets(tx, req.UserID)
+		if err != nil {
+			return err
+		}
 
-	err = s.transactionRepository.InsertTransaction(tx, topUpTransaction)
-	if err != nil {
-		return err
-	}
+		wallet, ok := wallets[req.UserID]
+		if !ok {
+			return newTransactionFailedError(req.TopUpID, entity.FailureReasonWalletNotFound)
+		}
+		if !entity.CanReceive(wallet.Status) {
+			return newTransactionFailedError(req.TopUpID, entity.FailureReasonAccountRestricted)
+		}
+		balanceBefore := wallet.Balance
+		balanceAfter, err := balanceBefore.Add(req.Amount)
+		if err != nil {
+			return amountError(req.TopUpID, err)
+		}
+		err = s.checkBalanceCap(req.TopUpID, req.UserID, entity.FailureReasonBalanceCap, balanceBefore, req.Amount)
+		if err != nil {
+			return err
+		}
 
-	err = s.walletRepository.UpdateBalance(tx, req.UserID, balanceAfter, now)
-	if err != nil {
-		return err
-	}
+		now := time.Now()
 
-	tx.Commit()
+		err = s.walletRepository.Credit(tx, req.UserID, req.Amount, now)
+		if err != nil {
+			return err
+		}
 
-	return err
+		transaction.Status = entity.TransactionStatusSuccess
+		transaction.BalanceBefore = balanceBefore
+		transaction.BalanceAfter = balanceAfter
+		transaction.UpdatedAt = now
+
+		err = s.transactionRepository.UpdateTransaction(tx, *transaction)
+		if err != nil {
+			return err
+		}
+
+		return s.ledgerService.PostTopUp(tx, req.TopUpID, req.UserID, req.Amount, now)
+	})
 }
 
 func (s *transactionService) StartPayment(req *entity.PaymentRequest) (string, error) {
+	if !req.Amount.IsPositive() {
+		return "", ErrInvalidAmount
+	}
+
+	user, err := s.checkAccountStatus(req.UserID, true)
+	if err != nil {
+		return "", err
+	}
+
 	currentBalance, err := s.walletRepository.GetCurrentBalance(req.UserID)
 	if err != nil {
 		return "", err
 	}
 
-	if currentBalance < req.Amount {
+	insufficient, err := currentBalance.LessThan(req.Amount)
+	if err != nil {
+		return "", err
+	}
+	if insufficient {
 		return "", fmt.Errorf("Balance is not enough")
 	}
 
+	usage := s.limitUsage("", req.UserID, entity.TransactionKindPayment, req.Amount)
+	if err := s.limitService.Check(user.Tier, usage); err != nil {
+		return "", err
+	}
+
+	// Checked last so a PIN attempt or code is not used up by a request that
+	// fails anyway. The second factor goes first: a missing code must not
+	// burn the confirmation token.
+	confirmation := entity.TransactionConfirmation{
+		UserID: req.UserID,
+		Type:   entity.ConfirmationTypePayment,
+		Amount: req.Amount,
+	}
+	if err := s.mfaService.RequireStepUp(req.UserID, req.Amount, req.OTPCode); err != nil {
+		return "", err
+	}
+	if err := s.confirmationService.Authorize(confirmation, req.Pin, req.ConfirmationToken, req.IPAddress); err != nil {
+		return "", err
+	}
+
 	paymentUuid := uuid.New().String()
 	req.PaymentID = paymentUuid
 
-	err = s.transactionRepository.PublishPayment(*req)
+	transaction := newPendingTransaction(paymentUuid, req.UserID, entity.TransactionTypeDebit, req.Amount, req.Remarks)
+	decision, err := s.assess(transaction, entity.TransactionKindPayment, "", req.SessionID, nil)
 	if err != nil {
 		return "", err
 	}
-	return paymentUuid, nil
-}
 
-func (s *transactionService) ProcessPayment(req entity.PaymentRequest)(err error){
-	balanceBefore, err := s.walletRepository.GetCurrentBalance(req.UserID)
-	if err != nil {
-		return err
-	}
-	
-	tx, err := s.db.Begin()
+	usage.TransactionID = paymentUuid
+	err = s.storeStarted(user.Tier, usage, transaction, decision, func(tx *sql.Tx) error {
+		return s.transactionRepository.PublishPayment(tx, *req)
+	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	defer tx.Rollback()
+	s.auditStart(entity.TransactionKindPayment, paymentUuid, req.UserID, req.Amount, req.IPAddress, req.UserAgent, map[string]interface{}{
+		"remarks":      req.Remarks,
+		"risk_outcome": decision.Outcome,
+	})
 
-	balanceAfter := balanceBefore - req.Amount
+	return paymentUuid, nil
+}
 
-	now := time.Now()
+func (s *transactionService) ProcessPayment(req entity.PaymentRequest) (err error) {
+	return s.applyTransaction(req.PaymentID, func(tx *sql.Tx, transaction *entity.Transaction) error {
+		if err := validateAmount(req.PaymentID, req.Amount); err != nil {
+			return err
+		}
 
-	paymentTransaction := entity.Transaction{
-		TransactionID: req.PaymentID,
-		UserID: req.UserID,
-		Type: "DEBIT",
-		Amount: req.Amount,
-		Status: "SUCCESS",
-		BalanceBefore: balanceBefore,
-		BalanceAfter: balanceAfter,
-		Description: req.Remarks,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
+		wallets, err := s.walletRepository.LockWallets(tx, req.UserID)
+		if err != nil {
+			return err
+		}
 
-	err = s.transactionRepository.InsertTransaction(tx, paymentTransaction)
-	if err != nil {
-		return err
-	}
+		wallet, ok := wallets[req.UserID]
+		if !ok {
+			return newTransactionFailedError(req.PaymentID, entity.FailureReasonWalletNotFound)
+		}
+		if !entity.CanSend(wallet.Status) {
+			return newTransactionFailedError(req.PaymentID, entity.FailureReasonAccountRestricted)
+		}
+		balanceBefore := wallet.Balance
+		balanceAfter, err := balanceBefore.Sub(req.Amount)
+		if err != nil {
+			return amountError(req.PaymentID, err)
+		}
+		if balanceAfter.IsNegative() {
+			return newTransactionFailedError(req.PaymentID, entity.FailureReasonInsufficientFunds)
+		}
 
-	err = s.walletRepository.UpdateBalance(tx, req.UserID, balanceAfter, now)
-	if err != nil {
-		return err
-	}
+		now := time.Now()
 
-	tx.Commit()
+		err = s.walletRepository.Debit(tx, req.UserID, req.Amount, now)
+		if err == repository.ErrInsufficientBalance {
+			return newTransactionFailedError(req.PaymentID, entity.FailureReasonInsufficientFunds)
+		}
+		if err != nil {
+			return err
+		}
 
-	return err
+		transaction.Status = entity.TransactionStatusSuccess
+		transaction.BalanceBefore = balanceBefore
+		transaction.BalanceAfter = balanceAfter
+		transaction.UpdatedAt = now
+
+		err = s.transactionRepository.UpdateTransaction(tx, *transaction)
+		if err != nil {
+			return err
+		}
+
+		return s.ledgerService.PostPayment(tx, req.PaymentID, req.UserID, req.Amount, req.Remarks, now)
+	})
 }
 
 func (s *transactionService) StartTransfer(req *entity.TransferRequest) (*entity.StartTransferResponse, error) {
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+
+	user, err := s.checkAccountStatus(req.UserID, true)
+	if err != nil {
+		return nil, err
+	}
+
 	currentBalance, err := s.walletRepository.GetCurrentBalance(req.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	if currentBalance < req.Amount {
+	insufficient, err := currentBalance.LessThan(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if insufficient {
 		return nil, fmt.Errorf("Balance is not enough")
 	}
 
-	_, err = s.userRepository.FindByID(req.TargetUser)
+	if req.UserID == req.TargetUser {
+		return nil, ErrSelfTransfer
+	}
+
+	targetUser, err := s.userRepository.FindByID(req.TargetUser)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("Target user not found")
-	} else if  err != nil {
+	} else if err != nil {
+		return nil, err
+	}
+	if targetUser.VerificationStatus != entity.UserVerificationStatusVerified {
+		return nil, fmt.Errorf("Target user is not verified")
+	}
+	if !entity.CanReceive(targetUser.Status) {
+		return nil, fmt.Errorf("Target user cannot receive transfers")
+	}
+
+	// The sender is not told how much the target may still receive.
+	targetBalance, err := s.walletRepository.GetCurrentBalance(req.TargetUser)
+	if err != nil {
+		return nil, err
+	}
+	err = s.limitService.CheckBalanceCap(targetUser.Tier, targetBalance, req.Amount)
+	var exceeded *LimitExceededError
+	if errors.As(err, &exceeded) {
+		return nil, ErrTargetBalanceCap
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage := s.limitUsage("", req.UserID, entity.TransactionKindTransfer, req.Amount)
+	if err := s.limitService.Check(user.Tier, usage); err != nil {
+		return nil, err
+	}
+
+	confirmation := entity.TransactionConfirmation{
+		UserID:     req.UserID,
+		Type:       entity.ConfirmationTypeTransfer,
+		Amount:     req.Amount,
+		TargetUser: req.TargetUser,
+	}
+	if err := s.mfaService.RequireStepUp(req.UserID, req.Amount, req.OTPCode); err != nil {
+		return nil, err
+	}
+	if err := s.confirmationService.Authorize(confirmation, req.Pin, req.ConfirmationToken, req.IPAddress); err != nil {
 		return nil, err
 	}
 
@@ -192,98 +408,630 @@ func (s *transactionService) StartTransfer(req *entity.TransferRequest) (*entity
 	targetTransferUuid := uuid.New().String()
 	req.TargetTransferID = targetTransferUuid
 
-	err = s.transactionRepository.PublishTransfer(*req)
+	// Only the sender's row exists while pending; the receiver's credit row is
+	// written when the transfer is applied.
+	transaction := newPendingTransaction(transferUuid, req.UserID, entity.TransactionTypeDebit, req.Amount, req.Remarks)
+	// A recipient on the watchlist holds the transfer through the
+	// SANCTIONS_MATCH rule.
+	counterpartyMatches := s.screeningService.Screen(targetUser.UserID, targetUser.FirstName, targetUser.LastName)
+	decision, err := s.assess(transaction, entity.TransactionKindTransfer, req.TargetUser, req.SessionID, counterpartyMatches)
 	if err != nil {
 		return nil, err
 	}
+
+	usage.TransactionID = transferUuid
+	err = s.storeStarted(user.Tier, usage, transaction, decision, func(tx *sql.Tx) error {
+		return s.transactionRepository.PublishTransfer(tx, *req)
+	})
+	var blocked *RiskBlockedError
+	if err == nil || errors.As(err, &blocked) {
+		// The transfer is stored and held either way, so a failure to open the
+		// case must not fail the request.
+		if flagErr := s.screeningService.Flag(targetUser, entity.ScreeningTriggerCounterparty, transferUuid, counterpartyMatches); flagErr != nil {
+			log.Printf("screening: failed to flag recipient %s of transfer %s: %v", targetUser.UserID, transferUuid, flagErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditStart(entity.TransactionKindTransfer, transferUuid, req.UserID, req.Amount, req.IPAddress, req.UserAgent, map[string]interface{}{
+		"target_user":  req.TargetUser,
+		"remarks":      req.Remarks,
+		"risk_outcome": decision.Outcome,
+	})
+
 	return &entity.StartTransferResponse{
-		TransferID: transferUuid,
+		TransferID:       transferUuid,
 		TargetTransferID: targetTransferUuid,
 	}, nil
 }
 
-func (s *transactionService) ProcessTransfer(req entity.TransferRequest)(err error){
-	balanceBefore, err := s.walletRepository.GetCurrentBalance(req.UserID)
+func (s *transactionService) ProcessTransfer(req entity.TransferRequest) (err error) {
+	return s.applyTransaction(req.TransferID, func(tx *sql.Tx, transaction *entity.Transaction) error {
+		if err := validateAmount(req.TransferID, req.Amount); err != nil {
+			return err
+		}
+		if req.UserID == req.TargetUser {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonInvalidTarget)
+		}
+
+		wallets, err := s.walletRepository.LockWallets(tx, req.UserID, req.TargetUser)
+		if err != nil {
+			return err
+		}
+
+		wallet, ok := wallets[req.UserID]
+		if !ok {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonWalletNotFound)
+		}
+		targetWallet, ok := wallets[req.TargetUser]
+		if !ok {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonTargetNotFound)
+		}
+		if !entity.CanSend(wallet.Status) {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonAccountRestricted)
+		}
+		if !entity.CanReceive(targetWallet.Status) {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonTargetRestricted)
+		}
+		balanceBefore := wallet.Balance
+		targetBalanceBefore := targetWallet.Balance
+
+		balanceAfter, err := balanceBefore.Sub(req.Amount)
+		if err != nil {
+			return amountError(req.TransferID, err)
+		}
+		if balanceAfter.IsNegative() {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonInsufficientFunds)
+		}
+
+		targetBalanceAfter, err := targetBalanceBefore.Add(req.Amount)
+		if err != nil {
+			return amountError(req.TransferID, err)
+		}
+		err = s.checkBalanceCap(req.TransferID, req.TargetUser, entity.FailureReasonTargetBalanceCap, targetBalanceBefore, req.Amount)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		err = s.walletRepository.Debit(tx, req.UserID, req.Amount, now)
+		if err == repository.ErrInsufficientBalance {
+			return newTransactionFailedError(req.TransferID, entity.FailureReasonInsufficientFunds)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = s.walletRepository.Credit(tx, req.TargetUser, req.Amount, now)
+		if err != nil {
+			return err
+		}
+
+		transaction.Status = entity.TransactionStatusSuccess
+		transaction.BalanceBefore = balanceBefore
+		transaction.BalanceAfter = balanceAfter
+		transaction.UpdatedAt = now
+
+		err = s.transactionRepository.UpdateTransaction(tx, *transaction)
+		if err != nil {
+			return err
+		}
+
+		targetUserTransferTransaction := entity.Transaction{
+			TransactionID: req.TargetTransferID,
+			UserID:        req.TargetUser,
+			Type:          entity.TransactionTypeCredit,
+			Amount:        req.Amount,
+			Status:        entity.TransactionStatusSuccess,
+			BalanceBefore: targetBalanceBefore,
+			BalanceAfter:  targetBalanceAfter,
+			Description:   req.Remarks,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		err = s.transactionRepository.InsertTransaction(tx, targetUserTransferTransaction)
+		if err != nil {
+			return err
+		}
+
+		return s.ledgerService.PostTransfer(tx, req.TransferID, req.UserID, req.TargetUser, req.Amount, req.Remarks, now)
+	})
+}
+
+// checkAccountStatus returns an AccountRestrictedError when the status of
+// the user does not allow money to leave (debit) or enter the wallet. The
+// wallet status is checked again when the transaction is applied.
+func (s *transactionService) checkAccountStatus(userID string, debit bool) (*entity.User, error) {
+	user, err := s.userRepository.FindByID(userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	allowed := entity.CanReceive(user.Status)
+	if debit {
+		allowed = entity.CanSend(user.Status)
+	}
+	if !allowed {
+		return nil, &AccountRestrictedError{Status: user.Status}
 	}
+	return user, nil
+}
 
-	targetBalanceBefore, err := s.walletRepository.GetCurrentBalance(req.TargetUser)
+// checkBalanceCap fails transactionID with reason when crediting amount would
+// take the wallet of userID past the balance cap of its tier.
+func (s *transactionService) checkBalanceCap(transactionID, userID, reason string, balance, amount entity.Money) error {
+	user, err := s.userRepository.FindByID(userID)
 	if err != nil {
 		return err
 	}
-	
-	tx, err := s.db.Begin()
+
+	err = s.limitService.CheckBalanceCap(user.Tier, balance, amount)
+	var exceeded *LimitExceededError
+	if errors.As(err, &exceeded) {
+		return newTransactionFailedError(transactionID, reason)
+	}
+	return err
+}
+
+func (s *transactionService) limitUsage(transactionID, userID, kind string, amount entity.Money) entity.LimitUsage {
+	return entity.LimitUsage{
+		TransactionID: transactionID,
+		UserID:        userID,
+		Kind:          kind,
+		Amount:        amount,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// assess runs the risk engine on a transaction that passed the checks of a
+// Start method. counterpartyMatches are the watchlist hits of the recipient
+// of a transfer.
+func (s *transactionService) assess(transaction entity.Transaction, kind, targetUser, sessionID string, counterpartyMatches []entity.ScreeningMatch) (*entity.RiskDecision, error) {
+	return s.riskService.Assess(&entity.RiskInput{
+		TransactionID:       transaction.TransactionID,
+		Kind:                kind,
+		UserID:              transaction.UserID,
+		TargetUser:          targetUser,
+		Amount:              transaction.Amount,
+		SessionID:           sessionID,
+		CreatedAt:           transaction.CreatedAt,
+		CounterpartyMatches: counterpartyMatches,
+	})
+}
+
+// storeStarted stores transaction with the risk decision. A blocked
+// transaction is stored as FAILED and a RiskBlockedError returned. Otherwise
+// usage is reserved against the limits of tier and the transaction inserted
+// and queued by publish, or held IN_REVIEW when the decision is REVIEW.
+// Reserving first means concurrent requests cannot pass a limit together.
+func (s *transactionService) storeStarted(tier string, usage entity.LimitUsage, transaction entity.Transaction, decision *entity.RiskDecision, publish func(tx *sql.Tx) error) error {
+	if decision.Outcome == entity.RiskOutcomeBlock {
+		return s.storeBlocked(transaction, decision)
+	}
+
+	held := decision.Outcome == entity.RiskOutcomeReview
+	if held {
+		transaction.Status = entity.TransactionStatusInReview
+	}
+
+	if err := s.limitService.Reserve(tier, usage); err != nil {
+		return err
+	}
+
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		if err := s.transactionRepository.InsertTransaction(tx, transaction); err != nil {
+			return err
+		}
+		if err := publish(tx); err != nil {
+			return err
+		}
+		if held {
+			if err := s.transactionRepository.HoldJob(tx, transaction.TransactionID); err != nil {
+				return err
+			}
+		}
+		if err := s.riskService.Record(tx, decision); err != nil {
+			return err
+		}
+		return s.limitService.Record(tx, usage)
+	})
+	if err != nil {
+		s.limitService.ReleaseCounters(usage)
+	}
+	return err
+}
+
+// storeBlocked keeps a blocked transaction as FAILED, so the decision has a
+// transaction to belong to. It moved no money and never counts against the
+// limits.
+func (s *transactionService) storeBlocked(transaction entity.Transaction, decision *entity.RiskDecision) error {
+	transaction.Status = entity.TransactionStatusFailed
+	transaction.FailureReason = entity.FailureReasonRiskBlocked
+
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		if err := s.transactionRepository.InsertTransaction(tx, transaction); err != nil {
+			return err
+		}
+		return s.riskService.Record(tx, decision)
+	})
 	if err != nil {
 		return err
 	}
 
-	defer tx.Rollback()
+	s.auditOutcome(entity.AuditEventTransactionFailed, &transaction)
+	return &RiskBlockedError{TransactionID: transaction.TransactionID}
+}
+
+// applyTransaction runs apply with the pending transaction locked. When apply
+// returns a TransactionFailedError its changes are rolled back and the
+// transaction is marked FAILED with the reason in a separate step.
+func (s *transactionService) applyTransaction(transactionID string, apply func(tx *sql.Tx, transaction *entity.Transaction) error) error {
+	var applied *entity.Transaction
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		applied = nil
+		transaction, err := s.lockPendingTransaction(tx, transactionID)
+		if err != nil || transaction == nil {
+			return err
+		}
+		if err := apply(tx, transaction); err != nil {
+			return err
+		}
+		applied = transaction
+		return nil
+	})
+	if err == nil && applied != nil {
+		s.auditOutcome(entity.AuditEventTransactionSucceeded, applied)
+	}
 
+	var failed *TransactionFailedError
+	if errors.As(err, &failed) {
+		if failErr := s.FailTransaction(transactionID, failed.Reason); failErr != nil {
+			return failErr
+		}
+	}
+
+	return err
+}
+
+func validateAmount(transactionID string, amount entity.Money) error {
+	if !amount.IsPositive() {
+		return newTransactionFailedError(transactionID, entity.FailureReasonInvalidAmount)
+	}
+	return nil
+}
+
+func amountError(transactionID string, err error) error {
+	if errors.Is(err, entity.ErrCurrencyMismatch) {
+		return newTransactionFailedError(transactionID, entity.FailureReasonCurrencyMismatch)
+	}
+	if errors.Is(err, entity.ErrMoneyOverflow) {
+		return newTransactionFailedError(transactionID, entity.FailureReasonInvalidAmount)
+	}
+	return err
+}
+
+func newPendingTransaction(transactionID, userID, transactionType string, amount entity.Money, description string) entity.Transaction {
 	now := time.Now()
-	balanceAfter := balanceBefore - req.Amount
 
-	userTransferTransaction := entity.Transaction{
-		TransactionID: req.TransferID,
-		UserID: req.UserID,
-		Type: "DEBIT",
-		Amount: req.Amount,
-		Status: "SUCCESS",
-		BalanceBefore: balanceBefore,
-		BalanceAfter: balanceAfter,
-		Description: req.Remarks,
-		CreatedAt: now,
-		UpdatedAt: now,
+	return entity.Transaction{
+		TransactionID: transactionID,
+		UserID:        userID,
+		Type:          transactionType,
+		Amount:        amount,
+		Status:        entity.TransactionStatusPending,
+		Description:   description,
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
+}
 
-	err = s.transactionRepository.InsertTransaction(tx, userTransferTransaction)
+// lockPendingTransaction returns nil without an error when the transaction was
+// already applied, which happens when the outbox relay delivers a job twice.
+func (s *transactionService) lockPendingTransaction(tx *sql.Tx, transactionID string) (*entity.Transaction, error) {
+	transaction, err := s.transactionRepository.LockTransaction(tx, transactionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transaction %s not found", transactionID)
+	}
 	if err != nil {
+		return nil, err
+	}
+
+	if transaction.Status != entity.TransactionStatusPending {
+		return nil, nil
+	}
+
+	return transaction, nil
+}
+
+// FailTransaction marks a pending transaction as FAILED. It is a no-op when the
+// transaction already reached a final state.
+func (s *transactionService) FailTransaction(transactionID string, reason string) error {
+	var failed *entity.Transaction
+	var released *entity.LimitUsage
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		failed, released = nil, nil
+		transaction, err := s.lockPendingTransaction(tx, transactionID)
+		if err != nil || transaction == nil {
+			return err
+		}
+
+		transaction.Status = entity.TransactionStatusFailed
+		transaction.FailureReason = reason
+		transaction.UpdatedAt = time.Now()
+
+		if err := s.transactionRepository.UpdateTransaction(tx, *transaction); err != nil {
+			return err
+		}
+		// A failed transaction moved no money, so it no longer counts
+		// against the limits.
+		if released, err = s.limitService.Release(tx, transactionID); err != nil {
+			return err
+		}
+		failed = transaction
+		return nil
+	})
+	if err == nil && released != nil {
+		s.limitService.ReleaseCounters(*released)
+	}
+	if err == nil && failed != nil {
+		s.auditOutcome(entity.AuditEventTransactionFailed, failed)
+	}
+
+	return err
+}
+
+// auditStart records a transaction that was accepted and queued.
+func (s *transactionService) auditStart(kind, transactionID, userID string, amount entity.Money, ip, userAgent string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["kind"] = kind
+	details["amount"] = amount.String()
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:      entity.AuditEventTransactionCreated,
+		ActorID:   userID,
+		UserID:    userID,
+		TargetID:  transactionID,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		Details:   details,
+		CreatedAt: time.Now(),
+	})
+}
+
+// auditOutcome records the final state of a transaction applied or failed by
+// a worker, so the event has no actor.
+func (s *transactionService) auditOutcome(eventType string, transaction *entity.Transaction) {
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:      eventType,
+		UserID:    transaction.UserID,
+		TargetID:  transaction.TransactionID,
+		After:     transaction,
+		CreatedAt: time.Now(),
+	})
+}
+
+// ReopenTransaction moves a transaction that failed with PROCESSING_ERROR back
+// to PENDING so its job can be replayed, and reports whether it did; a
+// PENDING transaction is left as it is. Business rule failures stay final.
+func (s *transactionService) ReopenTransaction(transactionID string) (bool, error) {
+	var restored *entity.LimitUsage
+	var reopened bool
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		restored, reopened = nil, false
+		transaction, err := s.transactionRepository.LockTransaction(tx, transactionID)
+		if err == sql.ErrNoRows {
+			return ErrTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case transaction.Status == entity.TransactionStatusPending:
+			return nil
+		case transaction.Status == entity.TransactionStatusFailed && transaction.FailureReason == entity.FailureReasonProcessingError:
+		default:
+			return fmt.Errorf("transaction %s is %s and cannot be retried", transactionID, transaction.Status)
+		}
+
+		transaction.Status = entity.TransactionStatusPending
+		transaction.FailureReason = ""
+		transaction.UpdatedAt = time.Now()
+
+		if err := s.transactionRepository.UpdateTransaction(tx, *transaction); err != nil {
+			return err
+		}
+		reopened = true
+		restored, err = s.limitService.Restore(tx, transactionID)
 		return err
+	})
+	if err == nil && restored != nil {
+		s.limitService.RestoreCounters(*restored)
 	}
 
-	err = s.walletRepository.UpdateBalance(tx, req.UserID, balanceAfter, now)
+	return reopened, err
+}
+
+func (s *transactionService) ReleaseTransaction(req *entity.ReviewTransactionRequest) error {
+	var released *entity.Transaction
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		released = nil
+		transaction, err := s.lockHeldTransaction(tx, req)
+		if err != nil {
+			return err
+		}
+
+		// Transactions under investigation are released by closing their
+		// case.
+		open, err := s.complianceRepository.CountOpenForTransaction(tx, transaction.TransactionID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrTransactionInCase
+		}
+
+		now := time.Now()
+		transaction.Status = entity.TransactionStatusPending
+		transaction.UpdatedAt = now
+
+		if err := s.transactionRepository.UpdateTransaction(tx, *transaction); err != nil {
+			return err
+		}
+		if err := s.transactionRepository.ReleaseJob(tx, transaction.TransactionID); err != nil {
+			return err
+		}
+		if err := s.riskService.MarkReviewed(tx, req, now); err != nil {
+			return err
+		}
+		released = transaction
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	targetBalanceAfter := targetBalanceBefore + req.Amount
+	s.auditReview(entity.AuditEventTransactionReleased, req, released)
+	return nil
+}
 
-	targetUserTransferTransaction := entity.Transaction{
-		TransactionID: req.TargetTransferID,
-		UserID: req.TargetUser,
-		Type: "CREDIT",
-		Amount: req.Amount,
-		Status: "SUCCESS",
-		BalanceBefore: targetBalanceBefore,
-		BalanceAfter: targetBalanceAfter,
-		Description: req.Remarks,
-		CreatedAt: now,
-		UpdatedAt: now,
+func (s *transactionService) RejectTransaction(req *entity.ReviewTransactionRequest) error {
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		return ErrReviewNoteRequired
 	}
 
-	err = s.transactionRepository.InsertTransaction(tx, targetUserTransferTransaction)
+	var rejected *entity.Transaction
+	var released *entity.LimitUsage
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		rejected, released = nil, nil
+		transaction, err := s.lockHeldTransaction(tx, req)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transaction.Status = entity.TransactionStatusFailed
+		transaction.FailureReason = entity.FailureReasonRiskRejected
+		transaction.UpdatedAt = now
+
+		if err := s.transactionRepository.UpdateTransaction(tx, *transaction); err != nil {
+			return err
+		}
+		if err := s.transactionRepository.DiscardJob(tx, transaction.TransactionID); err != nil {
+			return err
+		}
+		if err := s.riskService.MarkReviewed(tx, req, now); err != nil {
+			return err
+		}
+		if released, err = s.limitService.Release(tx, transaction.TransactionID); err != nil {
+			return err
+		}
+		rejected = transaction
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	err = s.walletRepository.UpdateBalance(tx, req.TargetUser, targetBalanceAfter, now)
-	if err != nil {
-		return err
+	if released != nil {
+		s.limitService.ReleaseCounters(*released)
 	}
+	s.auditReview(entity.AuditEventTransactionRejected, req, rejected)
+	s.auditOutcome(entity.AuditEventTransactionFailed, rejected)
+	return nil
+}
 
-	tx.Commit()
+// lockHeldTransaction locks a transaction that waits for review by staff
+// other than its owner.
+func (s *transactionService) lockHeldTransaction(tx *sql.Tx, req *entity.ReviewTransactionRequest) (*entity.Transaction, error) {
+	transaction, err := s.transactionRepository.LockTransaction(tx, req.TransactionID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status != entity.TransactionStatusInReview {
+		return nil, ErrTransactionNotHeld
+	}
+	if transaction.UserID == req.ActorID {
+		return nil, ErrSelfReview
+	}
+	return transaction, nil
+}
 
-	return err
+// auditReview records staff releasing or rejecting a held transaction.
+func (s *transactionService) auditReview(eventType string, req *entity.ReviewTransactionRequest, transaction *entity.Transaction) {
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:      eventType,
+		ActorID:   req.ActorID,
+		UserID:    transaction.UserID,
+		TargetID:  transaction.TransactionID,
+		IPAddress: req.IPAddress,
+		Details: map[string]interface{}{
+			"note": req.Note,
+		},
+		CreatedAt: transaction.UpdatedAt,
+	})
 }
 
 func (s *transactionService) FindTransactionByID(topUpID string) (*entity.Transaction, error) {
 	transaction, err := s.transactionRepository.FindTransactionByID(topUpID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	return transaction, err
 }
 
+// FindUserTransactionByID hides transactions of other users behind the same
+// not-found error so ids cannot be probed.
+func (s *transactionService) FindUserTransactionByID(userID, transactionID string) (*entity.Transaction, error) {
+	transaction, err := s.FindTransactionByID(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.UserID != userID {
+		return nil, ErrTransactionNotFound
+	}
+	return transaction, nil
+}
+
+// WaitForTransaction long-polls until the transaction is SUCCESS or FAILED,
+// the timeout passes or ctx is cancelled, and returns its latest state.
+func (s *transactionService) WaitForTransaction(ctx context.Context, userID, transactionID string, timeout time.Duration) (*entity.Transaction, error) {
+	timeout = min(timeout, maxTransactionWait)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(transactionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		transaction, err := s.FindUserTransactionByID(userID, transactionID)
+		if err != nil || transaction.IsFinal() {
+			return transaction, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return transaction, nil
+		case <-deadline.C:
+			return transaction, nil
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *transactionService) FindTransactionsByUserID(userID string) ([]*entity.Transaction, error) {
 	transactions, err := s.transactionRepository.FindTransactionsByUserID(userID)
 	if err != nil {
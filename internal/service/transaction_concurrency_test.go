@@ -0,0 +1,406 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/repository"
+)
+
+// These tests need a disposable MySQL database, e.g.
+// TEST_MYSQL_DSN="user:password@tcp(localhost:3306)/ewallet_test" go test ./internal/service/
+// The schema from init-scripts/init.sql is applied before each run.
+
+type concurrencyFixture struct {
+	db      *sql.DB
+	service *transactionService
+	ledger  ILedgerService
+	wallets repository.IWalletRepository
+	users   repository.IUserRepository
+}
+
+func newConcurrencyFixture(t *testing.T) *concurrencyFixture {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(20)
+
+	schema, err := os.ReadFile("../../init-scripts/init.sql")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	for _, statement := range strings.Split(string(schema), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("apply schema: %v", err)
+		}
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	walletRepo := repository.NewWalletRepository(db)
+	ledgerService := NewLedgerService(db, repository.NewLedgerRepository(db), walletRepo)
+	transactionRepo := repository.NewTransactionRepository(db, repository.NewOutboxRepository(db))
+	cfg := &config.Config{}
+	auditLogger := audit.NewLogLogger()
+	mfaService := NewMFAService(cfg, userRepo, repository.NewMFARepository(db), nil, auditLogger)
+	svc := NewTransactionService(cfg, db, transactionRepo, walletRepo, userRepo, ledgerService, mfaService, allowAllConfirmations{}, noLimits{}, noScreening{}, allowAllRisk{}, repository.NewComplianceRepository(db), auditLogger)
+
+	return &concurrencyFixture{
+		db:      db,
+		service: svc.(*transactionService),
+		ledger:  ledgerService,
+		wallets: walletRepo,
+		users:   userRepo,
+	}
+}
+
+// allowAllConfirmations skips the PIN check, which needs Redis for the
+// lockout counters.
+type allowAllConfirmations struct{}
+
+func (allowAllConfirmations) Confirm(req *entity.TransactionConfirmRequest) (*entity.TransactionConfirmResponse, error) {
+	return nil, errors.New("not supported in tests")
+}
+
+func (allowAllConfirmations) Authorize(confirmation entity.TransactionConfirmation, pin, token, ip string) error {
+	return nil
+}
+
+// noLimits skips the transaction limits, whose counters live in Redis.
+type noLimits struct{}
+
+func (noLimits) CheckBalanceCap(tier string, balance, amount entity.Money) error { return nil }
+func (noLimits) Check(tier string, usage entity.LimitUsage) error                { return nil }
+func (noLimits) Reserve(tier string, usage entity.LimitUsage) error              { return nil }
+func (noLimits) Record(tx *sql.Tx, usage entity.LimitUsage) error                { return nil }
+func (noLimits) ReleaseCounters(usage entity.LimitUsage)                         {}
+func (noLimits) RestoreCounters(usage entity.LimitUsage)                         {}
+func (noLimits) Release(tx *sql.Tx, id string) (*entity.LimitUsage, error)       { return nil, nil }
+func (noLimits) Restore(tx *sql.Tx, id string) (*entity.LimitUsage, error)       { return nil, nil }
+
+// noScreening matches nobody.
+type noScreening struct{}
+
+func (noScreening) Screen(userID, firstName, lastName string) []entity.ScreeningMatch { return nil }
+func (noScreening) Flag(user *entity.User, trigger, transactionID string, matches []entity.ScreeningMatch) error {
+	return nil
+}
+
+// allowAllRisk allows every transaction without storing the decision.
+type allowAllRisk struct{}
+
+func (allowAllRisk) Assess(input *entity.RiskInput) (*entity.RiskDecision, error) {
+	return &entity.RiskDecision{TransactionID: input.TransactionID, Outcome: entity.RiskOutcomeAllow}, nil
+}
+func (allowAllRisk) Record(tx *sql.Tx, decision *entity.RiskDecision) error { return nil }
+func (allowAllRisk) MarkReviewed(tx *sql.Tx, req *entity.ReviewTransactionRequest, reviewedAt time.Time) error {
+	return nil
+}
+func (allowAllRisk) FindDecision(id string) (*entity.RiskDecision, error) {
+	return nil, ErrRiskDecisionNotFound
+}
+func (allowAllRisk) FindDecisions(filter entity.RiskDecisionFilter) ([]*entity.RiskDecision, error) {
+	return nil, nil
+}
+
+func (f *concurrencyFixture) createUser(t *testing.T, balance entity.Money) string {
+	t.Helper()
+
+	now := time.Now()
+	user := &entity.User{
+		UserID:             uuid.New().String(),
+		PhoneNumber:        fmt.Sprintf("+62%d", rand.Int63n(1e11)),
+		Pin:                "-",
+		FirstName:          "Concurrency",
+		LastName:           "Test",
+		VerificationStatus: entity.UserVerificationStatusVerified,
+		Role:               entity.RoleCustomer,
+		Status:             entity.AccountStatusActive,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := f.users.Register(user); err != nil {
+		t.Fatalf("register user: %v", err)
+	}
+
+	if balance.IsPositive() {
+		if err := f.service.ProcessTopUp(f.startTopUp(t, user.UserID, balance)); err != nil {
+			t.Fatalf("seed balance: %v", err)
+		}
+	}
+
+	return user.UserID
+}
+
+// The start helpers create the PENDING row and outbox message the way the
+// HTTP handlers do, and return the job the worker would receive.
+
+func (f *concurrencyFixture) startTopUp(t *testing.T, userID string, amount entity.Money) entity.PublishTopUpRequest {
+	t.Helper()
+
+	topUpID, err := f.service.StartTopUp(&entity.PublishTopUpRequest{UserID: userID, Amount: amount})
+	if err != nil {
+		t.Fatalf("start top up: %v", err)
+	}
+	return entity.PublishTopUpRequest{TopUpID: topUpID, UserID: userID, Amount: amount}
+}
+
+func (f *concurrencyFixture) startPayment(t *testing.T, userID string, amount entity.Money) entity.PaymentRequest {
+	t.Helper()
+
+	req := &entity.PaymentRequest{UserID: userID, Amount: amount}
+	if _, err := f.service.StartPayment(req); err != nil {
+		t.Fatalf("start payment: %v", err)
+	}
+	return *req
+}
+
+func (f *concurrencyFixture) startTransfer(t *testing.T, userID, targetUser string, amount entity.Money) entity.TransferRequest {
+	t.Helper()
+
+	req := &entity.TransferRequest{UserID: userID, TargetUser: targetUser, Amount: amount}
+	if _, err := f.service.StartTransfer(req); err != nil {
+		t.Fatalf("start transfer: %v", err)
+	}
+	return *req
+}
+
+func (f *concurrencyFixture) balance(t *testing.T, userID string) entity.Money {
+	t.Helper()
+
+	balance, err := f.wallets.GetCurrentBalance(userID)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	return balance
+}
+
+// assertWalletMatchesLedger checks that the cached wallets.balance equals the
+// balance derived from postings.
+func (f *concurrencyFixture) assertWalletMatchesLedger(t *testing.T, userID string) {
+	t.Helper()
+
+	ledgerBalance, err := f.ledger.GetWalletBalance(userID)
+	if err != nil {
+		t.Fatalf("get ledger balance: %v", err)
+	}
+	if walletBalance := f.balance(t, userID); walletBalance.Amount != ledgerBalance.Amount {
+		t.Errorf("wallet %s balance %s does not match ledger balance %s", userID, walletBalance, ledgerBalance)
+	}
+}
+
+func (f *concurrencyFixture) assertStatus(t *testing.T, transactionID, status string) {
+	t.Helper()
+
+	transaction, err := f.service.FindTransactionByID(transactionID)
+	if err != nil {
+		t.Errorf("find transaction %s: %v", transactionID, err)
+		return
+	}
+	if transaction.Status != status {
+		t.Errorf("transaction %s status = %s, want %s", transactionID, transaction.Status, status)
+	}
+}
+
+func isFailure(err error, reason string) bool {
+	var failed *TransactionFailedError
+	return errors.As(err, &failed) && failed.Reason == reason
+}
+
+func money(t *testing.T, s string) entity.Money {
+	t.Helper()
+
+	m, err := entity.ParseMoney(s, entity.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("parse money %q: %v", s, err)
+	}
+	return m
+}
+
+func TestConcurrentTopUpsAreNotLost(t *testing.T) {
+	f := newConcurrencyFixture(t)
+	userID := f.createUser(t, entity.NewMoney(0, entity.DefaultCurrency))
+
+	const workers = 50
+	amount := money(t, "1.01")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		job := f.startTopUp(t, userID, amount)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- f.service.ProcessTopUp(job)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("top up failed: %v", err)
+		}
+	}
+
+	if got, want := f.balance(t, userID), money(t, "50.50"); got.Amount != want.Amount {
+		t.Errorf("balance = %s, want %s", got, want)
+	}
+	f.assertWalletMatchesLedger(t, userID)
+}
+
+func TestConcurrentPaymentsNeverOverdraw(t *testing.T) {
+	f := newConcurrencyFixture(t)
+	userID := f.createUser(t, money(t, "100.00"))
+
+	const workers = 25
+	amount := money(t, "10.00")
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+	)
+	jobs := make([]entity.PaymentRequest, workers)
+	for i := range jobs {
+		jobs[i] = f.startPayment(t, userID, amount)
+	}
+
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := f.service.ProcessPayment(job)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case isFailure(err, entity.FailureReasonInsufficientFunds):
+				insufficient++
+				f.assertStatus(t, job.PaymentID, entity.TransactionStatusFailed)
+			default:
+				t.Errorf("unexpected payment error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 10 || insufficient != workers-10 {
+		t.Errorf("succeeded = %d, insufficient = %d, want 10 and %d", succeeded, insufficient, workers-10)
+	}
+	if got := f.balance(t, userID); !got.IsZero() {
+		t.Errorf("balance = %s, want 0.00", got)
+	}
+	f.assertWalletMatchesLedger(t, userID)
+}
+
+func TestConcurrentOpposingTransfersConserveMoney(t *testing.T) {
+	f := newConcurrencyFixture(t)
+
+	initial := money(t, "500.00")
+	userIDs := []string{f.createUser(t, initial), f.createUser(t, initial), f.createUser(t, initial)}
+
+	const workers = 90
+	amount := money(t, "7.25")
+
+	jobs := make([]entity.TransferRequest, workers)
+	for i := range jobs {
+		// Alternate between the next and the previous wallet so every pair
+		// sees transfers in both directions.
+		from := userIDs[i%len(userIDs)]
+		to := userIDs[(i+1+(i/len(userIDs))%2)%len(userIDs)]
+		jobs[i] = f.startTransfer(t, from, to, amount)
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := f.service.ProcessTransfer(job)
+			if err != nil && !isFailure(err, entity.FailureReasonInsufficientFunds) {
+				t.Errorf("unexpected transfer error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := entity.NewMoney(0, entity.DefaultCurrency)
+	for _, userID := range userIDs {
+		balance := f.balance(t, userID)
+		if balance.IsNegative() {
+			t.Errorf("wallet %s went negative: %s", userID, balance)
+		}
+		f.assertWalletMatchesLedger(t, userID)
+
+		var err error
+		if total, err = total.Add(balance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := initial.Amount * int64(len(userIDs)); total.Amount != want {
+		t.Errorf("total balance = %s, want %s", total, entity.NewMoney(want, entity.DefaultCurrency))
+	}
+	if err := f.ledger.CheckConservation(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedeliveredJobIsAppliedOnce(t *testing.T) {
+	f := newConcurrencyFixture(t)
+	userID := f.createUser(t, money(t, "100.00"))
+	targetID := f.createUser(t, entity.NewMoney(0, entity.DefaultCurrency))
+
+	job := f.startTransfer(t, userID, targetID, money(t, "30.00"))
+
+	const deliveries = 5
+
+	var wg sync.WaitGroup
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.service.ProcessTransfer(job); err != nil {
+				t.Errorf("delivery failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := f.balance(t, userID), money(t, "70.00"); got.Amount != want.Amount {
+		t.Errorf("sender balance = %s, want %s", got, want)
+	}
+	if got, want := f.balance(t, targetID), money(t, "30.00"); got.Amount != want.Amount {
+		t.Errorf("receiver balance = %s, want %s", got, want)
+	}
+	f.assertWalletMatchesLedger(t, userID)
+	f.assertWalletMatchesLedger(t, targetID)
+}
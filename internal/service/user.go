@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/rbac"
+	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/utils"
+)
+
+var ErrInvalidRole = errors.New("role must be customer, support, finance-ops or admin")
+
+// IUserService holds the staff operations on other users' accounts.
+type IUserService interface {
+	FindUser(userID string) (*entity.UserAccount, error)
+	FindUserByPhoneNumber(phoneNumber string) (*entity.UserAccount, error)
+
+	// SetRole changes the role of a user and revokes their sessions, so the
+	// new role is in effect from the next login.
+	SetRole(req *entity.SetRoleRequest) error
+}
+
+type userService struct {
+	config           *config.Config
+	userRepository   repository.IUserRepository
+	walletRepository repository.IWalletRepository
+	authService      IAuthService
+	auditLogger      audit.Logger
+}
+
+func NewUserService(config *config.Config, userRepo repository.IUserRepository, walletRepo repository.IWalletRepository, authService IAuthService, auditLogger audit.Logger) IUserService {
+	return &userService{
+		config:           config,
+		userRepository:   userRepo,
+		walletRepository: walletRepo,
+		authService:      authService,
+		auditLogger:      auditLogger,
+	}
+}
+
+func (s *userService) FindUser(userID string) (*entity.UserAccount, error) {
+	user, err := s.userRepository.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.userAccount(user)
+}
+
+func (s *userService) FindUserByPhoneNumber(phoneNumber string) (*entity.UserAccount, error) {
+	phoneNumber, err := utils.NormalizePhoneNumber(phoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.FindByPhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.userAccount(user)
+}
+
+func (s *userService) userAccount(user *entity.User) (*entity.UserAccount, error) {
+	balance, err := s.walletRepository.GetCurrentBalance(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.UserAccount{User: user, Balance: balance}, nil
+}
+
+func (s *userService) SetRole(req *entity.SetRoleRequest) error {
+	if !rbac.ValidRole(req.Role) {
+		return ErrInvalidRole
+	}
+
+	user, err := s.userRepository.FindByID(req.UserID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+	if user.Role == req.Role {
+		return nil
+	}
+
+	if err := s.userRepository.UpdateRole(user.UserID, req.Role, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.authService.RevokeAllSessions(user.UserID); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(entity.AuditEvent{
+		Type:        entity.AuditEventRoleChanged,
+		UserID:      user.UserID,
+		ActorID:     req.ActorID,
+		PhoneNumber: user.PhoneNumber,
+		IPAddress:   req.IPAddress,
+		Details:     map[string]interface{}{"from": user.Role, "to": req.Role},
+		CreatedAt:   time.Now(),
+	})
+
+	return nil
+}
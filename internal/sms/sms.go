@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sender delivers text messages to a phone number. Production deployments
+// plug in a gateway client; the implementations here are local stand-ins.
+type Sender interface {
+	Send(phoneNumber, message string) error
+}
+
+type logSender struct{}
+
+// NewLogSender writes messages to the standard logger.
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+func (logSender) Send(phoneNumber, message string) error {
+	log.Printf("SMS to %s: %s", phoneNumber, message)
+	return nil
+}
+
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSender appends messages to the file at path, one per line.
+func NewFileSender(path string) Sender {
+	return &fileSender{path: path}
+}
+
+func (s *fileSender) Send(phoneNumber, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phoneNumber, message)
+	return err
+}
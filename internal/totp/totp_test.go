@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes, these are their last 6 digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, tt := range rfcVectors {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	code, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(59, 0)))
+	if err != nil || code != "287082" {
+		t.Errorf("Code = %s, %v, want 287082", code, err)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	codeAt := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name   string
+		code   string
+		skew   int64
+		ok     bool
+		stepOK int64
+	}{
+		{name: "current step", code: codeAt(current), skew: 0, ok: true, stepOK: current},
+		{name: "previous step without skew", code: codeAt(current - 1), skew: 0},
+		{name: "previous step", code: codeAt(current - 1), skew: 1, ok: true, stepOK: current - 1},
+		{name: "next step", code: codeAt(current + 1), skew: 1, ok: true, stepOK: current + 1},
+		{name: "two steps behind", code: codeAt(current - 2), skew: 1},
+		{name: "two steps ahead", code: codeAt(current + 2), skew: 1},
+		{name: "wrong code", code: "000000", skew: 1},
+		{name: "short code", code: "05047", skew: 1},
+		{name: "long code", code: "0050471", skew: 1},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && step != tt.stepOK {
+			t.Errorf("%s: step = %d, want %d", tt.name, step, tt.stepOK)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret is %d bytes, want %d", len(key), secretSize)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("E-Wallet", "+6281234567890", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse %s: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/E-Wallet:+6281234567890" {
+		t.Errorf("URI = %s", uri)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "E-Wallet",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashPin(pin string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
@@ -11,3 +16,43 @@ func CheckPinHash(pin, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin))
 	return err == nil
 }
+
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// NormalizePhoneNumber converts a phone number to E.164 (+<country><number>).
+// Numbers without an international prefix are read as national numbers of
+// defaultCountryCode, with or without the leading trunk 0.
+func NormalizePhoneNumber(phoneNumber, defaultCountryCode string) (string, error) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(phoneNumber) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhoneNumber
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = defaultCountryCode + number[1:]
+	case !strings.HasPrefix(number, defaultCountryCode):
+		number = defaultCountryCode + number
+	}
+
+	// E.164 allows at most 15 digits; anything shorter than 8 cannot be a
+	// subscriber number with a country code.
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return "+" + number, nil
+}
@@ -4,17 +4,28 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gomodule/redigo/redis"
 	"github.com/joho/godotenv"
 	"github.com/leonardoong/e-wallet/config"
+	"github.com/leonardoong/e-wallet/internal/audit"
+	"github.com/leonardoong/e-wallet/internal/blob"
+	"github.com/leonardoong/e-wallet/internal/cli"
+	"github.com/leonardoong/e-wallet/internal/domain/entity"
+	"github.com/leonardoong/e-wallet/internal/jwtkeys"
+	"github.com/leonardoong/e-wallet/internal/limits"
 	"github.com/leonardoong/e-wallet/internal/publisher"
 	"github.com/leonardoong/e-wallet/internal/queue"
+	"github.com/leonardoong/e-wallet/internal/relay"
 	"github.com/leonardoong/e-wallet/internal/repository"
+	"github.com/leonardoong/e-wallet/internal/risk"
 	"github.com/leonardoong/e-wallet/internal/routes"
+	"github.com/leonardoong/e-wallet/internal/screening"
 	"github.com/leonardoong/e-wallet/internal/service"
+	"github.com/leonardoong/e-wallet/internal/sms"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -26,6 +37,24 @@ func main() {
 	}
 
 	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	keySet, err := jwtkeys.Load(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load JWT keys: ", err)
+	}
+
+	limitRules, err := limits.NewStore(cfg.LimitsFile, time.Duration(cfg.LimitsReloadSeconds)*time.Second)
+	if err != nil {
+		log.Fatal("failed to load limits: ", err)
+	}
+
+	watchlist, err := screening.NewStore(cfg.ScreeningListFile, time.Duration(cfg.ScreeningPollSeconds)*time.Second)
+	if err != nil {
+		log.Fatal("failed to load screening list: ", err)
+	}
 
 	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 	dbConn, err := sql.Open(`mysql`, connection)
@@ -77,22 +106,93 @@ func main() {
 	cfg.CachePool = cache
 	defer cacheConn.Close()
 
-	redisPublisher := publisher.NewPublisher("ewallet", cache)
+	redisPublisher := publisher.NewPublisher(entity.JobNamespace, cache)
 	redisPublisher.Initialize()
 
 	userRepo := repository.NewUserRepository(dbConn)
 	walletRepo := repository.NewWalletRepository(dbConn)
-	transactionRepo := repository.NewTransactionRepository(dbConn, redisPublisher)
+	outboxRepo := repository.NewOutboxRepository(dbConn)
+	transactionRepo := repository.NewTransactionRepository(dbConn, outboxRepo)
+	ledgerRepo := repository.NewLedgerRepository(dbConn)
+	idempotencyRepo := repository.NewIdempotencyRepository(cache)
+	tokenRepo := repository.NewTokenRepository(cache)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(cache)
+
+	otpRepo := repository.NewOTPRepository(cache)
+	mfaRepo := repository.NewMFARepository(dbConn)
+	confirmationRepo := repository.NewConfirmationRepository(cache)
+	sessionRepo := repository.NewSessionRepository(dbConn)
+	limitUsageRepo := repository.NewLimitUsageRepository(dbConn)
+	limitCounterRepo := repository.NewLimitCounterRepository(cache)
+	kycRepo := repository.NewKYCRepository(dbConn)
+	riskRepo := repository.NewRiskRepository(dbConn)
+	complianceRepo := repository.NewComplianceRepository(dbConn)
+
+	blobStore, err := blob.NewLocalStore(cfg.BlobDir)
+	if err != nil {
+		log.Fatal("failed to open blob store: ", err)
+	}
+
+	auditRepo := repository.NewAuditRepository(dbConn)
+	auditLogger := audit.NewStoreLogger(auditRepo)
+
+	var smsSender sms.Sender = sms.NewLogSender()
+	if cfg.SMSOutboxFile != "" {
+		smsSender = sms.NewFileSender(cfg.SMSOutboxFile)
+	}
 
-	userService := service.NewAuthService(cfg, userRepo)
-	transactionService := service.NewTransactionService(cfg, dbConn, transactionRepo, walletRepo, userRepo)
+	otpService := service.NewOTPService(cfg, otpRepo, smsSender)
+	mfaService := service.NewMFAService(cfg, userRepo, mfaRepo, loginAttemptRepo, auditLogger)
+	screeningService := service.NewScreeningService(watchlist, float64(cfg.ScreeningMinScore)/100, dbConn, complianceRepo, userRepo, walletRepo, auditLogger)
+	authService := service.NewAuthService(cfg, keySet, userRepo, tokenRepo, loginAttemptRepo, sessionRepo, otpService, mfaService, screeningService, smsSender, auditLogger)
+	userService := service.NewUserService(cfg, userRepo, walletRepo, authService, auditLogger)
+	confirmationService := service.NewConfirmationService(authService, confirmationRepo)
+	ledgerService := service.NewLedgerService(dbConn, ledgerRepo, walletRepo)
+	accountService := service.NewAccountService(dbConn, userRepo, walletRepo, transactionRepo, complianceRepo, ledgerService, authService, auditLogger)
+	limitService := service.NewLimitService(limitRules, limitUsageRepo, limitCounterRepo)
+	riskConfig, err := loadRiskConfig(cfg)
+	if err != nil {
+		log.Fatal("failed to load risk rules: ", err)
+	}
+	riskService := service.NewRiskService(risk.NewEngine(risk.DefaultRules(riskConfig)...), riskConfig.HistoryWindow(), riskRepo, sessionRepo, ledgerRepo, userRepo)
+	transactionService := service.NewTransactionService(cfg, dbConn, transactionRepo, walletRepo, userRepo, ledgerService, mfaService, confirmationService, limitService, screeningService, riskService, complianceRepo, auditLogger)
+	auditService := service.NewAuditService(auditRepo)
+	kycService := service.NewKYCService(cfg, dbConn, kycRepo, userRepo, blobStore, auditLogger)
+	complianceService := service.NewComplianceService(cfg, dbConn, complianceRepo, userRepo, walletRepo, transactionService, blobStore, auditLogger)
+
+	jobRepo := repository.NewJobRepository(entity.JobNamespace, cache)
+	jobService := service.NewJobService(jobRepo, transactionService)
+
+	if len(os.Args) > 1 {
+		commands := cli.Commands{
+			JobService:    jobService,
+			UserService:   userService,
+			AuditService:  auditService,
+			LedgerService: ledgerService,
+		}
+		if err := commands.Run(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	redisConsumer := queue.NewQueue(cfg, transactionService)
 	redisConsumer.Initialize()
 
+	outboxRelay := relay.NewOutboxRelay(dbConn, outboxRepo, redisPublisher,
+		time.Duration(cfg.OutboxPollIntervalMs)*time.Millisecond, cfg.OutboxBatchSize)
+	outboxRelay.Start()
+	defer outboxRelay.Stop()
+
+	limitRules.Start()
+	defer limitRules.Stop()
+
+	watchlist.Start()
+	defer watchlist.Stop()
+
 	router := gin.Default()
 
-	routes.SetupRoutes(router, userService, transactionService)
+	routes.SetupRoutes(router, cfg, auditLogger, authService, userService, accountService, mfaService, transactionService, confirmationService, jobService, auditService, kycService, riskService, complianceService, idempotencyRepo)
 
 	serverAddr := fmt.Sprintf(":%s", cfg.ServerPort)
 	log.Printf("Server running on port %s", cfg.ServerPort)
@@ -101,3 +201,23 @@ func main() {
 	}
 
 }
+
+func loadRiskConfig(cfg *config.Config) (risk.Config, error) {
+	riskConfig := risk.Config{
+		NewDeviceAge:       time.Duration(cfg.RiskNewDeviceHours) * time.Hour,
+		PinResetWindow:     time.Duration(cfg.RiskPinResetHours) * time.Hour,
+		BurstWindow:        time.Duration(cfg.RiskBurstMinutes) * time.Minute,
+		BurstNewRecipients: cfg.RiskBurstRecipients,
+		StructuringWindow:  time.Duration(cfg.RiskStructuringHours) * time.Hour,
+		StructuringCount:   cfg.RiskStructuringCount,
+	}
+
+	var err error
+	if riskConfig.LargeAmount, err = entity.ParseMoney(cfg.RiskLargeAmount, entity.DefaultCurrency); err != nil {
+		return riskConfig, fmt.Errorf("RISK_LARGE_AMOUNT: %w", err)
+	}
+	if riskConfig.RoundAmountUnit, err = entity.ParseMoney(cfg.RiskRoundAmountUnit, entity.DefaultCurrency); err != nil {
+		return riskConfig, fmt.Errorf("RISK_ROUND_AMOUNT_UNIT: %w", err)
+	}
+	return riskConfig, nil
+}
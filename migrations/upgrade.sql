@@ -0,0 +1,77 @@
+-- upgrade.sql brings a database created by an earlier init.sql up to date.
+-- Run init.sql first, which creates the missing tables, then this file with
+-- the mysql client. Both can be run again safely.
+--
+-- Existing users were able to log in before phone verification, tiers and
+-- account statuses existed, so they are backfilled as VERIFIED, BASIC and
+-- ACTIVE customers. New rows keep the defaults from init.sql.
+
+DROP PROCEDURE IF EXISTS add_column_if_missing;
+DROP PROCEDURE IF EXISTS add_index_if_missing;
+
+DELIMITER //
+
+CREATE PROCEDURE add_column_if_missing(IN table_in VARCHAR(64), IN column_in VARCHAR(64), IN definition_in TEXT)
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = table_in AND column_name = column_in
+	) THEN
+		SET @ddl = CONCAT('ALTER TABLE ', table_in, ' ADD COLUMN ', column_in, ' ', definition_in);
+		PREPARE stmt FROM @ddl;
+		EXECUTE stmt;
+		DEALLOCATE PREPARE stmt;
+	END IF;
+END //
+
+CREATE PROCEDURE add_index_if_missing(IN table_in VARCHAR(64), IN index_in VARCHAR(64), IN definition_in TEXT)
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = table_in AND index_name = index_in
+	) THEN
+		SET @ddl = CONCAT('ALTER TABLE ', table_in, ' ADD ', definition_in);
+		PREPARE stmt FROM @ddl;
+		EXECUTE stmt;
+		DEALLOCATE PREPARE stmt;
+	END IF;
+END //
+
+DELIMITER ;
+
+-- users
+CALL add_column_if_missing('users', 'verification_status', 'VARCHAR(20) NOT NULL DEFAULT ''VERIFIED'' AFTER address');
+ALTER TABLE users ALTER COLUMN verification_status SET DEFAULT 'UNVERIFIED';
+CALL add_column_if_missing('users', 'totp_secret', 'VARCHAR(64) DEFAULT NULL AFTER verification_status');
+CALL add_column_if_missing('users', 'totp_enabled', 'BOOLEAN NOT NULL DEFAULT FALSE AFTER totp_secret');
+CALL add_column_if_missing('users', 'totp_last_step', 'BIGINT DEFAULT NULL AFTER totp_enabled');
+CALL add_column_if_missing('users', 'role', 'VARCHAR(20) NOT NULL DEFAULT ''customer'' AFTER totp_last_step');
+CALL add_column_if_missing('users', 'status', 'VARCHAR(20) NOT NULL DEFAULT ''ACTIVE'' AFTER role');
+CALL add_column_if_missing('users', 'status_reason', 'VARCHAR(255) DEFAULT NULL AFTER status');
+CALL add_column_if_missing('users', 'tier', 'VARCHAR(20) NOT NULL DEFAULT ''BASIC'' AFTER status_reason');
+CALL add_column_if_missing('users', 'pin_reset_at', 'DATETIME NULL DEFAULT NULL AFTER tier');
+-- PIN resets were only recorded in the audit log before pin_reset_at.
+UPDATE users u
+SET pin_reset_at = (SELECT MAX(e.created_at) FROM audit_events e WHERE e.action = 'PIN_RESET' AND e.user_id = u.user_id)
+WHERE u.pin_reset_at IS NULL;
+
+-- wallets
+CALL add_column_if_missing('wallets', 'status', 'VARCHAR(20) NOT NULL DEFAULT ''ACTIVE'' AFTER balance');
+CALL add_index_if_missing('wallets', 'user_id', 'UNIQUE INDEX user_id (user_id)');
+
+-- transactions
+CALL add_column_if_missing('transactions', 'failure_reason', 'VARCHAR(100) DEFAULT NULL AFTER status');
+CALL add_index_if_missing('transactions', 'transaction_id', 'UNIQUE INDEX transaction_id (transaction_id)');
+
+-- outbox_messages
+CALL add_column_if_missing('outbox_messages', 'reference_id', 'VARCHAR(100) NOT NULL DEFAULT '''' AFTER job_name');
+CALL add_index_if_missing('outbox_messages', 'idx_outbox_messages_reference_id', 'INDEX idx_outbox_messages_reference_id (reference_id)');
+
+-- compliance_cases
+ALTER TABLE compliance_cases ALTER COLUMN trigger_type SET DEFAULT '';
+CALL add_column_if_missing('compliance_cases', 'summary', 'VARCHAR(255) NOT NULL DEFAULT '''' AFTER trigger_type');
+CALL add_column_if_missing('compliance_cases', 'assignee_id', 'VARCHAR(100) DEFAULT NULL AFTER status');
+CALL add_index_if_missing('compliance_cases', 'idx_compliance_cases_assignee_id', 'INDEX idx_compliance_cases_assignee_id (assignee_id)');
+
+DROP PROCEDURE add_column_if_missing;
+DROP PROCEDURE add_index_if_missing;